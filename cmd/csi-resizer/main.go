@@ -29,26 +29,41 @@ import (
 
 	"github.com/kubernetes-csi/csi-lib-utils/metrics"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/csi"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/kubernetes"
+	storagev1listers "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 
 	"k8s.io/client-go/util/workqueue"
 
 	"github.com/kubernetes-csi/csi-lib-utils/leaderelection"
 	"github.com/kubernetes-csi/csi-lib-utils/standardflags"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/audit"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/controller"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/coordinator"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/credentials"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/csidriver"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/dispatcher"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/expansionhistory"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/features"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/modifier"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/modifycontroller"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/nodeexpansion"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/policy"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/pricing"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/resizer"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/sharding"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/vacschema"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/volumerequest"
 	csitrans "k8s.io/csi-translation-lib"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	server "k8s.io/apiserver/pkg/server"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
-	"k8s.io/client-go/informers"
 	cflag "k8s.io/component-base/cli/flag"
 	"k8s.io/klog/v2"
 
@@ -66,14 +81,64 @@ var (
 	resyncPeriod = flag.Duration("resync-period", time.Minute*10, "Resync period for cache")
 	workers      = flag.Int("workers", 10, "Concurrency to process multiple resize requests")
 
-	extraModifyMetadata = flag.Bool("extra-modify-metadata", false, "If set, add pv/pvc metadata to plugin modify requests as parameters.")
+	extraModifyMetadata         = flag.Bool("extra-modify-metadata", false, "If set, add pv/pvc metadata to plugin modify requests as parameters.")
+	extraExpandMetadata         = flag.String("extra-expand-metadata", "", "If set, add pv/pvc metadata to plugin expand requests: \""+string(resizer.MetadataGRPC)+"\" sends it as gRPC request headers, \""+string(resizer.MetadataSecrets)+"\" adds it to the secrets of the request.")
+	extraMetadataPVCLabels      = flag.String("extra-metadata-pvc-labels", "", "Comma separated list of PVC label keys that are added to the pv/pvc metadata of --extra-modify-metadata and --extra-expand-metadata, when set on the PVC.")
+	extraMetadataPVCAnnotations = flag.String("extra-metadata-pvc-annotations", "", "Comma separated list of PVC annotation keys that are added to the pv/pvc metadata of --extra-modify-metadata and --extra-expand-metadata, when set on the PVC.")
+
+	modifyParametersPrefix             = flag.String("modify-parameters-prefix", "", "Prefix of the modify parameters set from the labels and annotations of --modify-parameters-from-pvc-labels, --modify-parameters-from-pvc-annotations, --modify-parameters-from-pv-labels and --modify-parameters-from-pv-annotations.")
+	modifyParametersFromPVCLabels      = flag.String("modify-parameters-from-pvc-labels", "", "Comma separated list of PVC label keys that are passed as parameters of plugin modify requests, when set on the PVC. The volume is modified again when they change.")
+	modifyParametersFromPVCAnnotations = flag.String("modify-parameters-from-pvc-annotations", "", "Comma separated list of PVC annotation keys that are passed as parameters of plugin modify requests, when set on the PVC. The volume is modified again when they change.")
+	modifyParametersFromPVLabels       = flag.String("modify-parameters-from-pv-labels", "", "Comma separated list of PV label keys that are passed as parameters of plugin modify requests, when set on the PV.")
+	modifyParametersFromPVAnnotations  = flag.String("modify-parameters-from-pv-annotations", "", "Comma separated list of PV annotation keys that are passed as parameters of plugin modify requests, when set on the PV.")
 
 	timeout = flag.Duration("timeout", 10*time.Second, "Timeout for waiting for CSI driver socket.")
 
 	retryIntervalStart = flag.Duration("retry-interval-start", time.Second, "Initial retry interval of failed volume resize. It exponentially increases with each failure, up to retry-interval-max.")
 	retryIntervalMax   = flag.Duration("retry-interval-max", 5*time.Minute, "Maximum retry interval of failed volume resize.")
 
+	policyFile = flag.String("policy-file", "", "Path to a YAML file with rules that restrict volume modifications, such as required approvals and allowed VolumeAttributesClass transitions per StorageClass. If empty, all modifications are allowed.")
+
+	auditSinks       = flag.String("audit-sinks", "", "Comma separated list of destinations for the audit log of volume expansions and modifications: stdout, file:<path> or an http(s) URL. If empty, no audit log is written.")
+	auditHMACKeyFile = flag.String("audit-hmac-key-file", "", "Path to a file with a secret key. If set, audit records are chained with HMAC-SHA256 so that removed or changed records can be detected.")
+
+	vacSchemaConfigMap  = flag.String("vac-schema-configmap", "", "<namespace>/<name> of a ConfigMap with a parameter schema under the key \""+vacschema.SchemaKey+"\". If set, VolumeAttributesClasses of the driver are validated against it and modifications to invalid classes are held as Pending.")
+	vacSchemaFromDriver = flag.Bool("vac-schema-from-csidriver", false, "If set, VolumeAttributesClasses of the driver are validated against the parameter schema in the \""+vacschema.AnnSchema+"\" annotation of its CSIDriver object, if there is one. Cannot be used together with --vac-schema-configmap.")
+
+	modifyDriftReconcileInterval = flag.Duration("modify-drift-reconcile-interval", 0, "If greater than zero, volumes whose modification is complete are modified again with their current VolumeAttributesClass at this interval, to undo changes made out-of-band.")
+	modifyReconcileOnVACRecreate = flag.Bool("modify-reconcile-on-vac-recreate", false, "If set, volumes are modified again when their current VolumeAttributesClass is deleted and created again.")
+
+	sharedPVCQueue = flag.Bool("shared-pvc-queue", false, "If set, the resize and modify controllers share a single PVC event handler and workqueue, and a worker processes the operations of a PVC one after the other. --workers sets the number of workers of the shared queue.")
+	operationOrder = flag.String("operation-order", "", "If set, expansion and modification of a PVC are serialized. With \""+string(coordinator.OrderResizeFirst)+"\" or \""+string(coordinator.OrderModifyFirst)+"\", a PVC that requests both is expanded or modified first, with \""+string(coordinator.OrderSerial)+"\" in any order. The \""+coordinator.AnnOrder+"\" annotation of the CSIDriver object overrides the order.")
+
+	nodeExpansionThresholds       = flag.String("node-expansion-watchdog-thresholds", "", "Comma separated, ascending durations after which a Warning event is recorded for PVCs that still wait for the expansion of their volume on the node, e.g. \"1h,24h,168h\". Empty disables the watchdog.")
+	nodeExpansionAnnotatePods     = flag.Bool("node-expansion-watchdog-annotate-pods", false, "If set, pods that use PVCs past the first --node-expansion-watchdog-thresholds are annotated with \""+nodeexpansion.AnnPodPending+"\".")
+	nodeExpansionRestartWorkloads = flag.Bool("node-expansion-watchdog-restart-workloads", false, "If set, the Deployments, StatefulSets and DaemonSets whose pods use PVCs past the last --node-expansion-watchdog-thresholds are restarted, if the StorageClass of the PVC has the \""+nodeexpansion.AnnRestartWorkloads+"\" annotation set to \"true\".")
+
+	expansionHistoryLimit = flag.Int("expansion-history-limit", 5, "Number of expansions recorded in the \""+expansionhistory.Annotation+"\" annotation of each PV. 0 disables the history.")
+
+	resizeRequests            = flag.Bool("resize-requests", false, "If set, VolumeResizeRequests of PVCs of the driver are carried out. Requires the CustomResourceDefinitions from deploy/kubernetes/volumerequests.yaml.")
+	modifyRequests            = flag.Bool("modify-requests", false, "If set, each VolumeAttributesClass change of a PVC of the driver is recorded in a VolumeModifyRequest. Requires the CustomResourceDefinitions from deploy/kubernetes/volumerequests.yaml.")
+	modifyRequestHistoryLimit = flag.Int("modify-request-history-limit", 10, "Number of finished VolumeModifyRequests kept per PVC with --modify-requests.")
+	requestTTL                = flag.Duration("request-ttl", 24*time.Hour, "How long finished VolumeResizeRequests are kept before they are deleted, unless they set spec.ttlSecondsAfterFinished.")
+
+	pricingConfigMap = flag.String("pricing-configmap", "", "<namespace>/<name> of a ConfigMap with a price table under the key \""+pricing.PriceTableKey+"\". If set, PVCs are annotated with the estimated monthly cost change of resize and modify operations before they are carried out.")
+
+	watchNamespace   = flag.String("watch-namespace", "", "If set, only PVCs and Pods in this namespace are watched and handled. PVs and VolumeAttributesClasses are always watched cluster-wide.")
+	pvcLabelSelector = flag.String("pvc-label-selector", "", "If set, only PVCs that match this label selector are watched and handled.")
+	pvLabelSelector  = flag.String("pv-label-selector", "", "If set, only PVs that match this label selector are watched. PVCs bound to other PVs are not handled.")
+	pvFieldSelector  = flag.String("pv-field-selector", "", "If set, only PVs that match this field selector are watched. PVCs bound to other PVs are not handled.")
+
+	secretsDir           = flag.String("secrets-dir", "", "If set, expansion and modification secrets are read from files <secrets-dir>/<namespace>/<name>/<key>, e.g. mounted Secret or Secrets Store CSI volumes, instead of from the API server.")
+	secretCacheTTL       = flag.Duration("secret-cache-ttl", 0, "If greater than zero, secrets read from the API server are cached for this long.")
+	secretCacheNamespace = flag.String("secret-cache-namespace", "", "If set together with --secret-cache-ttl, cached secrets of this namespace are removed from the cache as soon as they change.")
+
+	resolveSecretTemplates = flag.Bool("resolve-secret-templates", false, "If set, expansion and modification secrets are resolved from the csi.storage.k8s.io/controller-expand-secret-* and csi.storage.k8s.io/controller-modify-secret-* templates in the StorageClass or VolumeAttributesClass of a volume for every operation, instead of using the secret recorded in the PV.")
+
+	shards = flag.Int("shards", 0, "If greater than zero, PVCs are split into this many shards that are distributed among all running replicas, each shard protected by its own Lease, instead of electing a single leader. All replicas must use the same value. Cannot be used together with --leader-election.")
+
 	handleVolumeInUseError = flag.Bool("handle-volume-inuse-error", true, "Flag to turn on/off capability to handle volume in use error in resizer controller. Defaults to true if not set.")
+	inUseTracking          = flag.String("inuse-tracking", inUseTrackingPod, "How the resizer decides whether a volume is in use after a volume-in-use error: \""+inUseTrackingPod+"\" considers PVCs used by non-terminated pods, \""+inUseTrackingVolumeAttachment+"\" considers volumes that are attached to a node. \""+inUseTrackingVolumeAttachment+"\" falls back to \""+inUseTrackingPod+"\" when the CSIDriver object of the driver has attachRequired set to false. \""+inUseTrackingAuto+"\" uses \""+inUseTrackingVolumeAttachment+"\" when the CSIDriver object of the driver has attachRequired set to true and \""+inUseTrackingPod+"\" otherwise.")
 
 	featureGates map[string]bool
 
@@ -151,7 +216,52 @@ func main() {
 		}
 	}
 
-	informerFactory := informers.NewSharedInformerFactory(kubeClient, *resyncPeriod)
+	resizerPolicy := &policy.Policy{}
+	if *policyFile != "" {
+		resizerPolicy, err = policy.Load(*policyFile)
+		if err != nil {
+			klog.ErrorS(err, "Failed to load policy")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+	}
+
+	auditSink, err := newAuditSink(*auditSinks, *auditHMACKeyFile, *timeout)
+	if err != nil {
+		klog.ErrorS(err, "Failed to create audit sink")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
+
+	scope := util.Scope{
+		Namespace:        *watchNamespace,
+		PVCLabelSelector: *pvcLabelSelector,
+		PVLabelSelector:  *pvLabelSelector,
+		PVFieldSelector:  *pvFieldSelector,
+	}
+	if err := scope.Validate(); err != nil {
+		klog.ErrorS(err, "Invalid scope")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
+	informerFactory := util.NewScopedInformerFactory(kubeClient, *resyncPeriod, scope)
+
+	var pricer *pricing.ConfigMapPricer
+	var costEstimator *pricing.Estimator
+	if *pricingConfigMap != "" {
+		namespace, name, err := cache.SplitMetaNamespaceKey(*pricingConfigMap)
+		if err != nil || namespace == "" {
+			klog.ErrorS(err, "Invalid --pricing-configmap, expected <namespace>/<name>", "value", *pricingConfigMap)
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		pricer, err = pricing.NewConfigMapPricer(kubeClient, namespace, name, *resyncPeriod)
+		if err != nil {
+			klog.ErrorS(err, "Failed to create pricer")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		var vacLister storagev1listers.VolumeAttributesClassLister
+		if utilfeature.DefaultFeatureGate.Enabled(features.VolumeAttributesClass) {
+			vacLister = informerFactory.Storage().V1().VolumeAttributesClasses().Lister()
+		}
+		costEstimator = pricing.NewEstimator(pricer, informerFactory.Storage().V1().StorageClasses().Lister(), vacLister)
+	}
 
 	mux := http.NewServeMux()
 
@@ -187,11 +297,15 @@ func main() {
 	// Also registers the `k8s.io/component-base/` work queue and leader election metrics we anonymously import.
 	metricsManager.WithAdditionalRegistry(legacyregistry.DefaultGatherer)
 
+	credentialProvider := newCredentialProvider(ctx, kubeClient)
+
 	csiResizer, err := resizer.NewResizerFromClient(
 		csiClient,
 		*timeout,
 		kubeClient,
-		driverName)
+		driverName,
+		credentialProvider,
+		resizer.MetadataMode(*extraExpandMetadata))
 	if err != nil && errors.Is(err, resizer.ResizeNotSupportErr) {
 		klog.InfoS("Resize not supported", "message", err)
 	} else if err != nil {
@@ -205,7 +319,8 @@ func main() {
 		kubeClient,
 		informerFactory,
 		*extraModifyMetadata,
-		driverName)
+		driverName,
+		credentialProvider)
 	if err != nil && errors.Is(err, modifier.ModifyNotSupportErr) {
 		klog.InfoS("Modify not supported", "message", err)
 	} else if err != nil {
@@ -217,6 +332,38 @@ func main() {
 		klog.Fatalf("CSI driver does not support resize nor modify")
 	}
 
+	csiDriverWatcher := csidriver.NewWatcher(kubeClient, driverName, *resyncPeriod)
+	go csiDriverWatcher.Run(ctx)
+	syncCtx, cancel := context.WithTimeout(ctx, *timeout)
+	if !csiDriverWatcher.WaitForCacheSync(syncCtx) {
+		klog.ErrorS(nil, "Failed to get CSIDriver object, using defaults", "driver", driverName)
+	}
+	cancel()
+	klog.V(2).InfoS("CSIDriver settings", "driver", driverName, "settings", csiDriverWatcher.Settings())
+	checkCSIDriver(csiClient, csiDriverWatcher.Settings(), *timeout)
+	csiDriverWatcher.OnChange(func(settings csidriver.Settings) {
+		klog.V(2).InfoS("CSIDriver settings changed", "driver", driverName, "settings", settings)
+		checkCSIDriver(csiClient, settings, *timeout)
+	})
+
+	var operationCoordinator *coordinator.Coordinator
+	if *operationOrder != "" {
+		order, err := coordinator.ParseOrder(*operationOrder)
+		if err != nil {
+			klog.ErrorS(err, "Invalid --operation-order")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		// An operation must not wait for the other one when its controller does not run.
+		if csiResizer != nil && csiModifier != nil && utilfeature.DefaultFeatureGate.Enabled(features.VolumeAttributesClass) {
+			operationCoordinator = coordinator.New(order, func() (string, bool) {
+				return csiDriverWatcher.Annotation(coordinator.AnnOrder)
+			})
+			mux.Handle("/debug/operations", operationCoordinator)
+		} else {
+			klog.InfoS("Ignoring --operation-order, the resize and modify controllers do not both run", "driver", driverName)
+		}
+	}
+
 	// Start HTTP server for metrics + leader election healthz
 	if addr != "" {
 		metricsManager.RegisterToServer(mux, standardflags.Configuration.MetricsPath)
@@ -232,14 +379,169 @@ func main() {
 	}
 
 	leaseHolder := ""
+	if csiResizer != nil {
+		leaseHolder = csiResizer.Name()
+	} else {
+		leaseHolder = csiModifier.Name()
+	}
+	// Instances that watch different scopes must not share a lease.
+	leaseName := "external-resizer-" + util.SanitizeName(leaseHolder)
+	if identity := scope.Identity(); identity != "" {
+		leaseName += "-" + util.SanitizeName(identity)
+	}
+
+	var shardManager *sharding.Manager
+	if *shards > 0 {
+		if standardflags.Configuration.LeaderElection {
+			klog.ErrorS(nil, "Only one of `--leader-election` and `--shards` can be set.")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		identity, err := os.Hostname()
+		if err != nil {
+			klog.ErrorS(err, "Failed to get shard identity")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		leaseNamespace := standardflags.Configuration.LeaderElectionNamespace
+		if leaseNamespace == "" {
+			leaseNamespace = inClusterNamespace()
+		}
+		shardManager = sharding.NewManager(kubeClient, leaseNamespace, leaseName, identity, *shards,
+			standardflags.Configuration.LeaderElectionLeaseDuration)
+	}
+
+	var secretTemplates *credentials.TemplateResolver
+	if *resolveSecretTemplates {
+		secretTemplates = credentials.NewTemplateResolver(informerFactory)
+	}
+
+	extraMetadata := util.ExtraMetadata{
+		PVCLabels:      splitList(*extraMetadataPVCLabels),
+		PVCAnnotations: splitList(*extraMetadataPVCAnnotations),
+	}
+	var extraExpandMetadataPtr *util.ExtraMetadata
+	if *extraExpandMetadata != "" {
+		extraExpandMetadataPtr = &extraMetadata
+	}
+
+	metadataParameters := &util.MetadataParameters{
+		Prefix:         *modifyParametersPrefix,
+		PVCLabels:      splitList(*modifyParametersFromPVCLabels),
+		PVCAnnotations: splitList(*modifyParametersFromPVCAnnotations),
+		PVLabels:       splitList(*modifyParametersFromPVLabels),
+		PVAnnotations:  splitList(*modifyParametersFromPVAnnotations),
+	}
+	if len(metadataParameters.PVCLabels)+len(metadataParameters.PVCAnnotations)+len(metadataParameters.PVLabels)+len(metadataParameters.PVAnnotations) == 0 {
+		metadataParameters = nil
+	}
+
+	var vacSchema vacschema.Source
+	var vacSchemaSource *vacschema.ConfigMapSource
+	switch {
+	case *vacSchemaConfigMap != "" && *vacSchemaFromDriver:
+		klog.ErrorS(nil, "--vac-schema-configmap and --vac-schema-from-csidriver cannot be used together")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	case *vacSchemaConfigMap != "":
+		namespace, name, err := cache.SplitMetaNamespaceKey(*vacSchemaConfigMap)
+		if err != nil || namespace == "" {
+			klog.ErrorS(err, "Invalid --vac-schema-configmap, expected <namespace>/<name>", "value", *vacSchemaConfigMap)
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		vacSchemaSource, err = vacschema.NewConfigMapSource(kubeClient, namespace, name, *resyncPeriod)
+		if err != nil {
+			klog.ErrorS(err, "Failed to create VolumeAttributesClass schema source")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		vacSchema = vacSchemaSource
+	case *vacSchemaFromDriver:
+		annotationSource := vacschema.NewAnnotationSource(func() (string, bool) {
+			return csiDriverWatcher.Annotation(vacschema.AnnSchema)
+		})
+		csiDriverWatcher.AddHandler(annotationSource.Changed)
+		vacSchema = annotationSource
+	}
+
+	var pvcDispatcher *dispatcher.Dispatcher
+	if *sharedPVCQueue {
+		pvcDispatcher = dispatcher.New(driverName, informerFactory.Core().V1().PersistentVolumeClaims(), *resyncPeriod, shardManager)
+	}
+
 	var rc controller.ResizeController
 	if csiResizer != nil {
+		trackVolumeAttachments, err := useVolumeAttachments(csiDriverWatcher.Settings(), *inUseTracking)
+		if err != nil {
+			klog.ErrorS(err, "Invalid --inuse-tracking")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		if *handleVolumeInUseError {
+			// The informers for in-use tracking are chosen at startup, restart to switch them.
+			csiDriverWatcher.OnChange(func(settings csidriver.Settings) {
+				if track, _ := useVolumeAttachments(settings, *inUseTracking); track != trackVolumeAttachments {
+					klog.ErrorS(nil, "CSIDriver object changed how volumes in use are tracked, restarting", "driver", driverName, "useVolumeAttachments", track)
+					klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+				}
+			})
+		}
 		resizerName := csiResizer.Name()
 		rc = controller.NewResizeController(resizerName, csiResizer, kubeClient, *resyncPeriod, informerFactory,
 			workqueue.NewTypedItemExponentialFailureRateLimiter[string](*retryIntervalStart, *retryIntervalMax),
-			*handleVolumeInUseError, *retryIntervalMax)
+			*handleVolumeInUseError, *retryIntervalMax, controller.Options{
+				AuditSink:             auditSink,
+				CostEstimator:         costEstimator,
+				Shards:                shardManager,
+				UseVolumeAttachments:  trackVolumeAttachments,
+				SecretTemplates:       secretTemplates,
+				ExtraMetadata:         extraExpandMetadataPtr,
+				Coordinator:           operationCoordinator,
+				Dispatcher:            pvcDispatcher,
+				ExpansionHistoryLimit: *expansionHistoryLimit,
+			})
+		if *expansionHistoryLimit > 0 {
+			mux.Handle("/debug/expansions", expansionhistory.NewHandler(informerFactory.Core().V1().PersistentVolumes().Lister(), resizerName))
+		}
+	}
+
+	var dynamicClient dynamic.Interface
+	if *resizeRequests || *modifyRequests {
+		dynamicClient, err = dynamic.NewForConfig(config)
+		if err != nil {
+			klog.ErrorS(err, "Failed to create dynamic client")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+	}
+
+	var watchdog *nodeexpansion.Watchdog
+	if *nodeExpansionThresholds != "" && csiResizer != nil {
+		thresholds, err := parseThresholds(*nodeExpansionThresholds)
+		if err != nil {
+			klog.ErrorS(err, "Invalid --node-expansion-watchdog-thresholds")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		watchdog = nodeexpansion.NewWatchdog(csiResizer.Name(), csiResizer, kubeClient, informerFactory, nodeexpansion.Config{
+			Thresholds:       thresholds,
+			AnnotatePods:     *nodeExpansionAnnotatePods,
+			RestartWorkloads: *nodeExpansionRestartWorkloads,
+		}, shardManager)
+	}
+
+	var requestInformerFactory dynamicinformer.DynamicSharedInformerFactory
+	var rrc *volumerequest.ResizeController
+	if *resizeRequests && csiResizer != nil {
+		if err := volumerequest.CheckResource(kubeClient.Discovery(), volumerequest.VolumeResizeRequestResource); err != nil {
+			klog.ErrorS(err, "Invalid --resize-requests")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		requestInformerFactory = dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient, *resyncPeriod, *watchNamespace, nil)
+		rrc = volumerequest.NewResizeController(csiResizer.Name(), csiResizer, kubeClient, dynamicClient, informerFactory,
+			requestInformerFactory, &resizerPolicy.Resize, shardManager, *requestTTL)
+	}
 
-		leaseHolder = resizerName
+	var modifyHistory *volumerequest.ModifyHistory
+	if *modifyRequests {
+		if err := volumerequest.CheckResource(kubeClient.Discovery(), volumerequest.VolumeModifyRequestResource); err != nil {
+			klog.ErrorS(err, "Invalid --modify-requests")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		modifyHistory = volumerequest.NewModifyHistory(dynamicClient, *modifyRequestHistoryLimit)
 	}
 
 	var mc modifycontroller.ModifyController
@@ -249,11 +551,20 @@ func main() {
 		if utilfeature.DefaultFeatureGate.Enabled(features.VolumeAttributesClass) {
 			mc = modifycontroller.NewModifyController(modifierName, csiModifier, kubeClient, *resyncPeriod,
 				*retryIntervalMax, *extraModifyMetadata, informerFactory,
-				workqueue.NewTypedItemExponentialFailureRateLimiter[string](*retryIntervalStart, *retryIntervalMax))
-		}
-
-		if leaseHolder == "" {
-			leaseHolder = modifierName
+				workqueue.NewTypedItemExponentialFailureRateLimiter[string](*retryIntervalStart, *retryIntervalMax), modifycontroller.Options{
+					Policy:             &resizerPolicy.Modify,
+					AuditSink:          auditSink,
+					CostEstimator:      costEstimator,
+					Shards:             shardManager,
+					SecretTemplates:    secretTemplates,
+					ExtraMetadata:      extraMetadata,
+					MetadataParameters: metadataParameters,
+					VACSchema:          vacSchema,
+					Drift:              modifycontroller.DriftReconciliation{Interval: *modifyDriftReconcileInterval, OnVACRecreate: *modifyReconcileOnVACRecreate},
+					Coordinator:        operationCoordinator,
+					Dispatcher:         pvcDispatcher,
+					History:            modifyHistory,
+				})
 		}
 	}
 
@@ -282,6 +593,15 @@ func main() {
 
 	run := func(ctx context.Context) {
 		informerFactory.Start(ctx.Done())
+		if requestInformerFactory != nil {
+			requestInformerFactory.Start(ctx.Done())
+		}
+		if pricer != nil {
+			go pricer.Run(ctx)
+		}
+		if vacSchemaSource != nil {
+			go vacSchemaSource.Run(ctx)
+		}
 		if utilfeature.DefaultFeatureGate.Enabled(features.ReleaseLeaderElectionOnExit) {
 			var wg sync.WaitGroup
 			if rc != nil {
@@ -290,6 +610,15 @@ func main() {
 			if mc != nil && utilfeature.DefaultFeatureGate.Enabled(features.VolumeAttributesClass) {
 				go mc.Run(*workers, controllerCtx, &wg)
 			}
+			if pvcDispatcher != nil {
+				go pvcDispatcher.Run(*workers, controllerCtx, &wg)
+			}
+			if rrc != nil {
+				go rrc.Run(*workers, controllerCtx, &wg)
+			}
+			if watchdog != nil {
+				go watchdog.Run(*workers, controllerCtx, &wg)
+			}
 			<-controllerCtx.Done()
 			wg.Wait()
 			terminate()
@@ -300,23 +629,176 @@ func main() {
 			if mc != nil && utilfeature.DefaultFeatureGate.Enabled(features.VolumeAttributesClass) {
 				go mc.Run(*workers, ctx, nil)
 			}
+			if pvcDispatcher != nil {
+				go pvcDispatcher.Run(*workers, ctx, nil)
+			}
+			if rrc != nil {
+				go rrc.Run(*workers, ctx, nil)
+			}
+			if watchdog != nil {
+				go watchdog.Run(*workers, ctx, nil)
+			}
 			<-ctx.Done()
 		}
 	}
 
+	if shardManager != nil {
+		go shardManager.Run(ctx)
+		run(ctx)
+		return
+	}
+
 	leaderelection.RunWithLeaderElection(
 		ctx,
 		config,
 		standardflags.Configuration,
 		run,
-		"external-resizer-"+util.SanitizeName(leaseHolder),
+		leaseName,
 		mux,
 		utilfeature.DefaultFeatureGate.Enabled(features.ReleaseLeaderElectionOnExit),
 	)
 }
 
+const (
+	inUseTrackingAuto             = "auto"
+	inUseTrackingPod              = "pod"
+	inUseTrackingVolumeAttachment = "volumeattachment"
+)
+
+// useVolumeAttachments returns true if in-use volumes should be tracked by their VolumeAttachments.
+func useVolumeAttachments(settings csidriver.Settings, mode string) (bool, error) {
+	switch mode {
+	case inUseTrackingPod:
+		return false, nil
+	case inUseTrackingVolumeAttachment:
+		if !settings.AttachRequired {
+			klog.InfoS("CSIDriver does not require attach, tracking volumes in use by pods")
+		}
+		return settings.AttachRequired, nil
+	case inUseTrackingAuto:
+		// Without a CSIDriver object, keep tracking pods as before.
+		return settings.Found && settings.AttachRequired, nil
+	}
+	return false, fmt.Errorf("unknown mode %q", mode)
+}
+
+// checkCSIDriver logs a warning for every setting of the CSIDriver object that contradicts the
+// capabilities of the driver.
+func checkCSIDriver(csiClient csi.Client, settings csidriver.Settings, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	supportsPublish, err := csiClient.SupportsControllerPublish(ctx)
+	if err != nil {
+		klog.ErrorS(err, "Failed to get controller capabilities")
+		return
+	}
+	for _, conflict := range settings.Conflicts(csidriver.Capabilities{ControllerPublish: supportsPublish}) {
+		klog.Warningf("CSIDriver object does not match the driver: %s", conflict)
+	}
+}
+
+// newCredentialProvider returns the provider of expansion and modification secrets described by the command line.
+func newCredentialProvider(ctx context.Context, kubeClient kubernetes.Interface) credentials.Provider {
+	if *secretsDir != "" {
+		return credentials.NewFileProvider(*secretsDir)
+	}
+	provider := credentials.NewAPIProvider(kubeClient)
+	if *secretCacheTTL <= 0 {
+		return provider
+	}
+	cachingProvider := credentials.NewCachingProvider(provider, *secretCacheTTL)
+	if *secretCacheNamespace != "" {
+		if err := cachingProvider.InvalidateOnChange(ctx, kubeClient, *secretCacheNamespace, *resyncPeriod); err != nil {
+			klog.ErrorS(err, "Failed to watch secrets", "namespace", *secretCacheNamespace)
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+	}
+	return cachingProvider
+}
+
+// inClusterNamespace returns the namespace of the pod, like leader election does for its lease.
+func inClusterNamespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	if data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		if ns := strings.TrimSpace(string(data)); ns != "" {
+			return ns
+		}
+	}
+	return "default"
+}
+
 func getDriverName(client csi.Client, timeout time.Duration) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 	return client.GetDriverName(ctx)
 }
+
+// splitList returns the non-empty elements of a comma separated list.
+func splitList(list string) []string {
+	var elements []string
+	for _, element := range strings.Split(list, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// parseThresholds parses a comma separated list of ascending durations.
+func parseThresholds(list string) ([]time.Duration, error) {
+	var thresholds []time.Duration
+	for _, element := range splitList(list) {
+		threshold, err := time.ParseDuration(element)
+		if err != nil {
+			return nil, err
+		}
+		if threshold <= 0 || (len(thresholds) > 0 && threshold <= thresholds[len(thresholds)-1]) {
+			return nil, fmt.Errorf("thresholds must be positive and ascending, got %q", list)
+		}
+		thresholds = append(thresholds, threshold)
+	}
+	return thresholds, nil
+}
+
+// auditQueueSize is the number of audit records buffered for each HTTP destination.
+const auditQueueSize = 1000
+
+// newAuditSink returns the audit sink described by the command line, or nil if auditing is disabled.
+func newAuditSink(specs, hmacKeyFile string, timeout time.Duration) (audit.Sink, error) {
+	if specs == "" {
+		return nil, nil
+	}
+	var key []byte
+	if hmacKeyFile != "" {
+		var err error
+		if key, err = os.ReadFile(hmacKeyFile); err != nil {
+			return nil, fmt.Errorf("failed to read audit HMAC key: %v", err)
+		}
+	}
+	var sinks []audit.Sink
+	for _, spec := range strings.Split(specs, ",") {
+		spec = strings.TrimSpace(spec)
+		sink, err := audit.NewSinkFromSpec(spec, timeout)
+		if err != nil {
+			return nil, err
+		}
+		if key != nil {
+			// Each destination has its own chain, so that a failure of one does not break the others.
+			lastHMAC := ""
+			if path, ok := strings.CutPrefix(spec, "file:"); ok {
+				// Continue the chain of the previous run.
+				if lastHMAC, err = audit.LastHMAC(path); err != nil {
+					return nil, err
+				}
+			}
+			sink = audit.NewHMACChainSink(sink, key, lastHMAC)
+		}
+		if strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://") {
+			sink = audit.NewAsyncSink(sink, auditQueueSize)
+		}
+		sinks = append(sinks, sink)
+	}
+	return audit.NewMultiSink(sinks...), nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// resizerctl shows PVCs whose resize or modify operation did not finish and
+// carries out guided recovery actions for them.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/resizerctl"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const usage = `Usage: resizerctl <command> [flags] [arguments]
+
+Commands:
+  status                       List PVCs with unfinished resize or modify operations, per driver.
+  reset-size <pvc> <size>      Lower the requested size of a PVC after an infeasible expansion.
+  clear-pre-resize-capacity <pv>
+                               Remove a stale pre-resize capacity annotation from a PV.
+  reset-modify <pvc>           Clear the modify volume status and conditions of a PVC.
+
+Flags must come before the arguments. Recovery commands print what they would
+change and apply it only with --yes.
+Run "resizerctl <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err := run(context.Background(), os.Args[1], os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "status", "reset-size", "clear-pre-resize-capacity", "reset-modify":
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	kubeconfig := flags.String("kubeconfig", "", "Path to a kubeconfig file. Defaults to the usual kubectl lookup.")
+	namespace := flags.String("namespace", "", "Namespace of the PVC. For status, an empty namespace lists all namespaces.")
+	driver := flags.String("driver", "", "Only list PVCs of this CSI driver.")
+	force := flags.Bool("force", false, "Skip the safety checks of a recovery command.")
+	yes := flags.Bool("yes", false, "Apply the recovery action instead of only printing it.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = *kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %v", err)
+	}
+	if *namespace == "" && command != "status" {
+		if *namespace, _, err = clientConfig.Namespace(); err != nil {
+			return err
+		}
+	}
+
+	var action *resizerctl.Action
+	switch command {
+	case "status":
+		states, err := resizerctl.ListStates(ctx, kubeClient, *namespace, *driver)
+		if err != nil {
+			return err
+		}
+		if len(states) == 0 {
+			fmt.Println("No PVCs with unfinished resize or modify operations found.")
+			return nil
+		}
+		return resizerctl.PrintStates(os.Stdout, states)
+	case "reset-size":
+		if flags.NArg() != 2 {
+			return fmt.Errorf("expected <pvc> <size> arguments")
+		}
+		size, err := resource.ParseQuantity(flags.Arg(1))
+		if err != nil {
+			return fmt.Errorf("invalid size %q: %v", flags.Arg(1), err)
+		}
+		action, err = resizerctl.ResetSize(ctx, kubeClient, *namespace, flags.Arg(0), size, *force)
+		if err != nil {
+			return err
+		}
+	case "clear-pre-resize-capacity":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("expected <pv> argument")
+		}
+		action, err = resizerctl.ClearPreResizeCapacity(ctx, kubeClient, flags.Arg(0), *force)
+		if err != nil {
+			return err
+		}
+	case "reset-modify":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("expected <pvc> argument")
+		}
+		action, err = resizerctl.ResetModifyStatus(ctx, kubeClient, *namespace, flags.Arg(0), *force)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !*yes {
+		fmt.Printf("Would: %s\nRe-run with --yes to apply.\n", action.Description)
+		return nil
+	}
+	if err := action.Apply(); err != nil {
+		return err
+	}
+	fmt.Printf("Done: %s\n", action.Description)
+	return nil
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var droppedRecords = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Subsystem:      "csi_resizer",
+		Name:           "audit_dropped_records_total",
+		Help:           "Number of audit records that were not written to a destination, by reason.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"reason"},
+)
+
+func init() {
+	legacyregistry.MustRegister(droppedRecords)
+}
+
+// writeBackoff is the backoff between attempts of an asynchronous sink to write a record.
+var writeBackoff = wait.Backoff{Duration: time.Second, Factor: 2, Steps: 5}
+
+// Operation is the kind of volume mutation recorded in an audit record.
+type Operation string
+
+const (
+	OperationExpand Operation = "Expand"
+	OperationModify Operation = "Modify"
+)
+
+// Outcome is the result of the recorded operation.
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "Success"
+	OutcomeFailure Outcome = "Failure"
+)
+
+// Record describes a single volume mutation performed by the external-resizer.
+type Record struct {
+	Time         time.Time `json:"time"`
+	Operation    Operation `json:"operation"`
+	Driver       string    `json:"driver"`
+	PVCNamespace string    `json:"pvcNamespace"`
+	PVCName      string    `json:"pvcName"`
+	PVCUID       string    `json:"pvcUID"`
+	PVName       string    `json:"pvName"`
+	// Requester is the field manager that last changed the PVC spec.
+	Requester string `json:"requester,omitempty"`
+	// From and To are sizes for expansion and VolumeAttributesClass names for modification.
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Response summarizes what the driver returned on success.
+	Response string        `json:"response,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Outcome  Outcome       `json:"outcome"`
+	Error    string        `json:"error,omitempty"`
+	Code     string        `json:"code,omitempty"`
+	// PrevHMAC and HMAC chain the records together when an HMAC key is configured.
+	PrevHMAC string `json:"prevHMAC,omitempty"`
+	// ChainStart marks the first record of a chain, written after the external-resizer started
+	// without the HMAC of the previous record.
+	ChainStart bool   `json:"chainStart,omitempty"`
+	HMAC       string `json:"hmac,omitempty"`
+}
+
+// NewRecord returns a Record for an operation on the given PVC and PV, with Requester
+// set from the PVC's managed fields.
+func NewRecord(op Operation, driver string, pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume, from, to string) *Record {
+	return &Record{
+		Time:         time.Now(),
+		Operation:    op,
+		Driver:       driver,
+		PVCNamespace: pvc.Namespace,
+		PVCName:      pvc.Name,
+		PVCUID:       string(pvc.UID),
+		PVName:       pv.Name,
+		Requester:    Requester(pvc),
+		From:         from,
+		To:           to,
+	}
+}
+
+// Finish fills in the duration and outcome of the operation that started at r.Time.
+func (r *Record) Finish(response string, err error) *Record {
+	r.Duration = time.Since(r.Time)
+	if err == nil {
+		r.Outcome = OutcomeSuccess
+		r.Response = response
+		return r
+	}
+	r.Outcome = OutcomeFailure
+	r.Error = err.Error()
+	if st, ok := status.FromError(err); ok {
+		r.Code = st.Code().String()
+	}
+	return r
+}
+
+// Sink receives audit records.
+type Sink interface {
+	// Write stores a single record. Implementations must be safe for concurrent use.
+	Write(ctx context.Context, r *Record) error
+}
+
+// Emit writes r to sink and logs failures. A nil sink discards the record.
+func Emit(sink Sink, r *Record) {
+	if sink == nil {
+		return
+	}
+	if err := sink.Write(context.TODO(), r); err != nil {
+		klog.ErrorS(err, "Failed to write audit record", "operation", r.Operation, "PVC", klog.KRef(r.PVCNamespace, r.PVCName))
+	}
+}
+
+type multiSink []Sink
+
+// NewMultiSink returns a Sink that writes every record to all sinks.
+func NewMultiSink(sinks ...Sink) Sink {
+	return multiSink(sinks)
+}
+
+func (m multiSink) Write(ctx context.Context, r *Record) error {
+	var errs []error
+	for _, s := range m {
+		// Every sink gets its own copy so that one can't change what the others see.
+		c := *r
+		if err := s.Write(ctx, &c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type asyncSink struct {
+	sink    Sink
+	records chan *Record
+	backoff wait.Backoff
+}
+
+// NewAsyncSink returns a Sink that passes records to sink in the background, in the order they
+// were written, so that slow destinations don't block the controllers. A record that sink fails to
+// write is retried with writeBackoff before it is dropped. Up to queueSize records are buffered;
+// when the buffer is full, Write drops the record and returns an error.
+func NewAsyncSink(sink Sink, queueSize int) Sink {
+	s := &asyncSink{sink: sink, records: make(chan *Record, queueSize), backoff: writeBackoff}
+	go s.run()
+	return s
+}
+
+func (s *asyncSink) Write(_ context.Context, r *Record) error {
+	c := *r
+	select {
+	case s.records <- &c:
+		return nil
+	default:
+		droppedRecords.WithLabelValues("queue_full").Inc()
+		return fmt.Errorf("audit queue is full, dropped record")
+	}
+}
+
+func (s *asyncSink) run() {
+	for r := range s.records {
+		if err := s.write(r); err != nil {
+			droppedRecords.WithLabelValues("write_failed").Inc()
+			klog.ErrorS(err, "Failed to write audit record, dropped record", "operation", r.Operation, "PVC", klog.KRef(r.PVCNamespace, r.PVCName))
+		}
+	}
+}
+
+// write passes r to the sink, retrying failures that may be temporary. Later records wait, so
+// that they are written in order.
+func (s *asyncSink) write(r *Record) error {
+	backoff := s.backoff
+	for {
+		// The record outlives the operation that wrote it.
+		err := s.sink.Write(context.Background(), r)
+		var permanent *permanentError
+		if err == nil || errors.As(err, &permanent) || backoff.Steps <= 1 {
+			return err
+		}
+		klog.V(4).InfoS("Retrying audit record", "operation", r.Operation, "PVC", klog.KRef(r.PVCNamespace, r.PVCName), "err", err)
+		time.Sleep(backoff.Step())
+	}
+}
+
+type hmacSink struct {
+	sink Sink
+	key  []byte
+
+	mu   sync.Mutex
+	last string
+}
+
+// NewHMACChainSink returns a Sink that sets PrevHMAC and HMAC of every record before passing it to
+// sink, so that removing or changing a record breaks the chain. last is the HMAC of the last record
+// written before a restart, if any; without it, the chain starts again and its first record is
+// marked with ChainStart. The chain only advances when sink accepted a record, so every
+// destination needs its own chain.
+func NewHMACChainSink(sink Sink, key []byte, last string) Sink {
+	return &hmacSink{sink: sink, key: key, last: last}
+}
+
+func (h *hmacSink) Write(ctx context.Context, r *Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r.PrevHMAC = h.last
+	r.ChainStart = h.last == ""
+	sum, err := ComputeHMAC(h.key, r)
+	if err != nil {
+		return err
+	}
+	r.HMAC = sum
+	if err := h.sink.Write(ctx, r); err != nil {
+		return err
+	}
+	h.last = sum
+	return nil
+}
+
+// ComputeHMAC returns the HMAC of a record, computed over its JSON encoding without the HMAC field.
+func ComputeHMAC(key []byte, r *Record) (string, error) {
+	c := *r
+	c.HMAC = ""
+	data, err := json.Marshal(&c)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit record: %v", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Verify checks that records form an unbroken HMAC chain. A record marked with ChainStart starts
+// a new chain; records removed right before it can't be detected.
+func Verify(key []byte, records []*Record) error {
+	prev := ""
+	for i, r := range records {
+		if r.ChainStart && r.PrevHMAC != "" {
+			return fmt.Errorf("record %d starts a chain but follows a previous record", i)
+		}
+		if i > 0 && !r.ChainStart && r.PrevHMAC != prev {
+			return fmt.Errorf("record %d does not follow the previous record", i)
+		}
+		sum, err := ComputeHMAC(key, r)
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal([]byte(sum), []byte(r.HMAC)) {
+			return fmt.Errorf("record %d has an invalid HMAC", i)
+		}
+		prev = r.HMAC
+	}
+	return nil
+}
+
+// Requester returns the field manager that most recently changed the PVC spec, as recorded in its
+// managed fields.
+func Requester(pvc *v1.PersistentVolumeClaim) string {
+	requester := ""
+	var latest time.Time
+	for _, mf := range pvc.ManagedFields {
+		if mf.Subresource != "" || mf.FieldsV1 == nil || !strings.Contains(string(mf.FieldsV1.Raw), `"f:spec"`) {
+			continue
+		}
+		if mf.Time == nil {
+			if requester == "" {
+				requester = mf.Manager
+			}
+			continue
+		}
+		if requester == "" || mf.Time.After(latest) {
+			requester = mf.Manager
+			latest = mf.Time.Time
+		}
+	}
+	return requester
+}
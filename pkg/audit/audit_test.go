@@ -0,0 +1,373 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func testPVC() *v1.PersistentVolumeClaim {
+	older := metav1.NewTime(time.Now().Add(-time.Hour))
+	newer := metav1.NewTime(time.Now())
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "uid",
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl-create", Time: &older, FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{}}`)}},
+				{Manager: "kubectl-edit", Time: &newer, FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:resources":{}}}`)}},
+				{Manager: "kube-controller-manager", Time: &newer, Subresource: "status", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:status":{}}`)}},
+				{Manager: "labeler", Time: &newer, FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{}}`)}},
+			},
+		},
+	}
+}
+
+func TestRequester(t *testing.T) {
+	if r := Requester(testPVC()); r != "kubectl-edit" {
+		t.Errorf("expected requester kubectl-edit, got %q", r)
+	}
+	if r := Requester(&v1.PersistentVolumeClaim{}); r != "" {
+		t.Errorf("expected empty requester, got %q", r)
+	}
+}
+
+func TestRecordFinish(t *testing.T) {
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv"}}
+	r := NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", "2Gi").Finish("ok", nil)
+	if r.Outcome != OutcomeSuccess || r.Response != "ok" || r.Error != "" {
+		t.Errorf("unexpected successful record: %+v", r)
+	}
+	r = NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", "2Gi").Finish("ok", status.Error(codes.OutOfRange, "too big"))
+	if r.Outcome != OutcomeFailure || r.Response != "" || r.Code != "OutOfRange" {
+		t.Errorf("unexpected failed record: %+v", r)
+	}
+}
+
+type memorySink struct {
+	records []*Record
+}
+
+func (m *memorySink) Write(_ context.Context, r *Record) error {
+	m.records = append(m.records, r)
+	return nil
+}
+
+func TestHMACChain(t *testing.T) {
+	key := []byte("secret")
+	mem := &memorySink{}
+	sink := NewHMACChainSink(mem, key, "")
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv"}}
+	for _, to := range []string{"2Gi", "3Gi", "4Gi"} {
+		Emit(sink, NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", to).Finish("", nil))
+	}
+	if err := Verify(key, mem.records); err != nil {
+		t.Fatalf("unexpected verification error: %v", err)
+	}
+
+	changed := *mem.records[1]
+	changed.To = "10Gi"
+	if err := Verify(key, []*Record{mem.records[0], &changed, mem.records[2]}); err == nil {
+		t.Errorf("expected changed record to be detected")
+	}
+	if err := Verify(key, []*Record{mem.records[0], mem.records[2]}); err == nil {
+		t.Errorf("expected removed record to be detected")
+	}
+	if err := Verify([]byte("other"), mem.records); err == nil {
+		t.Errorf("expected wrong key to be detected")
+	}
+}
+
+func TestHMACChainRestart(t *testing.T) {
+	key := []byte("secret")
+	mem := &memorySink{}
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv"}}
+	// Each run starts a new chain, like an HTTP destination after a restart.
+	for _, to := range []string{"2Gi", "3Gi"} {
+		sink := NewHMACChainSink(mem, key, "")
+		Emit(sink, NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", to).Finish("", nil))
+		Emit(sink, NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", to).Finish("", nil))
+	}
+	if !mem.records[0].ChainStart || mem.records[1].ChainStart || !mem.records[2].ChainStart || mem.records[3].ChainStart {
+		t.Fatalf("expected the first record of each run to start a chain, got %+v", mem.records)
+	}
+	if err := Verify(key, mem.records); err != nil {
+		t.Fatalf("unexpected verification error: %v", err)
+	}
+
+	unmarked := *mem.records[2]
+	unmarked.ChainStart = false
+	if err := Verify(key, []*Record{mem.records[0], mem.records[1], &unmarked, mem.records[3]}); err == nil {
+		t.Errorf("expected removed restart marker to be detected")
+	}
+	if err := Verify(key, []*Record{mem.records[0], mem.records[1], mem.records[3]}); err == nil {
+		t.Errorf("expected removed first record of a chain to be detected")
+	}
+
+	// A seeded chain continues the previous run.
+	seeded := &memorySink{}
+	Emit(NewHMACChainSink(seeded, key, mem.records[3].HMAC), NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", "4Gi").Finish("", nil))
+	if seeded.records[0].ChainStart {
+		t.Errorf("expected seeded chain to continue, got %+v", seeded.records[0])
+	}
+}
+
+type sinkFunc func(ctx context.Context, r *Record) error
+
+func (f sinkFunc) Write(ctx context.Context, r *Record) error {
+	return f(ctx, r)
+}
+
+func TestHMACChainPerSink(t *testing.T) {
+	key := []byte("secret")
+	mem := &memorySink{}
+	failing := false
+	sink := NewMultiSink(NewHMACChainSink(mem, key, ""), NewHMACChainSink(sinkFunc(func(ctx context.Context, r *Record) error {
+		if failing {
+			return errors.New("unavailable")
+		}
+		return nil
+	}), key, ""))
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv"}}
+	for i, to := range []string{"2Gi", "3Gi", "4Gi"} {
+		// The second destination fails after the first destination wrote the record.
+		failing = i == 1
+		Emit(sink, NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", to).Finish("", nil))
+	}
+	if err := Verify(key, mem.records); err != nil {
+		t.Errorf("expected an unbroken chain despite the failing destination, got %v", err)
+	}
+}
+
+func TestAsyncSink(t *testing.T) {
+	mem := &memorySink{}
+	done := make(chan struct{})
+	sink := NewAsyncSink(sinkFunc(func(ctx context.Context, r *Record) error {
+		err := mem.Write(ctx, r)
+		if len(mem.records) == 2 {
+			close(done)
+		}
+		return err
+	}), 2)
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv"}}
+	for _, to := range []string{"2Gi", "3Gi"} {
+		if err := sink.Write(t.Context(), NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", to)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	<-done
+	if mem.records[0].To != "2Gi" || mem.records[1].To != "3Gi" {
+		t.Errorf("expected records in order, got %+v", mem.records)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	blocked := NewAsyncSink(sinkFunc(func(ctx context.Context, r *Record) error {
+		<-release
+		return nil
+	}), 1)
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		err = blocked.Write(t.Context(), NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", "2Gi"))
+	}
+	if err == nil {
+		t.Errorf("expected error when the queue is full")
+	}
+}
+
+func TestAsyncSinkRetry(t *testing.T) {
+	oldBackoff := writeBackoff
+	writeBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 3}
+	defer func() { writeBackoff = oldBackoff }()
+	dropped := droppedRecords.WithLabelValues("write_failed")
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv"}}
+
+	tests := []struct {
+		name             string
+		err              error
+		failures         int
+		expectedAttempts int
+		expectDropped    bool
+	}{
+		{
+			name:             "temporary failure",
+			err:              errors.New("unavailable"),
+			failures:         2,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "persistent failure",
+			err:              errors.New("unavailable"),
+			failures:         10,
+			expectedAttempts: 3,
+			expectDropped:    true,
+		},
+		{
+			name:             "permanent failure",
+			err:              &permanentError{errors.New("bad request")},
+			failures:         10,
+			expectedAttempts: 1,
+			expectDropped:    true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before, err := testutil.GetCounterMetricValue(dropped)
+			if err != nil {
+				t.Fatal(err)
+			}
+			attempts := 0
+			done := make(chan struct{})
+			sink := NewAsyncSink(sinkFunc(func(ctx context.Context, r *Record) error {
+				attempts++
+				if r.To == "done" {
+					close(done)
+					return nil
+				}
+				if attempts <= test.failures {
+					return test.err
+				}
+				return nil
+			}), 2)
+			if err := sink.Write(t.Context(), NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", "2Gi")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// The records are written in order, so the first one is finished when the second one arrives.
+			if err := sink.Write(t.Context(), NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", "done")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			<-done
+			if attempts-1 != test.expectedAttempts {
+				t.Errorf("expected %d attempts, got %d", test.expectedAttempts, attempts-1)
+			}
+			after, err := testutil.GetCounterMetricValue(dropped)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dropped := after > before; dropped != test.expectDropped {
+				t.Errorf("expected dropped %v, got %v", test.expectDropped, dropped)
+			}
+		})
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if last, err := LastHMAC(path); err != nil || last != "" {
+		t.Fatalf("expected no HMAC for missing file, got %q, %v", last, err)
+	}
+
+	key := []byte("secret")
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv"}}
+	write := func(to string) {
+		last, err := LastHMAC(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileSink, err := NewSinkFromSpec("file:"+path, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		Emit(NewHMACChainSink(fileSink, key, last), NewRecord(OperationModify, "mock", testPVC(), pv, "silver", to).Finish("", nil))
+	}
+	// Simulate a restart between the records.
+	write("gold")
+	write("platinum")
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var records []*Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		r := &Record{}
+		if err := json.Unmarshal(scanner.Bytes(), r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if err := Verify(key, records); err != nil {
+		t.Errorf("unexpected verification error: %v", err)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	received := make(chan *Record, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r := &Record{}
+		if err := json.NewDecoder(req.Body).Decode(r); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- r
+	}))
+	defer server.Close()
+
+	sink, err := NewSinkFromSpec(server.URL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv"}}
+	if err := sink.Write(t.Context(), NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", "2Gi")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := <-received; r.PVName != "pv" || r.To != "2Gi" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+	var permanent *permanentError
+	if err := NewHTTPSink(rejecting.URL, time.Second).Write(t.Context(), NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", "2Gi")); !errors.As(err, &permanent) {
+		t.Errorf("expected permanent error for a rejected record, got %v", err)
+	}
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+	if err := NewHTTPSink(unavailable.URL, time.Second).Write(t.Context(), NewRecord(OperationExpand, "mock", testPVC(), pv, "1Gi", "2Gi")); err == nil || errors.As(err, &permanent) {
+		t.Errorf("expected temporary error for an unavailable destination, got %v", err)
+	}
+
+	if _, err := NewSinkFromSpec("syslog", time.Second); err == nil {
+		t.Errorf("expected error for unknown sink")
+	}
+}
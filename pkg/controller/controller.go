@@ -24,7 +24,14 @@ import (
 	"time"
 
 	"github.com/kubernetes-csi/csi-lib-utils/slowset"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/audit"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/coordinator"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/credentials"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/dispatcher"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/expansionhistory"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/features"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/pricing"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/sharding"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/resizer"
@@ -32,9 +39,10 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -43,7 +51,6 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
-	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -70,8 +77,8 @@ type resizeController struct {
 	finalErrorPVCs   sets.Set[string]
 	finalErrorPVCsMu sync.RWMutex
 
-	podLister       corelisters.PodLister
-	podListerSynced cache.InformerSynced
+	// inUseSynced reports whether the informer used for in-use tracking, of Pods or VolumeAttachments, has synced.
+	inUseSynced cache.InformerSynced
 
 	// slowSet is used to track PVCs for which expansion failed with infeasible error
 	// and should be retried at slower rate.
@@ -82,6 +89,48 @@ type resizeController struct {
 	// a cache to store PersistentVolumeClaim objects
 	claims                 cache.Store
 	handleVolumeInUseError bool
+
+	// auditSink receives a record of every expansion, nil disables auditing.
+	auditSink audit.Sink
+	// costEstimator annotates PVCs with the cost impact of expansions, nil disables it.
+	costEstimator *pricing.Estimator
+	// shards limits the controller to the PVCs of the shards this replica owns, nil handles all PVCs.
+	shards *sharding.Manager
+	// secretTemplates resolves expansion secrets from StorageClass templates, nil uses the secret of the PV.
+	secretTemplates *credentials.TemplateResolver
+	// extraMetadata selects the PV and PVC metadata passed to the resizer, nil passes none.
+	extraMetadata *util.ExtraMetadata
+	// coordinator serializes expansion and modification of PVCs, nil lets them run independently.
+	coordinator *coordinator.Coordinator
+	// dispatcher runs the workers of the PVCs shared with the modify controller, nil runs them here.
+	dispatcher *dispatcher.Dispatcher
+	// expansionHistoryLimit is the number of expansions recorded on each PV, 0 disables the history.
+	expansionHistoryLimit int
+	// invalidSchedules holds the annotation values of invalid scheduled resizes that were reported, by PVC key.
+	invalidSchedules sync.Map
+}
+
+// Options are the optional features of the resize controller. The zero value disables all of them.
+type Options struct {
+	// AuditSink receives a record of every expansion, nil disables auditing.
+	AuditSink audit.Sink
+	// CostEstimator annotates PVCs with the cost impact of expansions, nil disables it.
+	CostEstimator *pricing.Estimator
+	// Shards limits the controller to the PVCs of the shards this replica owns, nil handles all PVCs.
+	Shards *sharding.Manager
+	// UseVolumeAttachments tracks volumes in use with VolumeAttachments instead of Pods,
+	// when volume in use errors are handled.
+	UseVolumeAttachments bool
+	// SecretTemplates resolves expansion secrets from StorageClass templates, nil uses the secret of the PV.
+	SecretTemplates *credentials.TemplateResolver
+	// ExtraMetadata selects the PV and PVC metadata passed to the resizer, nil passes none.
+	ExtraMetadata *util.ExtraMetadata
+	// Coordinator serializes expansion and modification of PVCs, nil lets them run independently.
+	Coordinator *coordinator.Coordinator
+	// Dispatcher runs the workers of the PVCs shared with the modify controller, nil runs them here.
+	Dispatcher *dispatcher.Dispatcher
+	// ExpansionHistoryLimit is the number of expansions recorded on each PV, 0 disables the history.
+	ExpansionHistoryLimit int
 }
 
 // NewResizeController returns a ResizeController.
@@ -93,7 +142,9 @@ func NewResizeController(
 	informerFactory informers.SharedInformerFactory,
 	pvcRateLimiter workqueue.TypedRateLimiter[string],
 	handleVolumeInUseError bool,
-	maxRetryInterval time.Duration) ResizeController {
+	maxRetryInterval time.Duration,
+	opts Options) ResizeController {
+	shardManager, coord, pvcDispatcher := opts.Shards, opts.Coordinator, opts.Dispatcher
 	pvInformer := informerFactory.Core().V1().PersistentVolumes()
 	pvcInformer := informerFactory.Core().V1().PersistentVolumeClaims()
 	eventBroadcaster := record.NewBroadcaster()
@@ -102,10 +153,15 @@ func NewResizeController(
 	eventRecorder := eventBroadcaster.NewRecorder(scheme.Scheme,
 		v1.EventSource{Component: fmt.Sprintf("external-resizer %s", name)})
 
-	claimQueue := workqueue.NewTypedRateLimitingQueueWithConfig(
-		pvcRateLimiter, workqueue.TypedRateLimitingQueueConfig[string]{
-			Name: fmt.Sprintf("%s-pvc", name),
-		})
+	var claimQueue workqueue.TypedRateLimitingInterface[string]
+	if pvcDispatcher != nil {
+		claimQueue = pvcDispatcher.Queue(coordinator.Resize, pvcRateLimiter)
+	} else {
+		claimQueue = workqueue.NewTypedRateLimitingQueueWithConfig(
+			pvcRateLimiter, workqueue.TypedRateLimitingQueueConfig[string]{
+				Name: fmt.Sprintf("%s-pvc", name),
+			})
+	}
 
 	ctrl := &resizeController{
 		name:                   name,
@@ -121,22 +177,57 @@ func NewResizeController(
 		finalErrorPVCs:         sets.New[string](),
 		usedPVCs:               newUsedPVCStore(),
 		handleVolumeInUseError: handleVolumeInUseError,
+		auditSink:              opts.AuditSink,
+		costEstimator:          opts.CostEstimator,
+		shards:                 shardManager,
+		secretTemplates:        opts.SecretTemplates,
+		extraMetadata:          opts.ExtraMetadata,
+		coordinator:            coord,
+		dispatcher:             pvcDispatcher,
+		expansionHistoryLimit:  opts.ExpansionHistoryLimit,
+	}
+	shardManager.AddHandler(ctrl)
+	coord.Register(coordinator.Resize, claimQueue.Add)
+
+	if pvcDispatcher != nil {
+		pvcDispatcher.Register(coordinator.Resize, dispatcher.Handler{
+			AddPVC:    ctrl.addPVC,
+			UpdatePVC: ctrl.updatePVC,
+			DeletePVC: ctrl.deletePVC,
+			Sync:      ctrl.processPVC,
+		})
+	} else {
+		// Add a resync period as the PVC's request size can be resized again when we handling
+		// a previous resizing request of the same PVC.
+		pvcInformer.Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
+			AddFunc:    ctrl.addPVC,
+			UpdateFunc: ctrl.updatePVC,
+			DeleteFunc: ctrl.deletePVC,
+		}, resyncPeriod)
 	}
 
-	// Add a resync period as the PVC's request size can be resized again when we handling
-	// a previous resizing request of the same PVC.
-	pvcInformer.Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
-		AddFunc:    ctrl.addPVC,
-		UpdateFunc: ctrl.updatePVC,
-		DeleteFunc: ctrl.deletePVC,
-	}, resyncPeriod)
-
-	if handleVolumeInUseError {
+	if handleVolumeInUseError && opts.UseVolumeAttachments {
+		// track attachments of volumes of the driver, so as we can identify PVCs that are in-use
+		klog.InfoS("Register VolumeAttachment informer for resizer", "controller", ctrl.name)
+		ctrl.usedPVCs.useAttachments = true
+		vaInformer := informerFactory.Storage().V1().VolumeAttachments()
+		ctrl.inUseSynced = vaInformer.Informer().HasSynced
+		vaInformer.Informer().AddEventHandlerWithResyncPeriod(cache.FilteringResourceEventHandler{
+			FilterFunc: ctrl.isDriverVolumeAttachment,
+			Handler: cache.ResourceEventHandlerFuncs{
+				AddFunc:    ctrl.addVolumeAttachment,
+				DeleteFunc: ctrl.deleteVolumeAttachment,
+			},
+		}, resyncPeriod)
+	} else if handleVolumeInUseError {
 		// list pods so as we can identify PVC that are in-use
 		klog.InfoS("Register Pod informer for resizer", "controller", ctrl.name)
 		podInformer := informerFactory.Core().V1().Pods()
-		ctrl.podLister = podInformer.Lister()
-		ctrl.podListerSynced = podInformer.Informer().HasSynced
+		// Keep only what the in-use check needs, instead of caching full pods of the whole cluster.
+		if err := podInformer.Informer().SetTransform(stripPod); err != nil {
+			klog.ErrorS(err, "Failed to set Pod informer transform", "controller", ctrl.name)
+		}
+		ctrl.inUseSynced = podInformer.Informer().HasSynced
 		podInformer.Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
 			AddFunc:    ctrl.addPod,
 			DeleteFunc: ctrl.deletePod,
@@ -152,6 +243,9 @@ func (ctrl *resizeController) addPVC(obj any) {
 	if err != nil {
 		return
 	}
+	if !ctrl.shards.Owns(objKey) {
+		return
+	}
 	ctrl.claimQueue.Add(objKey)
 }
 
@@ -184,6 +278,44 @@ func (ctrl *resizeController) updatePod(oldObj, newObj any) {
 	}
 }
 
+func (ctrl *resizeController) isDriverVolumeAttachment(obj any) bool {
+	va := parseVolumeAttachment(obj)
+	return va != nil && va.Spec.Attacher == ctrl.name
+}
+
+func (ctrl *resizeController) addVolumeAttachment(obj any) {
+	va := parseVolumeAttachment(obj)
+	if va == nil {
+		return
+	}
+	ctrl.usedPVCs.addVolumeAttachment(va)
+}
+
+func (ctrl *resizeController) deleteVolumeAttachment(obj any) {
+	va := parseVolumeAttachment(obj)
+	if va == nil {
+		return
+	}
+	ctrl.usedPVCs.removeVolumeAttachment(va)
+
+	// The volume may be detached now, retry expansion of its PVC if it failed because the volume was in use.
+	if va.Spec.Source.PersistentVolumeName == nil {
+		return
+	}
+	obj, exists, err := ctrl.volumes.GetByKey(*va.Spec.Source.PersistentVolumeName)
+	if err != nil || !exists {
+		return
+	}
+	pv, ok := obj.(*v1.PersistentVolume)
+	if !ok || pv.Spec.ClaimRef == nil {
+		return
+	}
+	key := pv.Spec.ClaimRef.Namespace + "/" + pv.Spec.ClaimRef.Name
+	if ctrl.shards.Owns(key) {
+		ctrl.claimQueue.Add(key)
+	}
+}
+
 func (ctrl *resizeController) updatePVC(oldObj, newObj any) {
 	oldPVC, ok := oldObj.(*v1.PersistentVolumeClaim)
 	if !ok || oldPVC == nil {
@@ -194,6 +326,9 @@ func (ctrl *resizeController) updatePVC(oldObj, newObj any) {
 	if !ok || newPVC == nil {
 		return
 	}
+	if !ctrl.shards.Owns(newPVC.Namespace + "/" + newPVC.Name) {
+		return
+	}
 
 	newReq := newPVC.Spec.Resources.Requests[v1.ResourceStorage]
 	oldReq := oldPVC.Spec.Resources.Requests[v1.ResourceStorage]
@@ -237,7 +372,11 @@ func (ctrl *resizeController) updatePVC(oldObj, newObj any) {
 	// 3. An already expanded in-tree PVC:
 	// An in-tree PVC is resized with in-tree resizer. And later, CSI migration is turned on and resizer name is updated from
 	// in-tree resizer name to CSI driver name.
-	if pvcRequestSizeChanged || newResizerName != oldResizerName {
+	// A scheduled resize was added, changed or cancelled.
+	scheduleChanged := newPVC.Annotations[util.AnnScheduledSize] != oldPVC.Annotations[util.AnnScheduledSize] ||
+		newPVC.Annotations[util.AnnScheduledTime] != oldPVC.Annotations[util.AnnScheduledTime]
+
+	if pvcRequestSizeChanged || newResizerName != oldResizerName || scheduleChanged {
 		ctrl.addPVC(newObj)
 	} else {
 		// PVC's size not changed, so this Update event maybe caused by:
@@ -269,6 +408,8 @@ func (ctrl *resizeController) deletePVC(obj interface{}) {
 		return
 	}
 	ctrl.claimQueue.Forget(objKey)
+	ctrl.coordinator.Forget(objKey)
+	ctrl.invalidSchedules.Delete(objKey)
 }
 
 // Run starts the controller.
@@ -279,9 +420,9 @@ func (ctrl *resizeController) Run(workers int, ctx context.Context, wg *sync.Wai
 	defer klog.InfoS("Shutting down external resizer", "controller", ctrl.name)
 
 	stopCh := ctx.Done()
-	informersSyncd := []cache.InformerSynced{ctrl.pvSynced, ctrl.pvcSynced}
+	informersSyncd := []cache.InformerSynced{ctrl.pvSynced, ctrl.pvcSynced, ctrl.secretTemplates.HasSynced}
 	if ctrl.handleVolumeInUseError {
-		informersSyncd = append(informersSyncd, ctrl.podListerSynced)
+		informersSyncd = append(informersSyncd, ctrl.inUseSynced)
 	}
 
 	if !cache.WaitForCacheSync(stopCh, informersSyncd...) {
@@ -293,6 +434,12 @@ func (ctrl *resizeController) Run(workers int, ctx context.Context, wg *sync.Wai
 		go ctrl.slowSet.Run(stopCh)
 	}
 
+	if ctrl.dispatcher != nil {
+		ctrl.dispatcher.Ready(coordinator.Resize)
+		<-stopCh
+		return
+	}
+
 	if utilfeature.DefaultFeatureGate.Enabled(features.ReleaseLeaderElectionOnExit) {
 		for range workers {
 			wg.Go(func() {
@@ -316,10 +463,23 @@ func (ctrl *resizeController) syncPVCs() {
 	}
 	defer ctrl.claimQueue.Done(key)
 
+	ctrl.processPVC(key)
+}
+
+// processPVC syncs a PVC and enqueues it again if needed. It returns the error of the sync.
+func (ctrl *resizeController) processPVC(key string) error {
+	done, owned := ctrl.shards.Begin(key)
+	if !owned {
+		// Another replica handles this PVC now.
+		ctrl.claimQueue.Forget(key)
+		return nil
+	}
+	defer done()
+
 	err := ctrl.syncPVC(key)
 
 	if err != nil {
-		if utilfeature.DefaultFeatureGate.Enabled(features.RecoverVolumeExpansionFailure) && util.IsDelayRetryError(err) {
+		if util.IsDelayRetryError(err) {
 			// If the error is a DelayRetryError, we should requeue the PVC with a delay.
 			delayRetryError := err.(*util.DelayRetryError)
 			ctrl.claimQueue.AddAfter(key, delayRetryError.TryAfter())
@@ -331,6 +491,7 @@ func (ctrl *resizeController) syncPVCs() {
 	} else {
 		ctrl.claimQueue.Forget(key)
 	}
+	return err
 }
 
 // syncPVC checks if a pvc requests resizing, and execute the resize operation if requested.
@@ -376,9 +537,22 @@ func (ctrl *resizeController) syncPVC(key string) error {
 		return fmt.Errorf("expected volume but got %+v", volumeObj)
 	}
 
-	if utilfeature.DefaultFeatureGate.Enabled(features.AnnotateFsResize) && ctrl.isNodeExpandComplete(pvc, pv) && metav1.HasAnnotation(pv.ObjectMeta, util.AnnPreResizeCapacity) {
-		if err := ctrl.deletePreResizeCapAnnotation(pv); err != nil {
-			return fmt.Errorf("failed removing annotation %s from pv %q: %v", util.AnnPreResizeCapacity, pv.Name, err)
+	pvc, scheduledIn, err := ctrl.syncScheduledResize(pvc, pv)
+	if err != nil {
+		return err
+	}
+	if err := ctrl.syncResize(pvc, pv); err != nil || scheduledIn <= 0 {
+		return err
+	}
+	return util.NewDelayRetryError(fmt.Sprintf("resize of PVC %s is scheduled in %s", klog.KObj(pvc), scheduledIn), scheduledIn)
+}
+
+// syncResize executes the resize operation requested by pvc, if any.
+func (ctrl *resizeController) syncResize(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) error {
+	annotated := utilfeature.DefaultFeatureGate.Enabled(features.AnnotateFsResize) && metav1.HasAnnotation(pv.ObjectMeta, util.AnnPreResizeCapacity)
+	if ctrl.isNodeExpandComplete(pvc, pv) && (annotated || expansionhistory.NodeExpansionPending(pv)) {
+		if err := ctrl.finishNodeExpansion(pvc, pv); err != nil {
+			return fmt.Errorf("failed recording finished node expansion on pv %q: %v", pv.Name, err)
 		}
 	}
 
@@ -387,6 +561,15 @@ func (ctrl *resizeController) syncPVC(key string) error {
 		return nil
 	}
 
+	done, wait, changed := ctrl.coordinator.Begin(pvc, coordinator.Resize)
+	if wait != "" {
+		if changed {
+			ctrl.eventRecorder.Event(pvc, v1.EventTypeNormal, util.VolumeOperationWaiting, wait)
+		}
+		return util.NewDelayRetryError(wait, coordinator.RetryInterval)
+	}
+	defer done()
+
 	if utilfeature.DefaultFeatureGate.Enabled(features.RecoverVolumeExpansionFailure) {
 		_, _, err, _ := ctrl.expandAndRecover(pvc, pv)
 		return err
@@ -475,9 +658,11 @@ func (ctrl *resizeController) resizePVC(pvc *v1.PersistentVolumeClaim, pv *v1.Pe
 		return errors.New(msg)
 	}
 
+	pvc, costNote := ctrl.annotateCostEstimate(pvc, pv, pvc.Spec.Resources.Requests[v1.ResourceStorage])
+
 	// Record an event to indicate that external resizer is resizing this volume.
 	ctrl.eventRecorder.Event(pvc, v1.EventTypeNormal, util.VolumeResizing,
-		fmt.Sprintf("External resizer is resizing volume %s", pv.Name))
+		fmt.Sprintf("External resizer is resizing volume %s%s", pv.Name, costNote))
 
 	err := func() error {
 		newSize, fsResizeRequired, err := ctrl.resizeVolume(pvc, pv)
@@ -513,7 +698,16 @@ func (ctrl *resizeController) resizeVolume(
 
 	requestSize := pvc.Spec.Resources.Requests[v1.ResourceStorage]
 
-	newSize, fsResizeRequired, err := ctrl.resizer.Resize(pv, requestSize)
+	resizePV, err := ctrl.withSecretTemplate(pvc, pv)
+	if err != nil {
+		return pvc.Status.Capacity[v1.ResourceStorage], false, err
+	}
+
+	pvSize := pv.Spec.Capacity[v1.ResourceStorage]
+	record := audit.NewRecord(audit.OperationExpand, ctrl.name, pvc, pv, pvSize.String(), requestSize.String())
+	started := time.Now()
+	newSize, fsResizeRequired, err := ctrl.resizer.Resize(resizePV, requestSize, ctrl.metadata(pvc, pv))
+	audit.Emit(ctrl.auditSink, record.Finish(fmt.Sprintf("capacity=%s nodeExpansionRequired=%t", newSize.String(), fsResizeRequired), err))
 
 	if err != nil {
 		// if this error was a in-use error then it must be tracked so as we don't retry without
@@ -525,7 +719,7 @@ func (ctrl *resizeController) resizeVolume(
 	}
 	klog.V(4).InfoS("Resize volume succeeded start to update PV's capacity", "PV", klog.KObj(pv))
 
-	_, err = ctrl.updatePVCapacity(pv, pvc.Status.Capacity[v1.ResourceStorage], newSize, fsResizeRequired)
+	_, err = ctrl.updatePVCapacity(pvc, pv, pvc.Status.Capacity[v1.ResourceStorage], newSize, fsResizeRequired, started)
 	if err != nil {
 		return newSize, fsResizeRequired, err
 	}
@@ -534,6 +728,30 @@ func (ctrl *resizeController) resizeVolume(
 	return newSize, fsResizeRequired, nil
 }
 
+// withSecretTemplate returns pv with the expansion secret resolved from the templates in its StorageClass.
+// The returned PV is only passed to the resizer and must not be saved.
+func (ctrl *resizeController) withSecretTemplate(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) (*v1.PersistentVolume, error) {
+	ref, err := ctrl.secretTemplates.ExpandSecretRef(pv, pvc)
+	if err != nil {
+		ctrl.eventRecorder.Event(pvc, v1.EventTypeWarning, util.InvalidSecretTemplate, err.Error())
+		return pv, fmt.Errorf("failed to resolve expansion secret of volume %q: %v", pv.Name, err)
+	}
+	if ref == nil || pv.Spec.CSI == nil {
+		return pv, nil
+	}
+	pv = pv.DeepCopy()
+	pv.Spec.CSI.ControllerExpandSecretRef = ref
+	return pv, nil
+}
+
+// metadata returns the metadata of pvc and pv that is passed to the resizer, or nil if disabled.
+func (ctrl *resizeController) metadata(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) map[string]string {
+	if ctrl.extraMetadata == nil {
+		return nil
+	}
+	return ctrl.extraMetadata.Parameters(pvc, pv)
+}
+
 func (ctrl *resizeController) markPVCAsFSResizeRequired(pvc *v1.PersistentVolumeClaim) error {
 	pvcCondition := v1.PersistentVolumeClaimCondition{
 		Type:               v1.PersistentVolumeClaimFileSystemResizePending,
@@ -545,7 +763,7 @@ func (ctrl *resizeController) markPVCAsFSResizeRequired(pvc *v1.PersistentVolume
 	newPVC.Status.Conditions = util.MergeResizeConditionsOfPVC(newPVC.Status.Conditions,
 		[]v1.PersistentVolumeClaimCondition{pvcCondition}, false /*keepOldResizeCondition*/)
 
-	updatedPVC, err := util.PatchClaim(ctrl.kubeClient, pvc, newPVC, true /* addResourceVersionCheck */)
+	updatedPVC, err := util.ApplyClaimStatus(ctrl.kubeClient, resizeOwner, pvc, newPVC, true /* addResourceVersionCheck */)
 	if err != nil {
 		return fmt.Errorf("mark PVC %q as file system resize required failed: %w", klog.KObj(pvc), err)
 	}
@@ -574,7 +792,7 @@ func (ctrl *resizeController) markPVCResizeInProgress(pvc *v1.PersistentVolumeCl
 	newPVC.Status.Conditions = util.MergeResizeConditionsOfPVC(newPVC.Status.Conditions,
 		[]v1.PersistentVolumeClaimCondition{progressCondition}, false /*keepOldResizeCondition*/)
 
-	updatedPVC, err := util.PatchClaim(ctrl.kubeClient, pvc, newPVC, true /* addResourceVersionCheck */)
+	updatedPVC, err := util.ApplyClaimStatus(ctrl.kubeClient, resizeOwner, pvc, newPVC, true /* addResourceVersionCheck */)
 	if err != nil {
 		return updatedPVC, fmt.Errorf("Mark PVC %q as resize as in progress failed: %v", klog.KObj(pvc), err)
 	}
@@ -592,7 +810,7 @@ func (ctrl *resizeController) markPVCResizeFinished(
 	newPVC.Status.Capacity[v1.ResourceStorage] = newSize
 	newPVC.Status.Conditions = util.MergeResizeConditionsOfPVC(pvc.Status.Conditions, []v1.PersistentVolumeClaimCondition{}, false /*keepOldResizeCondition*/)
 
-	updatedPVC, err := util.PatchClaim(ctrl.kubeClient, pvc, newPVC, true /* addResourceVersionCheck */)
+	updatedPVC, err := util.ApplyClaimStatus(ctrl.kubeClient, resizeOwner, pvc, newPVC, true /* addResourceVersionCheck */)
 	if err != nil {
 		return fmt.Errorf("Mark PVC %q as resize finished failed: %w", klog.KObj(pvc), err)
 	}
@@ -608,27 +826,50 @@ func (ctrl *resizeController) markPVCResizeFinished(
 	return nil
 }
 
-func (ctrl *resizeController) deletePreResizeCapAnnotation(pv *v1.PersistentVolume) error {
-	// if the pv does not have a resize annotation skip the entire process
-	if !metav1.HasAnnotation(pv.ObjectMeta, util.AnnPreResizeCapacity) {
+// finishNodeExpansion removes the pre-resize capacity annotation from pv and finishes its expansion
+// history, after the volume was expanded on the node.
+func (ctrl *resizeController) finishNodeExpansion(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) error {
+	pvClone := pv.DeepCopy()
+	if utilfeature.DefaultFeatureGate.Enabled(features.AnnotateFsResize) {
+		delete(pvClone.ObjectMeta.Annotations, util.AnnPreResizeCapacity)
+	}
+	record := expansionhistory.FinishNodeExpansion(pvClone, time.Now())
+	if apiequality.Semantic.DeepEqual(pv.Annotations, pvClone.Annotations) {
 		return nil
 	}
-	pvClone := pv.DeepCopy()
-	delete(pvClone.ObjectMeta.Annotations, util.AnnPreResizeCapacity)
 
-	_, err := ctrl.patchPersistentVolume(pv, pvClone)
-	return err
+	if _, err := ctrl.patchPersistentVolume(pv, pvClone); err != nil {
+		return err
+	}
+	if record != nil {
+		ctrl.eventRecorder.Eventf(pvc, v1.EventTypeNormal, util.VolumeExpansionRecorded,
+			"Expanded volume from %s to %s in %s, including the expansion on the node", record.From.String(), record.To.String(), record.Duration.Duration)
+	}
+	return nil
 }
 
 func (ctrl *resizeController) updatePVCapacity(
+	pvc *v1.PersistentVolumeClaim,
 	pv *v1.PersistentVolume,
 	oldCapacity, newCapacity resource.Quantity,
-	fsResizeRequired bool) (*v1.PersistentVolume, error) {
+	fsResizeRequired bool,
+	started time.Time) (*v1.PersistentVolume, error) {
 
 	klog.V(4).InfoS("Resize volume succeeded, start to update PV's capacity", "PV", klog.KObj(pv))
 	newPV := pv.DeepCopy()
 	newPV.Spec.Capacity[v1.ResourceStorage] = newCapacity
 
+	record := expansionhistory.Record{
+		Started:               metav1.NewTime(started),
+		From:                  oldCapacity,
+		To:                    newCapacity,
+		NodeExpansionRequired: fsResizeRequired,
+	}
+	if !fsResizeRequired {
+		record.Duration = &metav1.Duration{Duration: time.Since(started)}
+	}
+	expansionhistory.Append(newPV, record, ctrl.expansionHistoryLimit)
+
 	if utilfeature.DefaultFeatureGate.Enabled(features.AnnotateFsResize) && fsResizeRequired {
 		// only update annotation if there already isn't one
 		if !metav1.HasAnnotation(pv.ObjectMeta, util.AnnPreResizeCapacity) {
@@ -643,9 +884,41 @@ func (ctrl *resizeController) updatePVCapacity(
 	if err != nil {
 		return pv, fmt.Errorf("updating capacity of PV %q to %s failed: %v", pv.Name, newCapacity.String(), err)
 	}
+	if ctrl.expansionHistoryLimit > 0 && !fsResizeRequired {
+		ctrl.eventRecorder.Eventf(pvc, v1.EventTypeNormal, util.VolumeExpansionRecorded,
+			"Expanded volume from %s to %s in %s", oldCapacity.String(), newCapacity.String(), record.Duration.Round(time.Millisecond))
+	}
 	return updatedPV, nil
 }
 
+// annotateCostEstimate sets the estimated monthly cost change of expanding the volume of pvc to
+// newSize on the PVC, and returns a note for the resizing event. Failures don't block the expansion.
+func (ctrl *resizeController) annotateCostEstimate(
+	pvc *v1.PersistentVolumeClaim,
+	pv *v1.PersistentVolume,
+	newSize resource.Quantity) (*v1.PersistentVolumeClaim, string) {
+	if ctrl.costEstimator == nil {
+		return pvc, ""
+	}
+	estimate, err := ctrl.costEstimator.EstimateResize(pvc, pv.Spec.Capacity[v1.ResourceStorage], newSize)
+	if err != nil {
+		klog.ErrorS(err, "Failed to estimate cost of expansion", "PVC", klog.KObj(pvc))
+		return pvc, ""
+	}
+	costNote := fmt.Sprintf(", estimated monthly cost change %s", estimate)
+	updatedPVC, err := pricing.Annotate(ctrl.kubeClient, pvc, estimate)
+	if err != nil {
+		klog.ErrorS(err, "Failed to annotate PVC with cost estimate", "PVC", klog.KObj(pvc))
+		return pvc, costNote
+	}
+	if updatedPVC != pvc {
+		if err := ctrl.claims.Update(updatedPVC); err != nil {
+			klog.ErrorS(err, "Failed to update PVC in local cache", "PVC", klog.KObj(updatedPVC))
+		}
+	}
+	return updatedPVC, costNote
+}
+
 func parsePod(obj any) *v1.Pod {
 	if obj == nil {
 		return nil
@@ -666,14 +939,30 @@ func parsePod(obj any) *v1.Pod {
 	return pod
 }
 
+func parseVolumeAttachment(obj any) *storagev1.VolumeAttachment {
+	if obj == nil {
+		return nil
+	}
+	va, ok := obj.(*storagev1.VolumeAttachment)
+	if !ok {
+		staleObj, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("couldn't get stale object %#v", obj))
+			return nil
+		}
+		va, ok = staleObj.Obj.(*storagev1.VolumeAttachment)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("stale object is not a VolumeAttachment %#v", obj))
+			return nil
+		}
+	}
+	return va
+}
+
 func (ctrl *resizeController) patchPersistentVolume(oldPV, newPV *v1.PersistentVolume) (*v1.PersistentVolume, error) {
-	patchBytes, err := util.GetPatchData(oldPV, newPV)
+	updatedPV, err := util.ApplyPersistentVolume(ctrl.kubeClient, resizeOwner, oldPV, newPV)
 	if err != nil {
-		return nil, fmt.Errorf("can't update capacity of PV %s as generate path data failed: %v", newPV.Name, err)
-	}
-	updatedPV, updateErr := ctrl.kubeClient.CoreV1().PersistentVolumes().Patch(context.TODO(), newPV.Name, types.StrategicMergePatchType, patchBytes, metav1.PatchOptions{})
-	if updateErr != nil {
-		return nil, fmt.Errorf("update capacity of PV %s failed: %v", newPV.Name, updateErr)
+		return nil, err
 	}
 	err = ctrl.volumes.Update(updatedPV)
 	if err != nil {
@@ -720,3 +1009,33 @@ func (ctrl *resizeController) removeFinalError(pvcKey string) {
 	defer ctrl.finalErrorPVCsMu.Unlock()
 	ctrl.finalErrorPVCs.Delete(pvcKey)
 }
+
+// ShardAcquired drops state about PVCs of the shard, which may be outdated, and enqueues them.
+func (ctrl *resizeController) ShardAcquired(shard int) {
+	ctrl.forgetShard(shard)
+	for _, key := range ctrl.claims.ListKeys() {
+		if ctrl.shards.Shard(key) == shard {
+			ctrl.claimQueue.Add(key)
+		}
+	}
+}
+
+// ShardReleased drops state about PVCs of the shard, another replica takes over from their status.
+func (ctrl *resizeController) ShardReleased(shard int) {
+	ctrl.forgetShard(shard)
+}
+
+func (ctrl *resizeController) forgetShard(shard int) {
+	for _, key := range ctrl.claims.ListKeys() {
+		if ctrl.shards.Shard(key) == shard {
+			ctrl.slowSet.Remove(key)
+		}
+	}
+	ctrl.finalErrorPVCsMu.Lock()
+	defer ctrl.finalErrorPVCsMu.Unlock()
+	for key := range ctrl.finalErrorPVCs {
+		if ctrl.shards.Shard(key) == shard {
+			ctrl.finalErrorPVCs.Delete(key)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,11 +14,14 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/util/workqueue"
 
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/credentials"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/csi"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/expansionhistory"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/resizer"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
 
 	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -237,7 +241,7 @@ func TestController(t *testing.T) {
 		pvcInformer := informerFactory.Core().V1().PersistentVolumeClaims()
 		podInformer := informerFactory.Core().V1().Pods()
 
-		csiResizer, err := resizer.NewResizerFromClient(client, 15*time.Second, kubeClient, driverName)
+		csiResizer, err := resizer.NewResizerFromClient(client, 15*time.Second, kubeClient, driverName, nil, resizer.MetadataNone)
 		if err != nil {
 			t.Fatalf("Test %s: Unable to create resizer: %v", test.Name, err)
 		}
@@ -247,7 +251,7 @@ func TestController(t *testing.T) {
 			kubeClient, time.Second,
 			informerFactory, workqueue.DefaultTypedControllerRateLimiter[string](),
 			!test.disableVolumeInUseErrorHandler,
-			2*time.Minute /* maxRetryInterval */)
+			2*time.Minute /* maxRetryInterval */, Options{})
 
 		ctrlInstance, _ := controller.(*resizeController)
 
@@ -400,7 +404,7 @@ func TestResizePVC(t *testing.T) {
 			pvcInformer := informerFactory.Core().V1().PersistentVolumeClaims()
 			podInformer := informerFactory.Core().V1().Pods()
 
-			csiResizer, err := resizer.NewResizerFromClient(client, 15*time.Second, kubeClient, driverName)
+			csiResizer, err := resizer.NewResizerFromClient(client, 15*time.Second, kubeClient, driverName, nil, resizer.MetadataNone)
 			if err != nil {
 				t.Fatalf("Unable to create resizer: %v", err)
 			}
@@ -410,7 +414,7 @@ func TestResizePVC(t *testing.T) {
 				kubeClient, time.Second,
 				informerFactory, workqueue.DefaultTypedControllerRateLimiter[string](),
 				true, /* disableVolumeInUseErrorHandler*/
-				2*time.Minute /* maxRetryInterval */)
+				2*time.Minute /* maxRetryInterval */, Options{})
 
 			ctrlInstance, _ := controller.(*resizeController)
 
@@ -462,6 +466,64 @@ func TestResizePVC(t *testing.T) {
 	}
 }
 
+func TestExpansionHistory(t *testing.T) {
+	fsVolumeMode := v1.PersistentVolumeFilesystem
+	client := csi.NewMockClient("mock", true /* nodeResize */, true, false, true, true)
+	driverName, _ := client.GetDriverName(context.TODO())
+	pvc := createPVC(2, 1)
+	pv := createPV(1, "testPVC", defaultNS, "foobar", &fsVolumeMode)
+	pv.Spec.PersistentVolumeSource.CSI.Driver = driverName
+
+	kubeClient, informerFactory := fakeK8s([]runtime.Object{pvc, pv})
+	informerFactory.Core().V1().PersistentVolumes().Informer().GetStore().Add(pv)
+	informerFactory.Core().V1().PersistentVolumeClaims().Informer().GetStore().Add(pvc)
+	csiResizer, err := resizer.NewResizerFromClient(client, 15*time.Second, kubeClient, driverName, nil, resizer.MetadataNone)
+	if err != nil {
+		t.Fatalf("Unable to create resizer: %v", err)
+	}
+	featuregatetesting.SetFeatureGateDuringTest(t, utilfeature.DefaultFeatureGate, features.RecoverVolumeExpansionFailure, false)
+	controller := NewResizeController(driverName, csiResizer, kubeClient, time.Second, informerFactory,
+		workqueue.DefaultTypedControllerRateLimiter[string](), true, 2*time.Minute, Options{ExpansionHistoryLimit: 5})
+	ctrl := controller.(*resizeController)
+	recorder := record.NewFakeRecorder(10)
+	ctrl.eventRecorder = recorder
+
+	if err := ctrl.resizePVC(pvc, pv); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	volObj, _, _ := ctrl.volumes.GetByKey("testPV")
+	pv = volObj.(*v1.PersistentVolume)
+	records := expansionhistory.Get(pv)
+	if len(records) != 1 || !records[0].NodeExpansionRequired || records[0].Finished() ||
+		records[0].From.Cmp(quantityGB(1)) != 0 || records[0].To.Cmp(quantityGB(2)) != 0 {
+		t.Fatalf("expected an expansion from 1Gi to 2Gi waiting for the node, got %+v", records)
+	}
+
+	// The volume was expanded on the node.
+	pvcObj, _, _ := ctrl.claims.GetByKey(defaultNS + "/testPVC")
+	pvc = pvcObj.(*v1.PersistentVolumeClaim).DeepCopy()
+	pvc.Status.Capacity[v1.ResourceStorage] = quantityGB(2)
+	pvc.Status.Conditions = nil
+	if err := ctrl.syncResize(pvc, pv); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	volObj, _, _ = ctrl.volumes.GetByKey("testPV")
+	pv = volObj.(*v1.PersistentVolume)
+	if records := expansionhistory.Get(pv); len(records) != 1 || !records[0].Finished() {
+		t.Errorf("expected the expansion finished, got %+v", records)
+	}
+	if _, ok := pv.Annotations[util.AnnPreResizeCapacity]; ok {
+		t.Errorf("expected annotation %s removed", util.AnnPreResizeCapacity)
+	}
+	var recorded bool
+	for len(recorder.Events) > 0 {
+		recorded = recorded || strings.HasPrefix(<-recorder.Events, "Normal "+util.VolumeExpansionRecorded+" Expanded volume from 1Gi to 2Gi in ")
+	}
+	if !recorded {
+		t.Errorf("expected %s event", util.VolumeExpansionRecorded)
+	}
+}
+
 func checkPreResizeCap(t *testing.T, testName string, pv *v1.PersistentVolume, expectedCap string) {
 	if pv.ObjectMeta.Annotations == nil {
 		t.Errorf("for %s, AnnpreResizeCapacity was not successfully updated, expected: %s, received: nil Annotations", testName, expectedCap)
@@ -548,3 +610,43 @@ func fakeK8s(objs []runtime.Object) (kubernetes.Interface, informers.SharedInfor
 	informerFactory := informers.NewSharedInformerFactory(client, 0)
 	return client, informerFactory
 }
+
+func TestWithSecretTemplate(t *testing.T) {
+	sc := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "fast"},
+		Parameters: map[string]string{
+			credentials.ExpandSecretNameKey:      "${pvc.annotations['example.com/secret']}",
+			credentials.ExpandSecretNamespaceKey: "${pvc.namespace}",
+		},
+	}
+	_, informerFactory := fakeK8s([]runtime.Object{sc})
+	secretTemplates := credentials.NewTemplateResolver(informerFactory)
+	informerFactory.Start(t.Context().Done())
+	informerFactory.WaitForCacheSync(t.Context().Done())
+
+	recorder := record.NewFakeRecorder(10)
+	ctrl := &resizeController{eventRecorder: recorder, secretTemplates: secretTemplates}
+	pvc := createPVC(2, 1)
+	pv := createPV(1, pvc.Name, pvc.Namespace, pvc.UID, nil)
+	pv.Spec.StorageClassName = sc.Name
+
+	if _, err := ctrl.withSecretTemplate(pvc, pv); err == nil {
+		t.Errorf("expected error for missing annotation")
+	}
+	if event := <-recorder.Events; !strings.Contains(event, util.InvalidSecretTemplate) {
+		t.Errorf("expected %s event, got %q", util.InvalidSecretTemplate, event)
+	}
+
+	pvc.Annotations = map[string]string{"example.com/secret": "tenant-secret"}
+	resizePV, err := ctrl.withSecretTemplate(pvc, pv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &v1.SecretReference{Name: "tenant-secret", Namespace: pvc.Namespace}
+	if ref := resizePV.Spec.CSI.ControllerExpandSecretRef; ref == nil || *ref != *expected {
+		t.Errorf("expected secret %+v, got %+v", expected, ref)
+	}
+	if pv.Spec.CSI.ControllerExpandSecretRef != nil {
+		t.Errorf("expected the original PV to be unchanged")
+	}
+}
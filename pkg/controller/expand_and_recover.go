@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/kubernetes-csi/csi-lib-utils/slowset"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/audit"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -179,9 +180,11 @@ func (ctrl *resizeController) expandAndRecover(pvc *v1.PersistentVolumeClaim, pv
 		return pvc, pv, errors.New(msg), resizeNotCalled
 	}
 
+	pvc, costNote := ctrl.annotateCostEstimate(pvc, pv, newSize)
+
 	// Record an event to indicate that external resizer is resizing this volume.
 	ctrl.eventRecorder.Event(pvc, v1.EventTypeNormal, util.VolumeResizing,
-		fmt.Sprintf("External resizer is resizing volume %s", pv.Name))
+		fmt.Sprintf("External resizer is resizing volume %s%s", pv.Name, costNote))
 
 	// before trying expansion we will remove the PVC from map
 	// that tracks PVCs which can't be expanded when in-use. If
@@ -233,7 +236,16 @@ func (ctrl *resizeController) callResizeOnPlugin(
 	pvc *v1.PersistentVolumeClaim,
 	pv *v1.PersistentVolume,
 	newSize, oldSize resource.Quantity) (*v1.PersistentVolumeClaim, *v1.PersistentVolume, error) {
-	updatedSize, fsResizeRequired, err := ctrl.resizer.Resize(pv, newSize)
+	resizePV, err := ctrl.withSecretTemplate(pvc, pv)
+	if err != nil {
+		return pvc, pv, err
+	}
+
+	pvSize := pv.Spec.Capacity[v1.ResourceStorage]
+	record := audit.NewRecord(audit.OperationExpand, ctrl.name, pvc, pv, pvSize.String(), newSize.String())
+	started := time.Now()
+	updatedSize, fsResizeRequired, err := ctrl.resizer.Resize(resizePV, newSize, ctrl.metadata(pvc, pv))
+	audit.Emit(ctrl.auditSink, record.Finish(fmt.Sprintf("capacity=%s nodeExpansionRequired=%t", updatedSize.String(), fsResizeRequired), err))
 
 	pvcKey, objectKeyError := util.GetObjectKey(pvc)
 	if objectKeyError != nil {
@@ -276,7 +288,7 @@ func (ctrl *resizeController) callResizeOnPlugin(
 
 	klog.V(4).InfoS("Resize volume succeeded, start to update PV's capacity", "PV", klog.KObj(pv))
 
-	pv, err = ctrl.updatePVCapacity(pv, oldSize, updatedSize, fsResizeRequired)
+	pv, err = ctrl.updatePVCapacity(pvc, pv, oldSize, updatedSize, fsResizeRequired, started)
 	if err != nil {
 		return pvc, pv, fmt.Errorf("error updating pv %q by resizer: %v", pv.Name, err)
 	}
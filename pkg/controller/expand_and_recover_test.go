@@ -174,7 +174,7 @@ func TestExpandAndRecover(t *testing.T) {
 
 			kubeClient, informerFactory := fakeK8s(initialObjects)
 
-			csiResizer, err := resizer.NewResizerFromClient(client, 15*time.Second, kubeClient, driverName)
+			csiResizer, err := resizer.NewResizerFromClient(client, 15*time.Second, kubeClient, driverName, nil, resizer.MetadataNone)
 			if err != nil {
 				t.Fatalf("Test %s: Unable to create resizer: %v", test.name, err)
 			}
@@ -182,7 +182,7 @@ func TestExpandAndRecover(t *testing.T) {
 			controller := NewResizeController(driverName,
 				csiResizer, kubeClient,
 				time.Second, informerFactory,
-				workqueue.DefaultTypedControllerRateLimiter[string](), true /*handleVolumeInUseError*/, 2*time.Minute /*maxRetryInterval*/)
+				workqueue.DefaultTypedControllerRateLimiter[string](), true /*handleVolumeInUseError*/, 2*time.Minute /*maxRetryInterval*/, Options{})
 
 			ctrlInstance, _ := controller.(*resizeController)
 			recorder := record.NewFakeRecorder(10)
@@ -245,7 +245,7 @@ func TestExpandAndRecoverConcurrent(t *testing.T) {
 
 	kubeClient, informerFactory := fakeK8s(initialObjects)
 
-	csiResizer, err := resizer.NewResizerFromClient(client, 15*time.Second, kubeClient, driverName)
+	csiResizer, err := resizer.NewResizerFromClient(client, 15*time.Second, kubeClient, driverName, nil, resizer.MetadataNone)
 	if err != nil {
 		t.Fatalf("Unable to create resizer: %v", err)
 	}
@@ -253,7 +253,7 @@ func TestExpandAndRecoverConcurrent(t *testing.T) {
 	controller := NewResizeController(driverName,
 		csiResizer, kubeClient,
 		time.Second, informerFactory,
-		workqueue.DefaultTypedControllerRateLimiter[string](), true, 2*time.Minute)
+		workqueue.DefaultTypedControllerRateLimiter[string](), true, 2*time.Minute, Options{})
 
 	ctrlInstance := controller.(*resizeController)
 	ctrlInstance.eventRecorder = record.NewFakeRecorder(1000)
@@ -20,6 +20,8 @@ import (
 	"sync"
 
 	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
 
@@ -32,6 +34,10 @@ type UniquePVCName types.UID
 type inUsePVCStore struct {
 	// map of pvc unique name and number of pods using it.
 	inUsePVC map[string]map[UniquePodName]UniquePodName
+	// map of PV name and names of VolumeAttachments of the PV, when attachments are tracked.
+	attachedPVs map[string]map[string]bool
+	// useAttachments makes checkForUse consider VolumeAttachments instead of pods.
+	useAttachments bool
 	// map of PVC unique name and whether in-use error has been triggered for it.
 	inUseErrors map[UniquePVCName]bool
 	sync.RWMutex
@@ -41,6 +47,41 @@ func newUsedPVCStore() *inUsePVCStore {
 	return &inUsePVCStore{
 		inUseErrors: map[UniquePVCName]bool{},
 		inUsePVC:    map[string]map[UniquePodName]UniquePodName{},
+		attachedPVs: map[string]map[string]bool{},
+	}
+}
+
+// addVolumeAttachment records that the PV of va is attached, or being attached or detached.
+// A volume is considered in use until its VolumeAttachment is deleted, which happens only
+// after the volume was detached.
+func (store *inUsePVCStore) addVolumeAttachment(va *storagev1.VolumeAttachment) {
+	pvName := va.Spec.Source.PersistentVolumeName
+	if pvName == nil {
+		return
+	}
+	store.Lock()
+	defer store.Unlock()
+
+	attachments, ok := store.attachedPVs[*pvName]
+	if !ok {
+		attachments = map[string]bool{}
+		store.attachedPVs[*pvName] = attachments
+	}
+	attachments[va.Name] = true
+}
+
+func (store *inUsePVCStore) removeVolumeAttachment(va *storagev1.VolumeAttachment) {
+	pvName := va.Spec.Source.PersistentVolumeName
+	if pvName == nil {
+		return
+	}
+	store.Lock()
+	defer store.Unlock()
+
+	attachments := store.attachedPVs[*pvName]
+	delete(attachments, va.Name)
+	if len(attachments) == 0 {
+		delete(store.attachedPVs, *pvName)
 	}
 }
 
@@ -97,6 +138,9 @@ func (store *inUsePVCStore) checkForUse(pvc *v1.PersistentVolumeClaim) bool {
 	store.RLock()
 	defer store.RUnlock()
 
+	if store.useAttachments {
+		return len(store.attachedPVs[pvc.Spec.VolumeName]) > 0
+	}
 	pvcNameUniqueName := pvc.Namespace + "/" + pvc.Name
 	if _, ok := store.inUsePVC[pvcNameUniqueName]; ok {
 		return true
@@ -134,3 +178,41 @@ func isPodTerminated(pod *v1.Pod) bool {
 	podStatus := pod.Status
 	return podStatus.Phase == v1.PodFailed || podStatus.Phase == v1.PodSucceeded
 }
+
+// stripPod is a cache.TransformFunc that reduces a pod to its namespace, name, UID,
+// phase and PVC volumes, which is all inUsePVCStore needs. It also keeps the annotations,
+// owner references and start time, which the node expansion watchdog reads from the same
+// shared informer. Other consumers of the shared Pod informer must not need more.
+func stripPod(obj any) (any, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return obj, nil
+	}
+	stripped := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+			Annotations:     pod.Annotations,
+			OwnerReferences: pod.OwnerReferences,
+		},
+		Status: v1.PodStatus{
+			Phase:     pod.Status.Phase,
+			StartTime: pod.Status.StartTime,
+		},
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil {
+			stripped.Spec.Volumes = append(stripped.Spec.Volumes, v1.Volume{
+				Name: volume.Name,
+				VolumeSource: v1.VolumeSource{
+					PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+						ClaimName: volume.PersistentVolumeClaim.ClaimName,
+					},
+				},
+			})
+		}
+	}
+	return stripped, nil
+}
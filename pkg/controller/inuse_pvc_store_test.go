@@ -17,13 +17,18 @@ limitations under the License.
 package controller
 
 import (
+	"fmt"
 	"reflect"
+	goruntime "runtime"
 	"testing"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 )
 
 const (
@@ -154,3 +159,163 @@ func TestAddRemovePods(t *testing.T) {
 		})
 	}
 }
+
+func TestVolumeAttachments(t *testing.T) {
+	pvName := "pv1"
+	va := func(name string) *storagev1.VolumeAttachment {
+		return &storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: storagev1.VolumeAttachmentSpec{
+				Attacher: "mock",
+				NodeName: defaultNodeName,
+				Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+			},
+		}
+	}
+	pvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "claim", Namespace: defaultNS},
+		Spec:       v1.PersistentVolumeClaimSpec{VolumeName: pvName},
+	}
+
+	store := newUsedPVCStore()
+	store.useAttachments = true
+	// Pods are ignored when attachments are tracked.
+	store.addPod(withPVC("claim", pod()))
+	if store.checkForUse(pvc) {
+		t.Errorf("expected unattached volume not to be in use")
+	}
+
+	store.addVolumeAttachment(va("va1"))
+	store.addVolumeAttachment(va("va2"))
+	store.removeVolumeAttachment(va("va1"))
+	if !store.checkForUse(pvc) {
+		t.Errorf("expected volume to be in use while it has an attachment")
+	}
+	store.removeVolumeAttachment(va("va2"))
+	if store.checkForUse(pvc) {
+		t.Errorf("expected detached volume not to be in use")
+	}
+}
+
+func TestStripPod(t *testing.T) {
+	full := withPVC("claim", pod())
+	full.Labels = map[string]string{"app": "db"}
+	full.Annotations = map[string]string{"note": "kept"}
+	full.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "db-1"}}
+	full.Status.StartTime = &metav1.Time{Time: time.Unix(1000, 0)}
+	full.Spec.Containers = []v1.Container{{Name: "db", Image: "db:1"}}
+	full.Spec.Volumes = append(full.Spec.Volumes, v1.Volume{
+		Name:         "config",
+		VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{}},
+	})
+
+	obj, err := stripPod(full)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := withPVC("claim", pod())
+	expected.Spec.NodeName = ""
+	expected.Annotations = full.Annotations
+	expected.OwnerReferences = full.OwnerReferences
+	expected.Status.StartTime = full.Status.StartTime
+	if !reflect.DeepEqual(obj, expected) {
+		t.Errorf("expected %+v, got %+v", expected, obj)
+	}
+	// The transform must be idempotent.
+	again, _ := stripPod(obj)
+	if !reflect.DeepEqual(again, expected) {
+		t.Errorf("expected %+v, got %+v", expected, again)
+	}
+
+	tombstone := cache.DeletedFinalStateUnknown{Key: "default/pod1", Obj: full}
+	if obj, _ := stripPod(tombstone); !reflect.DeepEqual(obj, tombstone) {
+		t.Errorf("expected other objects to be returned unchanged")
+	}
+}
+
+// largePod returns a pod of a typical size, with labels, several containers,
+// status and a PVC among other volumes.
+func largePod(i int) *v1.Pod {
+	p := withPVC(fmt.Sprintf("claim-%d", i), pod())
+	p.Name = fmt.Sprintf("pod-%d", i)
+	p.UID = types.UID(fmt.Sprintf("uid-%d", i))
+	p.Labels = map[string]string{"app": "db", "pod-template-hash": "5d8f7c9b6d", "tier": "backend"}
+	p.Annotations = map[string]string{"kubectl.kubernetes.io/restartedAt": "2026-01-01T00:00:00Z"}
+	p.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "db-5d8f7c9b6d", UID: "owner"}}
+	p.Spec.Volumes = append(p.Spec.Volumes,
+		v1.Volume{Name: "config", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: "db-config"}}}},
+		v1.Volume{Name: "token", VolumeSource: v1.VolumeSource{Projected: &v1.ProjectedVolumeSource{Sources: []v1.VolumeProjection{{ServiceAccountToken: &v1.ServiceAccountTokenProjection{Path: "token"}}}}}},
+	)
+	for _, name := range []string{"db", "exporter", "backup"} {
+		p.Spec.Containers = append(p.Spec.Containers, v1.Container{
+			Name:    name,
+			Image:   "registry.example.com/" + name + ":v1.2.3",
+			Command: []string{"/bin/" + name, "--config=/etc/config/config.yaml"},
+			Env:     []v1.EnvVar{{Name: "POD_NAME", Value: p.Name}, {Name: "LOG_LEVEL", Value: "info"}},
+			VolumeMounts: []v1.VolumeMount{
+				{Name: "claim", MountPath: "/data"},
+				{Name: "config", MountPath: "/etc/config"},
+				{Name: "token", MountPath: "/var/run/secrets/tokens"},
+			},
+		})
+		p.Status.ContainerStatuses = append(p.Status.ContainerStatuses, v1.ContainerStatus{
+			Name:        name,
+			Image:       "registry.example.com/" + name + ":v1.2.3",
+			ContainerID: "containerd://" + name + p.Name,
+			Ready:       true,
+		})
+	}
+	p.Status.Phase = v1.PodRunning
+	p.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}, {Type: v1.PodScheduled, Status: v1.ConditionTrue}}
+	return p
+}
+
+// BenchmarkPodCache reports the memory retained by the Pod informer cache for 100k pods,
+// with and without stripPod.
+func BenchmarkPodCache(b *testing.B) {
+	const pods = 100000
+	for _, test := range []struct {
+		name      string
+		transform cache.TransformFunc
+	}{
+		{name: "full", transform: func(obj any) (any, error) { return obj, nil }},
+		{name: "stripped", transform: stripPod},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			for b.Loop() {
+				var before, after goruntime.MemStats
+				goruntime.GC()
+				goruntime.ReadMemStats(&before)
+				store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+				for i := range pods {
+					obj, _ := test.transform(largePod(i))
+					_ = store.Add(obj)
+				}
+				goruntime.GC()
+				goruntime.ReadMemStats(&after)
+				// The heap can shrink while the store is filled, so subtract signed values.
+				b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc))/pods, "bytes/pod")
+				goruntime.KeepAlive(store)
+			}
+		})
+	}
+}
+
+// BenchmarkInUsePVCStore measures adding and removing 100k pods to the in-use store.
+func BenchmarkInUsePVCStore(b *testing.B) {
+	const pods = 100000
+	objs := make([]*v1.Pod, pods)
+	for i := range objs {
+		obj, _ := stripPod(largePod(i))
+		objs[i] = obj.(*v1.Pod)
+	}
+	for b.Loop() {
+		store := newUsedPVCStore()
+		for _, p := range objs {
+			store.addPod(p)
+		}
+		for _, p := range objs {
+			store.removePod(p)
+		}
+	}
+}
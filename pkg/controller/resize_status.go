@@ -19,6 +19,7 @@ package controller
 import (
 	"fmt"
 
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/expansionhistory"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -26,6 +27,18 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// resizeOwner is the part of PVCs and PVs that the resize controller writes with server-side apply.
+var resizeOwner = util.Owner{
+	FieldManager: util.ResizeFieldManager,
+	Resize:       true,
+	Conditions: []v1.PersistentVolumeClaimConditionType{
+		v1.PersistentVolumeClaimResizing,
+		v1.PersistentVolumeClaimFileSystemResizePending,
+		v1.PersistentVolumeClaimControllerResizeError,
+	},
+	PVAnnotations: []string{util.AnnPreResizeCapacity, expansionhistory.Annotation},
+}
+
 // markControllerResizeInProgress will mark PVC for controller resize, this function is newer version that uses
 // resizeStatus and sets allocatedResources.
 func (ctrl *resizeController) markControllerResizeInProgress(
@@ -53,7 +66,7 @@ func (ctrl *resizeController) markControllerResizeInProgress(
 		newPVC = mergeStorageResourceStatus(newPVC, v1.PersistentVolumeClaimControllerResizeInProgress)
 	}
 	newPVC = mergeStorageAllocatedResources(newPVC, newSize)
-	updatedPVC, err := util.PatchClaim(ctrl.kubeClient, pvc, newPVC, true /* addResourceVersionCheck */)
+	updatedPVC, err := util.ApplyClaimStatus(ctrl.kubeClient, resizeOwner, pvc, newPVC, true /* addResourceVersionCheck */)
 	if err != nil {
 		return pvc, err
 	}
@@ -84,7 +97,7 @@ func (ctrl *resizeController) markForPendingNodeExpansion(pvc *v1.PersistentVolu
 	newPVC = ctrl.removeNodeExpansionNotRequiredAnnotation(newPVC)
 
 	newPVC = mergeStorageResourceStatus(newPVC, v1.PersistentVolumeClaimNodeResizePending)
-	updatedPVC, err := util.PatchClaim(ctrl.kubeClient, pvc, newPVC, true /* addResourceVersionCheck */)
+	updatedPVC, err := util.ApplyClaimStatus(ctrl.kubeClient, resizeOwner, pvc, newPVC, true /* addResourceVersionCheck */)
 
 	if err != nil {
 		return updatedPVC, fmt.Errorf("mark PVC %q as node expansion required failed: %v", klog.KObj(pvc), err)
@@ -120,7 +133,7 @@ func (ctrl *resizeController) markControllerExpansionInfeasible(pvc *v1.Persiste
 	// operation must be restarted before ResizeStatus can be set to Expansionfailedoncontroller.
 	// Setting addResourceVersionCheck to `false` ensures that we set `ResizeStatus`
 	// even if our version of PVC was slightly older.
-	updatedPVC, err := util.PatchClaim(ctrl.kubeClient, pvc, newPVC, false /* addResourceVersionCheck */)
+	updatedPVC, err := util.ApplyClaimStatus(ctrl.kubeClient, resizeOwner, pvc, newPVC, false /* addResourceVersionCheck */)
 	if err != nil {
 		return pvc, fmt.Errorf("mark PVC %q as controller expansion failed, errored with: %v", klog.KObj(pvc), err)
 	}
@@ -149,7 +162,7 @@ func (ctrl *resizeController) markControllerExpansionFailedCondition(pvc *v1.Per
 	// operation must be restarted before ResizeStatus can be set to Expansionfailedoncontroller.
 	// Setting addResourceVersionCheck to `false` ensures that we set `ResizeStatus`
 	// even if our version of PVC was slightly older.
-	updatedPVC, err := util.PatchClaim(ctrl.kubeClient, pvc, newPVC, false /* addResourceVersionCheck */)
+	updatedPVC, err := util.ApplyClaimStatus(ctrl.kubeClient, resizeOwner, pvc, newPVC, false /* addResourceVersionCheck */)
 	if err != nil {
 		return pvc, fmt.Errorf("mark PVC %q as controller expansion failed, errored with: %v", klog.KObj(pvc), err)
 	}
@@ -181,7 +194,7 @@ func (ctrl *resizeController) markOverallExpansionAsFinished(
 	// this will ensure that kubelet does not try to resize volume again
 	newPVC = ctrl.addNodeExpansionNotRequiredAnnotation(newPVC)
 
-	updatedPVC, err := util.PatchClaim(ctrl.kubeClient, pvc, newPVC, true /* addResourceVersionCheck */)
+	updatedPVC, err := util.ApplyClaimStatus(ctrl.kubeClient, resizeOwner, pvc, newPVC, true /* addResourceVersionCheck */)
 	if err != nil {
 		return pvc, fmt.Errorf("mark PVC %q as resize finished failed: %v", klog.KObj(pvc), err)
 	}
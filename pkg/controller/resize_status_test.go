@@ -87,7 +87,7 @@ func TestResizeFunctions(t *testing.T) {
 
 			kubeClient, informerFactory := fakeK8s(initialObjects)
 
-			csiResizer, err := resizer.NewResizerFromClient(client, 15*time.Second, kubeClient, driverName)
+			csiResizer, err := resizer.NewResizerFromClient(client, 15*time.Second, kubeClient, driverName, nil, resizer.MetadataNone)
 			if err != nil {
 				t.Fatalf("Test %s: Unable to create resizer: %v", test.name, err)
 			}
@@ -96,7 +96,7 @@ func TestResizeFunctions(t *testing.T) {
 				time.Second, informerFactory,
 				workqueue.DefaultTypedControllerRateLimiter[string](),
 				true, /*handleVolumeInUseError*/
-				2*time.Minute /*maxRetryInterval*/)
+				2*time.Minute /*maxRetryInterval*/, Options{})
 
 			ctrlInstance, _ := controller.(*resizeController)
 
@@ -0,0 +1,103 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+)
+
+// syncScheduledResize applies a resize scheduled with the AnnScheduledSize and AnnScheduledTime
+// annotations. The schedule is stored only in the PVC, so it survives restarts of the resizer.
+// When the scheduled time is reached, the requested size of the PVC is set to the scheduled size
+// and the annotations are removed. It returns the possibly updated PVC and the time left until
+// the scheduled resize, or zero if no resize is pending.
+func (ctrl *resizeController) syncScheduledResize(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) (*v1.PersistentVolumeClaim, time.Duration, error) {
+	key := pvc.Namespace + "/" + pvc.Name
+	sizeValue, hasSize := pvc.Annotations[util.AnnScheduledSize]
+	timeValue, hasTime := pvc.Annotations[util.AnnScheduledTime]
+	if !hasSize && !hasTime {
+		ctrl.invalidSchedules.Delete(key)
+		return pvc, 0, nil
+	}
+	if !ctrl.resizer.CanSupport(pv, pvc) {
+		return pvc, 0, nil
+	}
+
+	size, err := resource.ParseQuantity(sizeValue)
+	if err != nil {
+		ctrl.warnInvalidSchedule(pvc, key, sizeValue, timeValue,
+			"Ignoring scheduled resize, invalid %s annotation %q: %v", util.AnnScheduledSize, sizeValue, err)
+		return pvc, 0, nil
+	}
+	at, err := time.Parse(time.RFC3339, timeValue)
+	if err != nil {
+		ctrl.warnInvalidSchedule(pvc, key, sizeValue, timeValue,
+			"Ignoring scheduled resize, invalid %s annotation %q: %v", util.AnnScheduledTime, timeValue, err)
+		return pvc, 0, nil
+	}
+	ctrl.invalidSchedules.Delete(key)
+
+	if wait := time.Until(at); wait > 0 {
+		klog.V(4).InfoS("Resize of PVC is scheduled", "PVC", klog.KObj(pvc), "size", size.String(), "time", at)
+		return pvc, wait, nil
+	}
+
+	newPVC := pvc.DeepCopy()
+	delete(newPVC.Annotations, util.AnnScheduledSize)
+	delete(newPVC.Annotations, util.AnnScheduledTime)
+	requestSize := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+	grow := size.Cmp(requestSize) > 0
+	if grow {
+		if newPVC.Spec.Resources.Requests == nil {
+			newPVC.Spec.Resources.Requests = v1.ResourceList{}
+		}
+		newPVC.Spec.Resources.Requests[v1.ResourceStorage] = size
+	}
+
+	// The resource version check makes sure that a schedule cancelled in the meantime is not applied.
+	updatedPVC, err := util.PatchClaimMetadata(ctrl.kubeClient, pvc, newPVC, true)
+	if err != nil {
+		return pvc, 0, err
+	}
+	if err := ctrl.claims.Update(updatedPVC); err != nil {
+		return updatedPVC, 0, err
+	}
+
+	if grow {
+		ctrl.eventRecorder.Eventf(updatedPVC, v1.EventTypeNormal, util.VolumeResizeScheduled,
+			"Requested size changed from %s to %s as scheduled at %s", requestSize.String(), size.String(), at.Format(time.RFC3339))
+	} else {
+		ctrl.eventRecorder.Eventf(updatedPVC, v1.EventTypeNormal, util.VolumeResizeScheduled,
+			"Skipped resize scheduled at %s, requested size %s is not smaller than %s", at.Format(time.RFC3339), requestSize.String(), size.String())
+	}
+	return updatedPVC, 0, nil
+}
+
+// warnInvalidSchedule records a warning about the invalid schedule of pvc, once for each value of the annotations.
+func (ctrl *resizeController) warnInvalidSchedule(pvc *v1.PersistentVolumeClaim, key, sizeValue, timeValue, messageFmt string, args ...interface{}) {
+	schedule := sizeValue + "@" + timeValue
+	if previous, warned := ctrl.invalidSchedules.Swap(key, schedule); warned && previous == schedule {
+		klog.V(4).InfoS("Ignoring invalid scheduled resize", "PVC", klog.KObj(pvc))
+		return
+	}
+	ctrl.eventRecorder.Eventf(pvc, v1.EventTypeWarning, util.InvalidScheduledResize, messageFmt, args...)
+}
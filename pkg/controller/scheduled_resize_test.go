@@ -0,0 +1,140 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/csi"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/resizer"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestSyncScheduledResize(t *testing.T) {
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	for _, test := range []struct {
+		name        string
+		annotations map[string]string
+		requestGB   int
+
+		expectDelay       bool
+		expectRequestGB   int
+		expectAnnotations bool
+		expectedEvent     string
+	}{
+		{
+			name:            "no schedule",
+			requestGB:       1,
+			expectRequestGB: 1,
+		},
+		{
+			name:              "scheduled in the future",
+			annotations:       map[string]string{util.AnnScheduledSize: "3Gi", util.AnnScheduledTime: future},
+			requestGB:         1,
+			expectDelay:       true,
+			expectRequestGB:   1,
+			expectAnnotations: true,
+		},
+		{
+			name:            "scheduled time reached",
+			annotations:     map[string]string{util.AnnScheduledSize: "3Gi", util.AnnScheduledTime: past},
+			requestGB:       1,
+			expectRequestGB: 3,
+			expectedEvent:   "Normal VolumeResizeScheduled Requested size changed from 1Gi to 3Gi as scheduled at " + past,
+		},
+		{
+			name:            "scheduled size already requested",
+			annotations:     map[string]string{util.AnnScheduledSize: "3Gi", util.AnnScheduledTime: past},
+			requestGB:       4,
+			expectRequestGB: 4,
+			expectedEvent:   "Normal VolumeResizeScheduled Skipped resize scheduled at " + past + ", requested size 4Gi is not smaller than 3Gi",
+		},
+		{
+			name:              "invalid time",
+			annotations:       map[string]string{util.AnnScheduledSize: "3Gi", util.AnnScheduledTime: "Saturday 02:00"},
+			requestGB:         1,
+			expectRequestGB:   1,
+			expectAnnotations: true,
+			expectedEvent:     "Warning InvalidScheduledResize Ignoring scheduled resize, invalid resizer.csi.k8s.io/scheduled-time annotation \"Saturday 02:00\"",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			client := csi.NewMockClient("mock", true, true, false, true, true)
+			driverName, _ := client.GetDriverName(context.TODO())
+
+			pvc := createPVC(test.requestGB, 1)
+			pvc.Annotations = test.annotations
+			pv := createPV(1, pvc.Name, pvc.Namespace, pvc.UID, nil)
+			pv.Spec.PersistentVolumeSource.CSI.Driver = driverName
+
+			kubeClient, informerFactory := fakeK8s([]runtime.Object{pvc, pv})
+			csiResizer, err := resizer.NewResizerFromClient(client, 15*time.Second, kubeClient, driverName, nil, resizer.MetadataNone)
+			if err != nil {
+				t.Fatalf("Unable to create resizer: %v", err)
+			}
+			controller := NewResizeController(driverName, csiResizer, kubeClient, time.Second, informerFactory,
+				workqueue.DefaultTypedControllerRateLimiter[string](), true, 2*time.Minute, Options{})
+			ctrlInstance, _ := controller.(*resizeController)
+			recorder := record.NewFakeRecorder(10)
+			ctrlInstance.eventRecorder = recorder
+
+			updatedPVC, delay, err := ctrlInstance.syncScheduledResize(pvc, pv)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (delay > 0) != test.expectDelay {
+				t.Errorf("expected delay %v, got %s", test.expectDelay, delay)
+			}
+			request := updatedPVC.Spec.Resources.Requests[v1.ResourceStorage]
+			if expected := quantityGB(test.expectRequestGB); request.Cmp(expected) != 0 {
+				t.Errorf("expected request %s, got %s", expected.String(), request.String())
+			}
+			_, hasAnnotation := updatedPVC.Annotations[util.AnnScheduledSize]
+			if hasAnnotation != test.expectAnnotations {
+				t.Errorf("expected schedule annotations %v, got %v", test.expectAnnotations, updatedPVC.Annotations)
+			}
+
+			select {
+			case event := <-recorder.Events:
+				if test.expectedEvent == "" || !strings.HasPrefix(event, test.expectedEvent) {
+					t.Errorf("expected event %q, got %q", test.expectedEvent, event)
+				}
+			default:
+				if test.expectedEvent != "" {
+					t.Errorf("expected event %q, got none", test.expectedEvent)
+				}
+			}
+
+			// An unchanged schedule is reported only once.
+			if _, _, err := ctrlInstance.syncScheduledResize(updatedPVC, pv); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(recorder.Events) != 0 {
+				t.Errorf("expected no event on the second sync, got %q", <-recorder.Events)
+			}
+		})
+	}
+}
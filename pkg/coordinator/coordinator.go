@@ -0,0 +1,301 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package coordinator serializes the expansion and the modification of a PVC
+// that requests both in the same change.
+package coordinator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+)
+
+// Operation is an operation of the external-resizer on a PVC.
+type Operation string
+
+const (
+	Resize Operation = "resize"
+	Modify Operation = "modify"
+)
+
+// Order decides which operation goes first when a PVC requests both.
+type Order string
+
+const (
+	// OrderSerial runs one operation at a time, in any order.
+	OrderSerial Order = "serial"
+	// OrderResizeFirst expands the volume before it is modified.
+	OrderResizeFirst Order = "resize-first"
+	// OrderModifyFirst modifies the volume before it is expanded.
+	OrderModifyFirst Order = "modify-first"
+)
+
+// AnnOrder on the CSIDriver object overrides the configured Order for the driver.
+const AnnOrder = "resizer.csi.k8s.io/operation-order"
+
+// RetryInterval is how long a waiting operation waits before it checks again,
+// in case it is not enqueued when the other operation finishes.
+const RetryInterval = 30 * time.Second
+
+// ParseOrder returns the Order named s.
+func ParseOrder(s string) (Order, error) {
+	switch o := Order(s); o {
+	case OrderSerial, OrderResizeFirst, OrderModifyFirst:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown operation order %q, expected %s, %s or %s", s, OrderSerial, OrderResizeFirst, OrderModifyFirst)
+}
+
+// Status is the combined status of the operations on a PVC.
+type Status struct {
+	PVC     string      `json:"pvc"`
+	Order   Order       `json:"order"`
+	Running Operation   `json:"running,omitempty"`
+	Waiting []Operation `json:"waiting,omitempty"`
+	Since   time.Time   `json:"since"`
+}
+
+// Coordinator lets only one of the resize and modify controllers work on a PVC at a time,
+// and holds back the second operation of a PVC that requests both until the first is done.
+// A nil *Coordinator lets both controllers run independently.
+type Coordinator struct {
+	order    Order
+	override func() (string, bool)
+	now      func() time.Time
+
+	mu      sync.Mutex
+	enqueue map[Operation]func(key string)
+	pvcs    map[string]*Status
+	// waits holds the last wait message of an operation, until it begins.
+	waits map[wait]string
+}
+
+type wait struct {
+	key string
+	op  Operation
+}
+
+// New returns a Coordinator using order, unless override returns a valid order set for the driver.
+// override may be nil.
+func New(order Order, override func() (string, bool)) *Coordinator {
+	return &Coordinator{
+		order:    order,
+		override: override,
+		now:      time.Now,
+		enqueue:  map[Operation]func(string){},
+		pvcs:     map[string]*Status{},
+		waits:    map[wait]string{},
+	}
+}
+
+// Register sets the function that enqueues a PVC key for op, to continue a waiting operation.
+func (c *Coordinator) Register(op Operation, enqueue func(key string)) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enqueue[op] = enqueue
+}
+
+// Order returns the order of the driver.
+func (c *Coordinator) Order() Order {
+	if c.override != nil {
+		if s, ok := c.override(); ok {
+			order, err := ParseOrder(s)
+			if err == nil {
+				return order
+			}
+			klog.ErrorS(err, "Ignoring CSIDriver annotation", "annotation", AnnOrder)
+		}
+	}
+	return c.order
+}
+
+// Begin starts op on pvc. It returns a function to call when the operation is done,
+// or a message explaining what op waits for. changed reports whether op starts to wait
+// or waits for another reason than on the previous call, to report each wait once.
+func (c *Coordinator) Begin(pvc *v1.PersistentVolumeClaim, op Operation) (done func(), msg string, changed bool) {
+	if c == nil {
+		return func() {}, "", false
+	}
+	key := pvc.Namespace + "/" + pvc.Name
+	other := Resize
+	if op == Resize {
+		other = Modify
+	}
+	order := c.Order()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := c.pvcs[key]
+	if status == nil {
+		status = &Status{PVC: key, Order: order, Since: c.now()}
+	}
+	status.Order = order
+
+	switch {
+	case status.Running == other:
+		msg = fmt.Sprintf("%s of PVC %s waits for the running %s", op, key, other)
+	case !Started(pvc, op) && Requested(pvc, other) && first(order) == other:
+		msg = fmt.Sprintf("%s of PVC %s waits for %s to finish, the operation order of the driver is %s", op, key, other, order)
+	}
+	if msg != "" {
+		if !slices.Contains(status.Waiting, op) {
+			status.Waiting = append(status.Waiting, op)
+		}
+		c.pvcs[key] = status
+		w := wait{key: key, op: op}
+		changed = c.waits[w] != msg
+		c.waits[w] = msg
+		return nil, msg, changed
+	}
+
+	delete(c.waits, wait{key: key, op: op})
+	status.Running = op
+	status.Waiting = slices.DeleteFunc(status.Waiting, func(o Operation) bool { return o == op })
+	c.pvcs[key] = status
+	return func() { c.end(key, op) }, "", false
+}
+
+func (c *Coordinator) end(key string, op Operation) {
+	c.mu.Lock()
+	status := c.pvcs[key]
+	if status == nil || status.Running != op {
+		c.mu.Unlock()
+		return
+	}
+	status.Running = ""
+	waiting := status.Waiting
+	status.Waiting = nil
+	delete(c.pvcs, key)
+	enqueue := make([]func(string), 0, len(waiting))
+	for _, o := range waiting {
+		if f := c.enqueue[o]; f != nil {
+			enqueue = append(enqueue, f)
+		}
+	}
+	c.mu.Unlock()
+
+	// A waiting operation checks the PVC again, it waits again if the first
+	// operation is not finished yet, e.g. when it is retried.
+	for _, f := range enqueue {
+		f(key)
+	}
+}
+
+// Forget drops the status of a deleted PVC.
+func (c *Coordinator) Forget(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pvcs, key)
+	delete(c.waits, wait{key: key, op: Resize})
+	delete(c.waits, wait{key: key, op: Modify})
+}
+
+// Statuses returns the combined status of the PVCs with a running or waiting operation, sorted by PVC.
+func (c *Coordinator) Statuses() []Status {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	statuses := make([]Status, 0, len(c.pvcs))
+	for _, s := range c.pvcs {
+		status := *s
+		status.Waiting = slices.Clone(s.Waiting)
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].PVC < statuses[j].PVC })
+	return statuses
+}
+
+// ServeHTTP writes the combined status of the PVCs as JSON.
+func (c *Coordinator) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c.Statuses()); err != nil {
+		klog.ErrorS(err, "Failed to write operation status")
+	}
+}
+
+func first(order Order) Operation {
+	switch order {
+	case OrderResizeFirst:
+		return Resize
+	case OrderModifyFirst:
+		return Modify
+	}
+	return ""
+}
+
+// Requested returns true if pvc requests op and the controller part of it is not done or failed yet.
+func Requested(pvc *v1.PersistentVolumeClaim, op Operation) bool {
+	if op == Modify {
+		vacName := ptr.Deref(pvc.Spec.VolumeAttributesClassName, "")
+		if vacName == "" || vacName == ptr.Deref(pvc.Status.CurrentVolumeAttributesClassName, "") {
+			return false
+		}
+		// Modifications held by a policy or rejected by the driver don't hold back expansion.
+		status := pvc.Status.ModifyVolumeStatus
+		return status == nil || status.Status == v1.PersistentVolumeClaimModifyVolumeInProgress
+	}
+
+	requested := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+	capacity := pvc.Status.Capacity[v1.ResourceStorage]
+	if requested.Cmp(capacity) <= 0 {
+		return false
+	}
+	switch pvc.Status.AllocatedResourceStatuses[v1.ResourceStorage] {
+	case v1.PersistentVolumeClaimControllerResizeInfeasible, v1.PersistentVolumeClaimNodeResizePending,
+		v1.PersistentVolumeClaimNodeResizeInProgress, v1.PersistentVolumeClaimNodeResizeInfeasible:
+		return false
+	}
+	for _, condition := range pvc.Status.Conditions {
+		if condition.Type == v1.PersistentVolumeClaimFileSystemResizePending && condition.Status == v1.ConditionTrue {
+			return false
+		}
+	}
+	return true
+}
+
+// Started returns true if op was already started on pvc, it is not held back then.
+func Started(pvc *v1.PersistentVolumeClaim, op Operation) bool {
+	if op == Modify {
+		status := pvc.Status.ModifyVolumeStatus
+		return status != nil && status.Status == v1.PersistentVolumeClaimModifyVolumeInProgress
+	}
+	if pvc.Status.AllocatedResourceStatuses[v1.ResourceStorage] == v1.PersistentVolumeClaimControllerResizeInProgress {
+		return true
+	}
+	for _, condition := range pvc.Status.Conditions {
+		if condition.Type == v1.PersistentVolumeClaimResizing && condition.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
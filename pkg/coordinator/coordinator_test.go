@@ -0,0 +1,148 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coordinator
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func testPVC(requested, capacity, vac, curVAC string) *v1.PersistentVolumeClaim {
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc", Namespace: "default"},
+		Spec: v1.PersistentVolumeClaimSpec{
+			Resources: v1.VolumeResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse(requested)},
+			},
+			VolumeAttributesClassName: ptr.To(vac),
+		},
+		Status: v1.PersistentVolumeClaimStatus{
+			Capacity:                         v1.ResourceList{v1.ResourceStorage: resource.MustParse(capacity)},
+			CurrentVolumeAttributesClassName: ptr.To(curVAC),
+		},
+	}
+}
+
+func TestRequested(t *testing.T) {
+	nodeResizePending := testPVC("2Gi", "1Gi", "gold", "gold")
+	nodeResizePending.Status.AllocatedResourceStatuses = map[v1.ResourceName]v1.ClaimResourceStatus{
+		v1.ResourceStorage: v1.PersistentVolumeClaimNodeResizePending,
+	}
+	modifyInfeasible := testPVC("1Gi", "1Gi", "gold", "silver")
+	modifyInfeasible.Status.ModifyVolumeStatus = &v1.ModifyVolumeStatus{
+		TargetVolumeAttributesClassName: "gold",
+		Status:                          v1.PersistentVolumeClaimModifyVolumeInfeasible,
+	}
+
+	tests := []struct {
+		name   string
+		pvc    *v1.PersistentVolumeClaim
+		resize bool
+		modify bool
+	}{
+		{name: "nothing", pvc: testPVC("1Gi", "1Gi", "gold", "gold")},
+		{name: "both", pvc: testPVC("2Gi", "1Gi", "gold", "silver"), resize: true, modify: true},
+		{name: "node expansion pending", pvc: nodeResizePending},
+		{name: "modify infeasible", pvc: modifyInfeasible},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Requested(test.pvc, Resize); got != test.resize {
+				t.Errorf("expected resize requested %v, got %v", test.resize, got)
+			}
+			if got := Requested(test.pvc, Modify); got != test.modify {
+				t.Errorf("expected modify requested %v, got %v", test.modify, got)
+			}
+		})
+	}
+}
+
+func TestBegin(t *testing.T) {
+	pvc := testPVC("2Gi", "1Gi", "gold", "silver")
+
+	var nilCoordinator *Coordinator
+	if done, wait, _ := nilCoordinator.Begin(pvc, Modify); wait != "" || done == nil {
+		t.Fatalf("expected nil coordinator to allow modify, got %q", wait)
+	}
+
+	c := New(OrderResizeFirst, nil)
+	var enqueued []string
+	c.Register(Modify, func(key string) { enqueued = append(enqueued, key) })
+
+	if _, wait, changed := c.Begin(pvc, Modify); wait == "" || !changed {
+		t.Fatalf("expected modify to start waiting for resize, got %q, changed %v", wait, changed)
+	}
+	if _, wait, changed := c.Begin(pvc, Modify); wait == "" || changed {
+		t.Fatalf("expected modify to keep waiting for the same reason, got %q, changed %v", wait, changed)
+	}
+	done, wait, _ := c.Begin(pvc, Resize)
+	if wait != "" {
+		t.Fatalf("expected resize to begin, got %q", wait)
+	}
+	expected := []Status{{PVC: "default/pvc", Order: OrderResizeFirst, Running: Resize, Waiting: []Operation{Modify}}}
+	if diff := cmp.Diff(expected, c.Statuses(), cmp.FilterPath(func(p cmp.Path) bool {
+		return p.Last().String() == ".Since"
+	}, cmp.Ignore())); diff != "" {
+		t.Errorf("unexpected statuses (-want +got):\n%s", diff)
+	}
+	started := pvc.DeepCopy()
+	started.Status.ModifyVolumeStatus = &v1.ModifyVolumeStatus{
+		TargetVolumeAttributesClassName: "gold",
+		Status:                          v1.PersistentVolumeClaimModifyVolumeInProgress,
+	}
+	if _, wait, changed := c.Begin(started, Modify); wait == "" || !changed {
+		t.Errorf("expected started modify to wait for the running resize for a new reason, got %q, changed %v", wait, changed)
+	}
+
+	done()
+	if diff := cmp.Diff([]string{"default/pvc"}, enqueued); diff != "" {
+		t.Errorf("unexpected enqueued keys (-want +got):\n%s", diff)
+	}
+	if len(c.Statuses()) != 0 {
+		t.Errorf("expected no statuses after resize, got %v", c.Statuses())
+	}
+
+	// The modification goes on once the controller part of the expansion is done.
+	pvc.Status.AllocatedResourceStatuses = map[v1.ResourceName]v1.ClaimResourceStatus{
+		v1.ResourceStorage: v1.PersistentVolumeClaimNodeResizePending,
+	}
+	if _, wait, _ := c.Begin(pvc, Modify); wait != "" {
+		t.Errorf("expected modify to begin, got %q", wait)
+	}
+	if len(c.waits) != 0 {
+		t.Errorf("expected no wait messages after modify began, got %v", c.waits)
+	}
+}
+
+func TestOrderOverride(t *testing.T) {
+	pvc := testPVC("2Gi", "1Gi", "gold", "silver")
+	annotation := string(OrderModifyFirst)
+	c := New(OrderResizeFirst, func() (string, bool) { return annotation, true })
+
+	if _, wait, _ := c.Begin(pvc, Resize); wait == "" {
+		t.Errorf("expected resize to wait for modify with order from CSIDriver")
+	}
+	annotation = "bogus"
+	if order := c.Order(); order != OrderResizeFirst {
+		t.Errorf("expected invalid annotation to be ignored, got %s", order)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package credentials provides the secrets that are passed to ControllerExpandVolume and
+// ControllerModifyVolume.
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// Provider returns the credentials stored in a Secret.
+type Provider interface {
+	// GetCredentials returns the data of the Secret ref, or nil if ref is nil.
+	GetCredentials(ctx context.Context, ref *v1.SecretReference) (map[string]string, error)
+}
+
+type apiProvider struct {
+	kubeClient kubernetes.Interface
+}
+
+// NewAPIProvider returns a Provider that gets every Secret from the API server.
+func NewAPIProvider(kubeClient kubernetes.Interface) Provider {
+	return &apiProvider{kubeClient: kubeClient}
+}
+
+func (p *apiProvider) GetCredentials(ctx context.Context, ref *v1.SecretReference) (map[string]string, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	secret, err := p.kubeClient.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting secret %s in namespace %s: %v", ref.Name, ref.Namespace, err)
+	}
+	credentials := map[string]string{}
+	for key, value := range secret.Data {
+		credentials[key] = string(value)
+	}
+	return credentials, nil
+}
+
+type cacheEntry struct {
+	credentials map[string]string
+	expires     time.Time
+}
+
+// CachingProvider keeps the credentials returned by another Provider for a TTL.
+type CachingProvider struct {
+	provider Provider
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	// generations counts the invalidations of every Secret, so that a fetch that started before an
+	// invalidation does not cache what it got.
+	generations map[string]uint64
+}
+
+var _ Provider = &CachingProvider{}
+
+// NewCachingProvider returns a Provider that caches the credentials of provider for ttl.
+func NewCachingProvider(provider Provider, ttl time.Duration) *CachingProvider {
+	return &CachingProvider{
+		provider:    provider,
+		ttl:         ttl,
+		now:         time.Now,
+		entries:     map[string]cacheEntry{},
+		generations: map[string]uint64{},
+	}
+}
+
+func (p *CachingProvider) GetCredentials(ctx context.Context, ref *v1.SecretReference) (map[string]string, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	key := ref.Namespace + "/" + ref.Name
+	p.mu.Lock()
+	entry, ok := p.entries[key]
+	generation := p.generations[key]
+	p.mu.Unlock()
+	if ok && p.now().Before(entry.expires) {
+		return maps.Clone(entry.credentials), nil
+	}
+
+	credentials, err := p.provider.GetCredentials(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	// The Secret may have changed while it was fetched, the next call fetches it again.
+	if p.generations[key] == generation {
+		p.entries[key] = cacheEntry{credentials: maps.Clone(credentials), expires: p.now().Add(p.ttl)}
+	}
+	p.mu.Unlock()
+	return credentials, nil
+}
+
+// Invalidate removes the credentials of the Secret namespace/name from the cache.
+func (p *CachingProvider) Invalidate(namespace, name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := namespace + "/" + name
+	delete(p.entries, key)
+	p.generations[key]++
+}
+
+// InvalidateOnChange watches the Secrets in namespace and removes them from the cache as soon as they
+// are changed or deleted, so that they need not wait for the TTL. Secret data is not kept in memory
+// by the informer. The informer runs until ctx is done, InvalidateOnChange returns once it has synced.
+func (p *CachingProvider) InvalidateOnChange(ctx context.Context, kubeClient kubernetes.Interface, namespace string, resyncPeriod time.Duration) error {
+	informer := coreinformers.NewSecretInformer(kubeClient, namespace, resyncPeriod, cache.Indexers{})
+	if err := informer.SetTransform(stripSecret); err != nil {
+		return err
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj any) {
+			oldSecret, oldOK := oldObj.(*v1.Secret)
+			newSecret, newOK := newObj.(*v1.Secret)
+			// Skip resyncs.
+			if oldOK && newOK && oldSecret.ResourceVersion == newSecret.ResourceVersion {
+				return
+			}
+			p.invalidateObject(newObj)
+		},
+		DeleteFunc: p.invalidateObject,
+	})
+	if err != nil {
+		return err
+	}
+	go informer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return fmt.Errorf("failed to sync secrets of namespace %s", namespace)
+	}
+	return nil
+}
+
+func (p *CachingProvider) invalidateObject(obj any) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	secret, ok := obj.(*v1.Secret)
+	if !ok {
+		return
+	}
+	klog.V(4).InfoS("Secret changed, removing it from the credentials cache", "secret", klog.KObj(secret))
+	p.Invalidate(secret.Namespace, secret.Name)
+}
+
+// stripSecret is a cache.TransformFunc that drops the data of Secrets.
+func stripSecret(obj any) (any, error) {
+	secret, ok := obj.(*v1.Secret)
+	if !ok {
+		return obj, nil
+	}
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            secret.Name,
+			Namespace:       secret.Namespace,
+			UID:             secret.UID,
+			ResourceVersion: secret.ResourceVersion,
+		},
+	}, nil
+}
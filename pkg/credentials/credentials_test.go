@@ -0,0 +1,170 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testSecret(value string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "secret", Namespace: "default"},
+		Data:       map[string][]byte{"key": []byte(value)},
+	}
+}
+
+var testRef = &v1.SecretReference{Name: "secret", Namespace: "default"}
+
+func TestCachingProvider(t *testing.T) {
+	client := fake.NewSimpleClientset(testSecret("a"))
+	p := NewCachingProvider(NewAPIProvider(client), time.Minute)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	get := func(expected string) {
+		t.Helper()
+		credentials, err := p.GetCredentials(t.Context(), testRef)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if credentials["key"] != expected {
+			t.Errorf("expected %q, got %q", expected, credentials["key"])
+		}
+	}
+
+	get("a")
+	if _, err := client.CoreV1().Secrets("default").Update(t.Context(), testSecret("b"), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	get("a")
+	now = now.Add(2 * time.Minute)
+	get("b")
+
+	if _, err := client.CoreV1().Secrets("default").Update(t.Context(), testSecret("c"), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	p.Invalidate("default", "secret")
+	get("c")
+
+	if credentials, err := p.GetCredentials(t.Context(), nil); credentials != nil || err != nil {
+		t.Errorf("expected no credentials for nil reference, got %v, %v", credentials, err)
+	}
+}
+
+type providerFunc func(ctx context.Context, ref *v1.SecretReference) (map[string]string, error)
+
+func (f providerFunc) GetCredentials(ctx context.Context, ref *v1.SecretReference) (map[string]string, error) {
+	return f(ctx, ref)
+}
+
+func TestInvalidateDuringFetch(t *testing.T) {
+	var p *CachingProvider
+	value := "a"
+	fetches := 0
+	p = NewCachingProvider(providerFunc(func(ctx context.Context, ref *v1.SecretReference) (map[string]string, error) {
+		fetches++
+		credentials := map[string]string{"key": value}
+		if fetches == 1 {
+			// The Secret changes after the first fetch got it.
+			value = "b"
+			p.Invalidate(ref.Namespace, ref.Name)
+		}
+		return credentials, nil
+	}), time.Hour)
+
+	for _, expected := range []string{"a", "b", "b"} {
+		credentials, err := p.GetCredentials(t.Context(), testRef)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if credentials["key"] != expected {
+			t.Errorf("expected %q, got %q", expected, credentials["key"])
+		}
+	}
+	if fetches != 2 {
+		t.Errorf("expected the stale credentials not cached and 2 fetches, got %d", fetches)
+	}
+}
+
+func TestInvalidateOnChange(t *testing.T) {
+	client := fake.NewSimpleClientset(testSecret("a"))
+	p := NewCachingProvider(NewAPIProvider(client), time.Hour)
+	if err := p.InvalidateOnChange(t.Context(), client, "default", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := p.GetCredentials(t.Context(), testRef); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	updated := testSecret("b")
+	updated.ResourceVersion = "2"
+	if _, err := client.CoreV1().Secrets("default").Update(t.Context(), updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err := wait.PollUntilContextTimeout(t.Context(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		credentials, err := p.GetCredentials(ctx, testRef)
+		return err == nil && credentials["key"] == "b", nil
+	})
+	if err != nil {
+		t.Errorf("expected changed secret to be removed from the cache: %v", err)
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	secretDir := filepath.Join(dir, "default", "secret")
+	// Mimic the layout of a Secret volume.
+	dataDir := filepath.Join(secretDir, "..2026_01_01_00_00_00.000000000")
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "key"), []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Base(dataDir), filepath.Join(secretDir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..data", "key"), filepath.Join(secretDir, "key")); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewFileProvider(dir)
+	credentials, err := p.GetCredentials(t.Context(), testRef)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := map[string]string{"key": "a"}; !reflect.DeepEqual(credentials, expected) {
+		t.Errorf("expected %v, got %v", expected, credentials)
+	}
+
+	if _, err := p.GetCredentials(t.Context(), &v1.SecretReference{Name: "missing", Namespace: "default"}); err == nil {
+		t.Errorf("expected error for missing secret")
+	}
+	if _, err := p.GetCredentials(t.Context(), &v1.SecretReference{Name: "..", Namespace: "default"}); err == nil {
+		t.Errorf("expected error for invalid reference")
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+type fileProvider struct {
+	dir string
+}
+
+// NewFileProvider returns a Provider that reads the Secret namespace/name from the directory
+// <dir>/<namespace>/<name>, with one file per key. This is the layout of Secret and projected
+// volumes and of the Secrets Store CSI driver, so that secrets can be mounted into the container
+// instead of being read from the API server.
+func NewFileProvider(dir string) Provider {
+	return &fileProvider{dir: dir}
+}
+
+func (p *fileProvider) GetCredentials(_ context.Context, ref *v1.SecretReference) (map[string]string, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	if !validPathElement(ref.Namespace) || !validPathElement(ref.Name) {
+		return nil, fmt.Errorf("invalid secret reference %s/%s", ref.Namespace, ref.Name)
+	}
+	dir := filepath.Join(p.dir, ref.Namespace, ref.Name)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading secret %s in namespace %s: %v", ref.Name, ref.Namespace, err)
+	}
+	credentials := map[string]string{}
+	for _, entry := range entries {
+		// Secret volumes contain hidden directories like ..data for atomic updates.
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		// Keys are usually symbolic links into the hidden directories.
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading secret %s in namespace %s: %v", ref.Name, ref.Namespace, err)
+		}
+		if info.IsDir() {
+			continue
+		}
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading secret %s in namespace %s: %v", ref.Name, ref.Namespace, err)
+		}
+		credentials[entry.Name()] = string(value)
+	}
+	return credentials, nil
+}
+
+func validPathElement(s string) bool {
+	return s != "" && s != "." && s != ".." && !strings.ContainsAny(s, `/\`)
+}
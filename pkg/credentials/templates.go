@@ -0,0 +1,189 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/informers"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Parameters of StorageClasses and VolumeAttributesClasses with secret templates, as used by the external-provisioner.
+const (
+	ExpandSecretNameKey      = "csi.storage.k8s.io/controller-expand-secret-name"
+	ExpandSecretNamespaceKey = "csi.storage.k8s.io/controller-expand-secret-namespace"
+	ModifySecretNameKey      = "csi.storage.k8s.io/controller-modify-secret-name"
+	ModifySecretNamespaceKey = "csi.storage.k8s.io/controller-modify-secret-namespace"
+)
+
+var templateVariable = regexp.MustCompile(`\${([^}]*)}`)
+
+// TemplateResolver resolves secret references from the templates in the StorageClass or
+// VolumeAttributesClass of a volume at operation time. A nil *TemplateResolver resolves nothing.
+type TemplateResolver struct {
+	scLister storagelisters.StorageClassLister
+	scSynced cache.InformerSynced
+}
+
+// NewTemplateResolver returns a TemplateResolver that reads StorageClasses from informerFactory.
+func NewTemplateResolver(informerFactory informers.SharedInformerFactory) *TemplateResolver {
+	scInformer := informerFactory.Storage().V1().StorageClasses()
+	return &TemplateResolver{
+		scLister: scInformer.Lister(),
+		scSynced: scInformer.Informer().HasSynced,
+	}
+}
+
+// HasSynced returns true once the StorageClasses have been listed.
+func (r *TemplateResolver) HasSynced() bool {
+	return r == nil || r.scSynced()
+}
+
+// ExpandSecretRef returns the expansion secret of pv from the templates in its StorageClass,
+// or nil if there are none.
+func (r *TemplateResolver) ExpandSecretRef(pv *v1.PersistentVolume, pvc *v1.PersistentVolumeClaim) (*v1.SecretReference, error) {
+	if r == nil {
+		return nil, nil
+	}
+	params, err := r.storageClassParameters(pv)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveSecretRef(params, ExpandSecretNameKey, ExpandSecretNamespaceKey, pv, pvc)
+}
+
+// ModifySecretRef returns the modification secret of pv from the templates in vac,
+// or else from its StorageClass, or nil if there are none.
+func (r *TemplateResolver) ModifySecretRef(pv *v1.PersistentVolume, pvc *v1.PersistentVolumeClaim, vac *storagev1.VolumeAttributesClass) (*v1.SecretReference, error) {
+	if r == nil {
+		return nil, nil
+	}
+	if vac != nil {
+		ref, err := ResolveSecretRef(vac.Parameters, ModifySecretNameKey, ModifySecretNamespaceKey, pv, pvc)
+		if ref != nil || err != nil {
+			return ref, err
+		}
+	}
+	params, err := r.storageClassParameters(pv)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveSecretRef(params, ModifySecretNameKey, ModifySecretNamespaceKey, pv, pvc)
+}
+
+func (r *TemplateResolver) storageClassParameters(pv *v1.PersistentVolume) (map[string]string, error) {
+	if pv.Spec.StorageClassName == "" {
+		return nil, nil
+	}
+	sc, err := r.scLister.Get(pv.Spec.StorageClassName)
+	if apierrors.IsNotFound(err) {
+		// The class may be gone, use the secret of the PV.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sc.Parameters, nil
+}
+
+// StripSecretParameters returns params without the secret templates, so that they are not passed to the driver.
+func StripSecretParameters(params map[string]string) map[string]string {
+	if _, ok := params[ModifySecretNameKey]; !ok {
+		if _, ok := params[ModifySecretNamespaceKey]; !ok {
+			return params
+		}
+	}
+	params = maps.Clone(params)
+	delete(params, ModifySecretNameKey)
+	delete(params, ModifySecretNamespaceKey)
+	return params
+}
+
+// ResolveSecretRef resolves the templates params[nameKey] and params[namespaceKey] for pv and pvc.
+// It returns nil if neither is set. Names support ${pv.name}, ${pvc.namespace}, ${pvc.name} and
+// ${pvc.annotations['<key>']}, namespaces support ${pv.name} and ${pvc.namespace}.
+func ResolveSecretRef(params map[string]string, nameKey, namespaceKey string, pv *v1.PersistentVolume, pvc *v1.PersistentVolumeClaim) (*v1.SecretReference, error) {
+	nameTemplate, hasName := params[nameKey]
+	namespaceTemplate, hasNamespace := params[namespaceKey]
+	if !hasName && !hasNamespace {
+		return nil, nil
+	}
+	if !hasName || !hasNamespace {
+		return nil, fmt.Errorf("either both or none of %s and %s must be set", nameKey, namespaceKey)
+	}
+
+	variables := map[string]string{
+		"pv.name":       pv.Name,
+		"pvc.namespace": pvc.Namespace,
+	}
+	namespace, err := resolveTemplate(namespaceTemplate, variables, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", namespaceKey, err)
+	}
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return nil, fmt.Errorf("%s: %q is not a valid namespace: %s", namespaceKey, namespace, strings.Join(errs, ", "))
+	}
+
+	variables["pvc.name"] = pvc.Name
+	annotations := pvc.Annotations
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	name, err := resolveTemplate(nameTemplate, variables, annotations)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", nameKey, err)
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return nil, fmt.Errorf("%s: %q is not a valid secret name: %s", nameKey, name, strings.Join(errs, ", "))
+	}
+	return &v1.SecretReference{Name: name, Namespace: namespace}, nil
+}
+
+// resolveTemplate replaces the ${...} variables of template. annotations are available
+// as ${pvc.annotations['<key>']} if not nil.
+func resolveTemplate(template string, variables, annotations map[string]string) (string, error) {
+	var missing []string
+	resolved := templateVariable.ReplaceAllStringFunc(template, func(match string) string {
+		variable := templateVariable.FindStringSubmatch(match)[1]
+		if value, ok := variables[variable]; ok {
+			return value
+		}
+		if annotations != nil && strings.HasPrefix(variable, "pvc.annotations['") && strings.HasSuffix(variable, "']") {
+			key := strings.TrimSuffix(strings.TrimPrefix(variable, "pvc.annotations['"), "']")
+			if value, ok := annotations[key]; ok {
+				return value
+			}
+			missing = append(missing, fmt.Sprintf("PVC annotation %q is not set", key))
+			return match
+		}
+		missing = append(missing, fmt.Sprintf("unknown variable %s", match))
+		return match
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("invalid template %q: %s", template, strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestResolveSecretRef(t *testing.T) {
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-1"}}
+	pvc := &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:        "data",
+		Namespace:   "tenant-a",
+		Annotations: map[string]string{"example.com/secret": "rotated-2"},
+	}}
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expected  *v1.SecretReference
+		expectErr bool
+	}{
+		{
+			name:   "no templates",
+			params: map[string]string{"type": "ssd"},
+		},
+		{
+			name:     "static",
+			params:   map[string]string{ExpandSecretNameKey: "secret", ExpandSecretNamespaceKey: "kube-system"},
+			expected: &v1.SecretReference{Name: "secret", Namespace: "kube-system"},
+		},
+		{
+			name:     "per tenant",
+			params:   map[string]string{ExpandSecretNameKey: "${pvc.name}-${pv.name}", ExpandSecretNamespaceKey: "${pvc.namespace}"},
+			expected: &v1.SecretReference{Name: "data-pv-1", Namespace: "tenant-a"},
+		},
+		{
+			name:     "annotation",
+			params:   map[string]string{ExpandSecretNameKey: "${pvc.annotations['example.com/secret']}", ExpandSecretNamespaceKey: "${pvc.namespace}"},
+			expected: &v1.SecretReference{Name: "rotated-2", Namespace: "tenant-a"},
+		},
+		{
+			name:      "missing annotation",
+			params:    map[string]string{ExpandSecretNameKey: "${pvc.annotations['example.com/other']}", ExpandSecretNamespaceKey: "${pvc.namespace}"},
+			expectErr: true,
+		},
+		{
+			name:      "annotation in namespace",
+			params:    map[string]string{ExpandSecretNameKey: "secret", ExpandSecretNamespaceKey: "${pvc.annotations['example.com/secret']}"},
+			expectErr: true,
+		},
+		{
+			name:      "unknown variable",
+			params:    map[string]string{ExpandSecretNameKey: "${pvc.uid}", ExpandSecretNamespaceKey: "${pvc.namespace}"},
+			expectErr: true,
+		},
+		{
+			name:      "only name",
+			params:    map[string]string{ExpandSecretNameKey: "secret"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid name",
+			params:    map[string]string{ExpandSecretNameKey: "Secret_${pvc.name}", ExpandSecretNamespaceKey: "${pvc.namespace}"},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ref, err := ResolveSecretRef(test.params, ExpandSecretNameKey, ExpandSecretNamespaceKey, pv, pvc)
+			if test.expectErr != (err != nil) {
+				t.Fatalf("expected error %t, got %v", test.expectErr, err)
+			}
+			if !reflect.DeepEqual(ref, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, ref)
+			}
+		})
+	}
+}
+
+func TestTemplateResolver(t *testing.T) {
+	sc := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "fast"},
+		Parameters: map[string]string{
+			ExpandSecretNameKey:      "expand-${pvc.name}",
+			ExpandSecretNamespaceKey: "${pvc.namespace}",
+			ModifySecretNameKey:      "modify-sc",
+			ModifySecretNamespaceKey: "${pvc.namespace}",
+		},
+	}
+	client := fake.NewSimpleClientset(sc)
+	factory := informers.NewSharedInformerFactory(client, 0)
+	r := NewTemplateResolver(factory)
+	factory.Start(t.Context().Done())
+	factory.WaitForCacheSync(t.Context().Done())
+
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-1"}, Spec: v1.PersistentVolumeSpec{StorageClassName: "fast"}}
+	pvc := &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "tenant-a"}}
+
+	ref, err := r.ExpandSecretRef(pv, pvc)
+	if err != nil || !reflect.DeepEqual(ref, &v1.SecretReference{Name: "expand-data", Namespace: "tenant-a"}) {
+		t.Errorf("unexpected expansion secret %+v, %v", ref, err)
+	}
+
+	vac := &storagev1.VolumeAttributesClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "gold"},
+		Parameters: map[string]string{ModifySecretNameKey: "modify-vac", ModifySecretNamespaceKey: "${pvc.namespace}", "iops": "3000"},
+	}
+	ref, err = r.ModifySecretRef(pv, pvc, vac)
+	if err != nil || !reflect.DeepEqual(ref, &v1.SecretReference{Name: "modify-vac", Namespace: "tenant-a"}) {
+		t.Errorf("expected secret of the VolumeAttributesClass, got %+v, %v", ref, err)
+	}
+	ref, err = r.ModifySecretRef(pv, pvc, &storagev1.VolumeAttributesClass{})
+	if err != nil || !reflect.DeepEqual(ref, &v1.SecretReference{Name: "modify-sc", Namespace: "tenant-a"}) {
+		t.Errorf("expected secret of the StorageClass, got %+v, %v", ref, err)
+	}
+	if params := StripSecretParameters(vac.Parameters); !reflect.DeepEqual(params, map[string]string{"iops": "3000"}) {
+		t.Errorf("unexpected parameters %v", params)
+	}
+
+	// Volumes of deleted classes keep their secret.
+	pv.Spec.StorageClassName = "deleted"
+	if ref, err := r.ExpandSecretRef(pv, pvc); ref != nil || err != nil {
+		t.Errorf("expected no secret, got %+v, %v", ref, err)
+	}
+
+	var nilResolver *TemplateResolver
+	if ref, err := nilResolver.ExpandSecretRef(pv, pvc); ref != nil || err != nil || !nilResolver.HasSynced() {
+		t.Errorf("expected nil resolver to resolve nothing")
+	}
+}
@@ -54,6 +54,10 @@ type Client interface {
 	// SINGLE_NODE_MULTI_WRITER in ControllerGetCapabilities() gRPC call.
 	SupportsControllerSingleNodeMultiWriter(ctx context.Context) (bool, error)
 
+	// SupportsControllerPublish returns whether the CSI driver reports PUBLISH_UNPUBLISH_VOLUME
+	// in ControllerGetCapabilities() gRPC call.
+	SupportsControllerPublish(ctx context.Context) (bool, error)
+
 	// Expand expands the volume to a new size at least as big as requestBytes.
 	// It returns the new size and whether the volume need expand operation on the node.
 	Expand(ctx context.Context, volumeID string, requestBytes int64, secrets map[string]string, capability *csi.VolumeCapability) (int64, bool, error)
@@ -146,6 +150,14 @@ func (c *client) SupportsControllerSingleNodeMultiWriter(ctx context.Context) (b
 	return caps[csi.ControllerServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER], nil
 }
 
+func (c *client) SupportsControllerPublish(ctx context.Context) (bool, error) {
+	caps, err := csirpc.GetControllerCapabilities(ctx, c.conn)
+	if err != nil {
+		return false, fmt.Errorf("error getting controller capabilities: %v", err)
+	}
+	return caps[csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME], nil
+}
+
 func (c *client) Expand(
 	ctx context.Context,
 	volumeID string,
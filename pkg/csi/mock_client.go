@@ -9,6 +9,7 @@ import (
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-csi/csi-lib-utils/connection"
+	"google.golang.org/grpc/metadata"
 )
 
 func NewMockClient(
@@ -37,6 +38,7 @@ type MockClient struct {
 	supportsControllerModify                bool
 	supportsPluginControllerService         bool
 	supportsControllerSingleNodeMultiWriter bool
+	supportsControllerPublish               bool
 	expandCalled                            atomic.Int32
 	modifyCalled                            atomic.Int32
 	expansionError                          error
@@ -44,6 +46,7 @@ type MockClient struct {
 	checkMigratedLabel                      bool
 	usedSecrets                             atomic.Pointer[map[string]string]
 	usedCapability                          atomic.Pointer[csi.VolumeCapability]
+	usedMetadata                            atomic.Pointer[metadata.MD]
 	modifyMu                                sync.Mutex
 	modifiedParameters                      map[string]string
 }
@@ -72,6 +75,14 @@ func (c *MockClient) SupportsControllerSingleNodeMultiWriter(context.Context) (b
 	return c.supportsControllerSingleNodeMultiWriter, nil
 }
 
+func (c *MockClient) SupportsControllerPublish(context.Context) (bool, error) {
+	return c.supportsControllerPublish, nil
+}
+
+func (c *MockClient) SetControllerPublish(supported bool) {
+	c.supportsControllerPublish = supported
+}
+
 func (c *MockClient) SetExpansionError(err error) {
 	c.expansionError = err
 }
@@ -107,6 +118,8 @@ func (c *MockClient) Expand(
 	c.expandCalled.Add(1)
 	c.usedSecrets.Store(&secrets)
 	c.usedCapability.Store(capability)
+	md, _ := metadata.FromOutgoingContext(ctx)
+	c.usedMetadata.Store(&md)
 	return requestBytes, c.supportsNodeResize, nil
 }
 
@@ -133,6 +146,11 @@ func (c *MockClient) GetSecrets() map[string]string {
 	return *c.usedSecrets.Load()
 }
 
+// GetMetadata returns the gRPC metadata of the last expansion.
+func (c *MockClient) GetMetadata() metadata.MD {
+	return *c.usedMetadata.Load()
+}
+
 func (c *MockClient) CloseConnection() {
 
 }
@@ -0,0 +1,171 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package csidriver watches the CSIDriver object of the driver and exposes its settings.
+package csidriver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	storageinformers "k8s.io/client-go/informers/storage/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Settings are the settings of a CSIDriver object, with the API defaults applied.
+type Settings struct {
+	// Found is false if there is no CSIDriver object for the driver.
+	Found bool
+	// AttachRequired is true if volumes of the driver are attached by VolumeAttachments.
+	AttachRequired bool
+	// RequiresRepublish is true if kubelet calls NodePublishVolume periodically.
+	RequiresRepublish bool
+	// FSGroupPolicy tells whether kubelet changes the ownership of volumes of the driver.
+	FSGroupPolicy storagev1.FSGroupPolicy
+}
+
+// DefaultSettings are the settings that apply to a driver without CSIDriver object.
+var DefaultSettings = Settings{
+	AttachRequired: true,
+	FSGroupPolicy:  storagev1.ReadWriteOnceWithFSTypeFSGroupPolicy,
+}
+
+// Watcher keeps the CSIDriver object of a driver in memory.
+type Watcher struct {
+	driverName string
+	informer   cache.SharedIndexInformer
+}
+
+// NewWatcher returns a Watcher for the CSIDriver object named driverName.
+// Only this object is watched.
+func NewWatcher(kubeClient kubernetes.Interface, driverName string, resyncPeriod time.Duration) *Watcher {
+	informer := storageinformers.NewFilteredCSIDriverInformer(kubeClient, resyncPeriod, cache.Indexers{},
+		func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", driverName).String()
+		})
+	return &Watcher{driverName: driverName, informer: informer}
+}
+
+// Run watches the CSIDriver object until ctx is done.
+func (w *Watcher) Run(ctx context.Context) {
+	w.informer.Run(ctx.Done())
+}
+
+// WaitForCacheSync waits until the CSIDriver object was listed, or ctx is done.
+// It returns false in the latter case.
+func (w *Watcher) WaitForCacheSync(ctx context.Context) bool {
+	return cache.WaitForCacheSync(ctx.Done(), w.informer.HasSynced)
+}
+
+// Settings returns the current settings of the driver.
+func (w *Watcher) Settings() Settings {
+	obj, exists, err := w.informer.GetStore().GetByKey(w.driverName)
+	if err != nil || !exists {
+		return DefaultSettings
+	}
+	csiDriver, ok := obj.(*storagev1.CSIDriver)
+	if !ok {
+		return DefaultSettings
+	}
+	return SettingsOf(csiDriver)
+}
+
+// AddHandler registers handler to be called whenever the CSIDriver object is created, changed or deleted.
+func (w *Watcher) AddHandler(handler func()) {
+	w.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { handler() },
+		UpdateFunc: func(_, _ interface{}) { handler() },
+		DeleteFunc: func(interface{}) { handler() },
+	})
+}
+
+// OnChange registers handler to be called with the new settings whenever the CSIDriver
+// object is created, changed or deleted in a way that changes the settings.
+func (w *Watcher) OnChange(handler func(Settings)) {
+	last := w.Settings()
+	w.AddHandler(func() {
+		settings := w.Settings()
+		if settings == last {
+			return
+		}
+		last = settings
+		handler(settings)
+	})
+}
+
+// Annotation returns the value of the annotation key of the CSIDriver object and whether it is set.
+func (w *Watcher) Annotation(key string) (string, bool) {
+	obj, exists, err := w.informer.GetStore().GetByKey(w.driverName)
+	if err != nil || !exists {
+		return "", false
+	}
+	csiDriver, ok := obj.(*storagev1.CSIDriver)
+	if !ok {
+		return "", false
+	}
+	value, ok := csiDriver.Annotations[key]
+	return value, ok
+}
+
+// SettingsOf returns the settings of csiDriver.
+func SettingsOf(csiDriver *storagev1.CSIDriver) Settings {
+	settings := DefaultSettings
+	settings.Found = true
+	spec := csiDriver.Spec
+	if spec.AttachRequired != nil {
+		settings.AttachRequired = *spec.AttachRequired
+	}
+	if spec.RequiresRepublish != nil {
+		settings.RequiresRepublish = *spec.RequiresRepublish
+	}
+	if spec.FSGroupPolicy != nil {
+		settings.FSGroupPolicy = *spec.FSGroupPolicy
+	}
+	return settings
+}
+
+// Capabilities are the capabilities advertised by the CSI driver that relate to CSIDriver settings.
+type Capabilities struct {
+	// ControllerPublish is true if the driver reports PUBLISH_UNPUBLISH_VOLUME.
+	ControllerPublish bool
+}
+
+// Conflicts returns a description of every setting that contradicts the capabilities of the driver.
+func (s Settings) Conflicts(caps Capabilities) []string {
+	var conflicts []string
+	if !s.Found {
+		conflicts = append(conflicts, "there is no CSIDriver object for the driver, the defaults apply")
+	}
+	if s.AttachRequired && !caps.ControllerPublish {
+		conflicts = append(conflicts, fmt.Sprintf("the driver does not support ControllerPublishVolume, but attachRequired is true%s", defaultNote(s)))
+	}
+	if !s.AttachRequired && caps.ControllerPublish {
+		conflicts = append(conflicts, "the driver supports ControllerPublishVolume, but attachRequired is false and volumes are never published")
+	}
+	return conflicts
+}
+
+func defaultNote(s Settings) string {
+	if s.Found {
+		return ""
+	}
+	return " by default"
+}
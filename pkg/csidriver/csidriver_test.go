@@ -0,0 +1,147 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csidriver
+
+import (
+	"testing"
+	"time"
+
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestWatcher(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&storagev1.CSIDriver{
+			ObjectMeta: metav1.ObjectMeta{Name: "mock", Annotations: map[string]string{"example.com/a": "b"}},
+			Spec: storagev1.CSIDriverSpec{
+				AttachRequired:    ptr.To(false),
+				RequiresRepublish: ptr.To(true),
+				FSGroupPolicy:     ptr.To(storagev1.FileFSGroupPolicy),
+			},
+		},
+		&storagev1.CSIDriver{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+	)
+	w := NewWatcher(client, "mock", 0)
+	go w.Run(t.Context())
+	if !w.WaitForCacheSync(t.Context()) {
+		t.Fatalf("failed to sync")
+	}
+	expected := Settings{Found: true, RequiresRepublish: true, FSGroupPolicy: storagev1.FileFSGroupPolicy}
+	if settings := w.Settings(); settings != expected {
+		t.Errorf("expected %+v, got %+v", expected, settings)
+	}
+	if value, ok := w.Annotation("example.com/a"); !ok || value != "b" {
+		t.Errorf("unexpected annotation %q, %t", value, ok)
+	}
+
+	missing := NewWatcher(client, "missing", 0)
+	go missing.Run(t.Context())
+	missing.WaitForCacheSync(t.Context())
+	if settings := missing.Settings(); settings != DefaultSettings {
+		t.Errorf("expected defaults for missing CSIDriver, got %+v", settings)
+	}
+	if _, ok := missing.Annotation("example.com/a"); ok {
+		t.Errorf("expected no annotation for missing CSIDriver")
+	}
+}
+
+func TestOnChange(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	w := NewWatcher(client, "mock", 0)
+	go w.Run(t.Context())
+	if !w.WaitForCacheSync(t.Context()) {
+		t.Fatalf("failed to sync")
+	}
+	changes := make(chan Settings, 10)
+	w.OnChange(func(settings Settings) { changes <- settings })
+
+	csiDriver := &storagev1.CSIDriver{ObjectMeta: metav1.ObjectMeta{Name: "mock"}, Spec: storagev1.CSIDriverSpec{AttachRequired: ptr.To(false)}}
+	if _, err := client.StorageV1().CSIDrivers().Create(t.Context(), csiDriver, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case settings := <-changes:
+		if !settings.Found || settings.AttachRequired {
+			t.Errorf("unexpected settings %+v", settings)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected a change of the settings")
+	}
+
+	// Changes of other fields do not change the settings.
+	csiDriver.Labels = map[string]string{"a": "b"}
+	if _, err := client.StorageV1().CSIDrivers().Update(t.Context(), csiDriver, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.StorageV1().CSIDrivers().Delete(t.Context(), "mock", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case settings := <-changes:
+		if settings != DefaultSettings {
+			t.Errorf("expected defaults after deletion, got %+v", settings)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected a change of the settings")
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no further changes, got %d", len(changes))
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		settings  Settings
+		caps      Capabilities
+		conflicts int
+	}{
+		{
+			name:     "attach required and supported",
+			settings: Settings{Found: true, AttachRequired: true},
+			caps:     Capabilities{ControllerPublish: true},
+		},
+		{
+			name:      "attach required but not supported",
+			settings:  Settings{Found: true, AttachRequired: true},
+			conflicts: 1,
+		},
+		{
+			name:      "attach supported but not required",
+			settings:  Settings{Found: true},
+			caps:      Capabilities{ControllerPublish: true},
+			conflicts: 1,
+		},
+		{
+			name:      "missing CSIDriver",
+			settings:  DefaultSettings,
+			caps:      Capabilities{ControllerPublish: true},
+			conflicts: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conflicts := test.settings.Conflicts(test.caps)
+			if len(conflicts) != test.conflicts {
+				t.Errorf("expected %d conflicts, got %v", test.conflicts, conflicts)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dispatcher routes PVC events of a single informer handler and a single
+// workqueue to the resize and modify controllers.
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/coordinator"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/features"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/sharding"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	syncs = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      "csi_resizer",
+			Name:           "pvc_syncs_total",
+			Help:           "Number of PVC syncs of the shared PVC queue, by operation and result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"operation", "result"},
+	)
+	syncDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      "csi_resizer",
+			Name:           "pvc_sync_duration_seconds",
+			Help:           "Duration of PVC syncs of the shared PVC queue, by operation.",
+			Buckets:        metrics.ExponentialBuckets(0.001, 4, 10),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"operation"},
+	)
+	retries = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      "csi_resizer",
+			Name:           "pvc_rate_limited_retries_total",
+			Help:           "Number of PVCs enqueued again with the rate limiter of an operation.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"operation"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(syncs, syncDuration, retries)
+}
+
+// Handler is the part of a controller that the Dispatcher calls.
+type Handler struct {
+	// AddPVC, UpdatePVC and DeletePVC filter PVC events and enqueue PVCs through the Queue of the operation.
+	AddPVC    func(obj interface{})
+	UpdatePVC func(oldObj, newObj interface{})
+	DeletePVC func(obj interface{})
+	// Sync processes a PVC and enqueues it again through the Queue of the operation if needed.
+	Sync func(key string) error
+}
+
+type registration struct {
+	Handler
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+// Dispatcher owns the PVC informer handler and a single queue of PVC keys. A worker processes
+// the operations enqueued for a PVC one after the other, so the controllers never work on the same
+// PVC at the same time. Each operation keeps its own rate limiter.
+type Dispatcher struct {
+	queue  workqueue.TypedDelayingInterface[string]
+	shards *sharding.Manager
+	now    func() time.Time
+
+	mu       sync.Mutex
+	ops      []coordinator.Operation
+	handlers map[coordinator.Operation]*registration
+	// pending maps PVC keys to the operations enqueued for them and when they are due.
+	pending map[string]map[coordinator.Operation]time.Time
+}
+
+// New returns a Dispatcher of the PVCs of pvcInformer. The controllers must register before the informer is started.
+func New(name string, pvcInformer coreinformers.PersistentVolumeClaimInformer, resyncPeriod time.Duration, shards *sharding.Manager) *Dispatcher {
+	d := &Dispatcher{
+		queue: workqueue.NewTypedDelayingQueueWithConfig(workqueue.TypedDelayingQueueConfig[string]{
+			Name: name + "-pvc",
+		}),
+		shards:   shards,
+		now:      time.Now,
+		handlers: map[coordinator.Operation]*registration{},
+		pending:  map[string]map[coordinator.Operation]time.Time{},
+	}
+	pvcInformer.Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
+		AddFunc:    d.addPVC,
+		UpdateFunc: d.updatePVC,
+		DeleteFunc: d.deletePVC,
+	}, resyncPeriod)
+	return d
+}
+
+// Queue returns the queue of op, with its own rate limiter. Adding a PVC key to it enqueues
+// the PVC for op in the shared queue. Its Get always reports a queue that is shut down,
+// the Dispatcher runs the workers and calls the Handler of op instead.
+func (d *Dispatcher) Queue(op coordinator.Operation, rateLimiter workqueue.TypedRateLimiter[string]) workqueue.TypedRateLimitingInterface[string] {
+	return &operationQueue{dispatcher: d, op: op, rateLimiter: rateLimiter}
+}
+
+// Register sets the Handler of op. Operations of a PVC are processed in the order they are registered.
+func (d *Dispatcher) Register(op coordinator.Operation, h Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.handlers[op]; !ok {
+		d.ops = append(d.ops, op)
+	}
+	d.handlers[op] = &registration{Handler: h, ready: make(chan struct{})}
+}
+
+// Ready tells the Dispatcher that the controller of op has synced its caches and may process PVCs.
+func (d *Dispatcher) Ready(op coordinator.Operation) {
+	d.mu.Lock()
+	r := d.handlers[op]
+	d.mu.Unlock()
+	if r != nil {
+		r.readyOnce.Do(func() { close(r.ready) })
+	}
+}
+
+// Run waits until all registered controllers are ready and processes PVCs with workers until ctx is done.
+func (d *Dispatcher) Run(workers int, ctx context.Context, wg *sync.WaitGroup) {
+	defer d.queue.ShutDown()
+
+	d.mu.Lock()
+	registrations := make([]*registration, 0, len(d.handlers))
+	for _, r := range d.handlers {
+		registrations = append(registrations, r)
+	}
+	d.mu.Unlock()
+	for _, r := range registrations {
+		select {
+		case <-r.ready:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	klog.InfoS("Starting PVC dispatcher", "operations", d.ops)
+	defer klog.InfoS("Shutting down PVC dispatcher")
+
+	stopCh := ctx.Done()
+	if utilfeature.DefaultFeatureGate.Enabled(features.ReleaseLeaderElectionOnExit) {
+		for range workers {
+			wg.Go(func() {
+				wait.Until(d.processNext, 0, stopCh)
+			})
+		}
+	} else {
+		for range workers {
+			go wait.Until(d.processNext, 0, stopCh)
+		}
+	}
+
+	<-stopCh
+}
+
+func (d *Dispatcher) addPVC(obj interface{}) {
+	key, err := util.GetObjectKey(obj)
+	if err != nil || !d.shards.Owns(key) {
+		return
+	}
+	for _, r := range d.registrations() {
+		r.AddPVC(obj)
+	}
+}
+
+func (d *Dispatcher) updatePVC(oldObj, newObj interface{}) {
+	key, err := util.GetObjectKey(newObj)
+	if err != nil || !d.shards.Owns(key) {
+		return
+	}
+	for _, r := range d.registrations() {
+		r.UpdatePVC(oldObj, newObj)
+	}
+}
+
+func (d *Dispatcher) deletePVC(obj interface{}) {
+	key, err := util.GetObjectKey(obj)
+	if err != nil {
+		return
+	}
+	for _, r := range d.registrations() {
+		r.DeletePVC(obj)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pending, key)
+}
+
+func (d *Dispatcher) registrations() []*registration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	registrations := make([]*registration, 0, len(d.ops))
+	for _, op := range d.ops {
+		registrations = append(registrations, d.handlers[op])
+	}
+	return registrations
+}
+
+// enqueue enqueues key for op after delay. An earlier pending time of op is kept.
+func (d *Dispatcher) enqueue(key string, op coordinator.Operation, delay time.Duration) {
+	at := d.now().Add(delay)
+	d.mu.Lock()
+	ops := d.pending[key]
+	if ops == nil {
+		ops = map[coordinator.Operation]time.Time{}
+		d.pending[key] = ops
+	}
+	if cur, ok := ops[op]; !ok || at.Before(cur) {
+		ops[op] = at
+	}
+	d.mu.Unlock()
+
+	if delay > 0 {
+		d.queue.AddAfter(key, delay)
+	} else {
+		d.queue.Add(key)
+	}
+}
+
+// due returns the operations of key that are due, in registration order, and enqueues
+// key again for the operations that are due later.
+func (d *Dispatcher) due(key string) []coordinator.Operation {
+	now := d.now()
+	var due []coordinator.Operation
+	var next time.Duration
+
+	d.mu.Lock()
+	ops := d.pending[key]
+	for _, op := range d.ops {
+		at, ok := ops[op]
+		switch {
+		case !ok:
+		case !at.After(now):
+			due = append(due, op)
+			delete(ops, op)
+		case next == 0 || at.Sub(now) < next:
+			next = at.Sub(now)
+		}
+	}
+	if len(ops) == 0 {
+		delete(d.pending, key)
+	}
+	d.mu.Unlock()
+
+	if next > 0 {
+		d.queue.AddAfter(key, next)
+	}
+	return due
+}
+
+func (d *Dispatcher) processNext() {
+	key, quit := d.queue.Get()
+	if quit {
+		return
+	}
+	defer d.queue.Done(key)
+
+	for _, op := range d.due(key) {
+		d.mu.Lock()
+		r := d.handlers[op]
+		d.mu.Unlock()
+
+		start := d.now()
+		result := "success"
+		if err := r.Sync(key); err != nil {
+			result = "error"
+		}
+		syncs.WithLabelValues(string(op), result).Inc()
+		syncDuration.WithLabelValues(string(op)).Observe(d.now().Sub(start).Seconds())
+	}
+}
+
+// pendingCount returns the number of PVCs enqueued for op.
+func (d *Dispatcher) pendingCount(op coordinator.Operation) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	count := 0
+	for _, ops := range d.pending {
+		if _, ok := ops[op]; ok {
+			count++
+		}
+	}
+	return count
+}
+
+// operationQueue is the queue of an operation in the shared queue of a Dispatcher.
+type operationQueue struct {
+	dispatcher  *Dispatcher
+	op          coordinator.Operation
+	rateLimiter workqueue.TypedRateLimiter[string]
+}
+
+var _ workqueue.TypedRateLimitingInterface[string] = &operationQueue{}
+
+func (q *operationQueue) Add(key string) {
+	q.dispatcher.enqueue(key, q.op, 0)
+}
+
+func (q *operationQueue) AddAfter(key string, duration time.Duration) {
+	q.dispatcher.enqueue(key, q.op, duration)
+}
+
+func (q *operationQueue) AddRateLimited(key string) {
+	retries.WithLabelValues(string(q.op)).Inc()
+	q.dispatcher.enqueue(key, q.op, q.rateLimiter.When(key))
+}
+
+func (q *operationQueue) Forget(key string) {
+	q.rateLimiter.Forget(key)
+}
+
+func (q *operationQueue) NumRequeues(key string) int {
+	return q.rateLimiter.NumRequeues(key)
+}
+
+func (q *operationQueue) Len() int {
+	return q.dispatcher.pendingCount(q.op)
+}
+
+func (q *operationQueue) Get() (string, bool) {
+	return "", true
+}
+
+func (q *operationQueue) Done(string) {}
+
+// ShutDown does nothing, the Dispatcher shuts the shared queue down.
+func (q *operationQueue) ShutDown() {}
+
+func (q *operationQueue) ShutDownWithDrain() {}
+
+func (q *operationQueue) ShuttingDown() bool {
+	return q.dispatcher.queue.ShuttingDown()
+}
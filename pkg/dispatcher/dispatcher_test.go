@@ -0,0 +1,120 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/coordinator"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const testKey = "default/pvc"
+
+type testController struct {
+	op     coordinator.Operation
+	queue  workqueue.TypedRateLimitingInterface[string]
+	synced *[]string
+	err    error
+}
+
+func (c *testController) handler() Handler {
+	return Handler{
+		AddPVC: func(obj interface{}) {
+			c.queue.Add(obj.(*v1.PersistentVolumeClaim).Namespace + "/" + obj.(*v1.PersistentVolumeClaim).Name)
+		},
+		UpdatePVC: func(_, newObj interface{}) {},
+		DeletePVC: func(obj interface{}) {},
+		Sync: func(key string) error {
+			*c.synced = append(*c.synced, string(c.op)+" "+key)
+			if c.err != nil {
+				c.queue.AddRateLimited(key)
+			}
+			return c.err
+		},
+	}
+}
+
+func newTestDispatcher(t *testing.T) (*Dispatcher, *testController, *testController, *[]string) {
+	t.Helper()
+	informerFactory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	d := New("test", informerFactory.Core().V1().PersistentVolumeClaims(), 0, nil)
+	t.Cleanup(d.queue.ShutDown)
+
+	var synced []string
+	resize := &testController{op: coordinator.Resize, synced: &synced,
+		queue: d.Queue(coordinator.Resize, workqueue.DefaultTypedControllerRateLimiter[string]())}
+	modify := &testController{op: coordinator.Modify, synced: &synced,
+		queue: d.Queue(coordinator.Modify, workqueue.NewTypedItemExponentialFailureRateLimiter[string](time.Hour, time.Hour))}
+	d.Register(coordinator.Resize, resize.handler())
+	d.Register(coordinator.Modify, modify.handler())
+	return d, resize, modify, &synced
+}
+
+func TestDispatch(t *testing.T) {
+	d, resize, modify, synced := newTestDispatcher(t)
+
+	// One PVC event reaches both controllers, their operations are processed in one go.
+	d.addPVC(&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "pvc", Namespace: "default"}})
+	if d.queue.Len() != 1 || resize.queue.Len() != 1 || modify.queue.Len() != 1 {
+		t.Fatalf("expected PVC to be enqueued once for both operations, got %d, %d, %d", d.queue.Len(), resize.queue.Len(), modify.queue.Len())
+	}
+	d.processNext()
+	if diff := cmp.Diff([]string{"resize " + testKey, "modify " + testKey}, *synced); diff != "" {
+		t.Errorf("unexpected syncs (-want +got):\n%s", diff)
+	}
+
+	// A failed operation is retried with its own rate limiter, the other one is not held back.
+	*synced = nil
+	modify.err = errors.New("modify failed")
+	modify.queue.Add(testKey)
+	d.processNext()
+	if modify.queue.Len() != 1 || modify.queue.NumRequeues(testKey) != 1 {
+		t.Fatalf("expected modify to be retried later, got %d pending and %d requeues", modify.queue.Len(), modify.queue.NumRequeues(testKey))
+	}
+	resize.queue.Add(testKey)
+	d.processNext()
+	if diff := cmp.Diff([]string{"modify " + testKey, "resize " + testKey}, *synced); diff != "" {
+		t.Errorf("unexpected syncs (-want +got):\n%s", diff)
+	}
+	if modify.queue.Len() != 1 {
+		t.Errorf("expected modify to stay pending")
+	}
+
+	d.deletePVC(&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "pvc", Namespace: "default"}})
+	if modify.queue.Len() != 0 {
+		t.Errorf("expected deleted PVC to be dropped")
+	}
+}
+
+func TestOperationQueueGet(t *testing.T) {
+	d, resize, _, _ := newTestDispatcher(t)
+	if _, shutdown := resize.queue.Get(); !shutdown {
+		t.Errorf("expected operation queue to report shut down to workers")
+	}
+	d.queue.ShutDown()
+	if !resize.queue.ShuttingDown() {
+		t.Errorf("expected operation queue to shut down with the shared queue")
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package expansionhistory records the last expansions of a volume in an annotation of its PV,
+// so that it can be told after the fact when a volume was expanded, by how much and whether
+// the expansion on the node finished.
+package expansionhistory
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	csitrans "k8s.io/csi-translation-lib"
+	"k8s.io/klog/v2"
+)
+
+// Annotation is the annotation of PVs with their expansion history, a JSON list of Records, oldest first.
+const Annotation = "resizer.csi.k8s.io/expansion-history"
+
+// Record is one expansion of a volume.
+type Record struct {
+	// Started is when ControllerExpandVolume was called.
+	Started metav1.Time `json:"started"`
+	// From and To are the capacity before and after the expansion.
+	From resource.Quantity `json:"from"`
+	To   resource.Quantity `json:"to"`
+	// NodeExpansionRequired is true if the volume had to be expanded on the node, too.
+	NodeExpansionRequired bool `json:"nodeExpansion,omitempty"`
+	// Duration is how long the whole expansion took, including the expansion on the node.
+	// It is not set while the expansion on the node has not finished.
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+// Finished returns true if the expansion finished, including the expansion on the node.
+func (r *Record) Finished() bool {
+	return r.Duration != nil
+}
+
+// invalidAnnotations holds the invalid annotations logged, by PV name, to log each one once.
+var invalidAnnotations sync.Map
+
+// Get returns the expansion history of pv, oldest first. An invalid annotation is ignored,
+// and replaced by the next expansion.
+func Get(pv *v1.PersistentVolume) []Record {
+	value, ok := pv.Annotations[Annotation]
+	if !ok {
+		return nil
+	}
+	var records []Record
+	if err := json.Unmarshal([]byte(value), &records); err != nil {
+		if logged, loaded := invalidAnnotations.Swap(pv.Name, value); !loaded || logged != value {
+			klog.ErrorS(err, "Ignoring invalid expansion history", "PV", klog.KObj(pv))
+		}
+		return nil
+	}
+	return records
+}
+
+func set(pv *v1.PersistentVolume, records []Record) {
+	value, err := json.Marshal(records)
+	if err != nil {
+		// Records always marshal.
+		klog.ErrorS(err, "Failed to marshal expansion history", "PV", klog.KObj(pv))
+		return
+	}
+	metav1.SetMetaDataAnnotation(&pv.ObjectMeta, Annotation, string(value))
+}
+
+// Append adds record to the history of pv and drops the oldest records beyond limit.
+// Unfinished records stay unfinished, they are finished with the next expansion on the node.
+func Append(pv *v1.PersistentVolume, record Record, limit int) {
+	if limit <= 0 {
+		return
+	}
+	records := append(Get(pv), roundDuration(record))
+	if len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+	set(pv, records)
+}
+
+// FinishNodeExpansion finishes the unfinished records of pv at now and returns the last one,
+// or nil if all records were finished already.
+func FinishNodeExpansion(pv *v1.PersistentVolume, now time.Time) *Record {
+	records := Get(pv)
+	if !finishNodeExpansion(records, now) {
+		return nil
+	}
+	set(pv, records)
+	return &records[len(records)-1]
+}
+
+// NodeExpansionPending returns true if the history of pv has a record waiting for the expansion on the node.
+func NodeExpansionPending(pv *v1.PersistentVolume) bool {
+	return slices.ContainsFunc(Get(pv), func(r Record) bool { return !r.Finished() })
+}
+
+func finishNodeExpansion(records []Record, now time.Time) bool {
+	finished := false
+	for i := range records {
+		if !records[i].Finished() {
+			records[i].Duration = &metav1.Duration{Duration: now.Sub(records[i].Started.Time)}
+			records[i] = roundDuration(records[i])
+			finished = true
+		}
+	}
+	return finished
+}
+
+func roundDuration(record Record) Record {
+	if record.Duration != nil {
+		record.Duration = &metav1.Duration{Duration: record.Duration.Round(time.Millisecond)}
+	}
+	return record
+}
+
+// Volume is the expansion history of a PV, as served by Handler.
+type Volume struct {
+	PersistentVolume      string   `json:"persistentVolume"`
+	PersistentVolumeClaim string   `json:"persistentVolumeClaim,omitempty"`
+	Expansions            []Record `json:"expansions"`
+}
+
+// Handler serves the expansion history of the PVs of a driver as JSON.
+type Handler struct {
+	pvLister   corelisters.PersistentVolumeLister
+	driverName string
+}
+
+// NewHandler returns a Handler of the PVs of driverName.
+func NewHandler(pvLister corelisters.PersistentVolumeLister, driverName string) *Handler {
+	return &Handler{pvLister: pvLister, driverName: driverName}
+}
+
+// ServeHTTP writes the expansion histories, sorted by PV name. The pv query parameter
+// selects a single PV.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	pvs, err := h.pvLister.List(labels.Everything())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	name := req.URL.Query().Get("pv")
+	volumes := []Volume{}
+	for _, pv := range pvs {
+		if name != "" && pv.Name != name {
+			continue
+		}
+		if driver(pv) != h.driverName {
+			continue
+		}
+		records := Get(pv)
+		if len(records) == 0 {
+			continue
+		}
+		volume := Volume{PersistentVolume: pv.Name, Expansions: records}
+		if ref := pv.Spec.ClaimRef; ref != nil {
+			volume.PersistentVolumeClaim = ref.Namespace + "/" + ref.Name
+		}
+		volumes = append(volumes, volume)
+	}
+	slices.SortFunc(volumes, func(a, b Volume) int {
+		return strings.Compare(a.PersistentVolume, b.PersistentVolume)
+	})
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(volumes); err != nil {
+		klog.ErrorS(err, "Failed to write expansion history")
+	}
+}
+
+// driver returns the CSI driver of pv, or the CSI driver that an in-tree volume is migrated to.
+func driver(pv *v1.PersistentVolume) string {
+	if pv.Spec.CSI != nil {
+		return pv.Spec.CSI.Driver
+	}
+	translator := csitrans.New()
+	plugin, err := translator.GetInTreePluginNameFromSpec(pv, nil)
+	if err != nil {
+		return ""
+	}
+	name, err := translator.GetCSINameFromInTreeName(plugin)
+	if err != nil {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expansionhistory
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var testStart = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func record(minute int, from, to string, nodeExpansion bool) Record {
+	r := Record{
+		Started:               metav1.NewTime(testStart.Add(time.Duration(minute) * time.Minute)),
+		From:                  resource.MustParse(from),
+		To:                    resource.MustParse(to),
+		NodeExpansionRequired: nodeExpansion,
+	}
+	if !nodeExpansion {
+		r.Duration = &metav1.Duration{Duration: 1500 * time.Microsecond}
+	}
+	return r
+}
+
+func testPV(name, driver string) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: driver, VolumeHandle: name},
+			},
+			ClaimRef: &v1.ObjectReference{Namespace: "default", Name: "claim-" + name},
+		},
+	}
+}
+
+func TestAppend(t *testing.T) {
+	pv := testPV("pv", "mock")
+	Append(pv, record(0, "1Gi", "2Gi", false), 2)
+	Append(pv, record(1, "2Gi", "3Gi", true), 2)
+	Append(pv, record(3, "3Gi", "4Gi", false), 2)
+
+	records := Get(pv)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %+v", records)
+	}
+	if records[0].To.String() != "3Gi" || records[1].To.String() != "4Gi" {
+		t.Errorf("expected the last 2 expansions, got %+v", records)
+	}
+	// The node expansion of the previous record is not finished by the next expansion.
+	if records[0].Finished() || !NodeExpansionPending(pv) {
+		t.Errorf("expected node expansion pending, got %+v", records[0].Duration)
+	}
+	if records[1].Duration.Duration != 2*time.Millisecond {
+		t.Errorf("expected duration rounded to milliseconds, got %v", records[1].Duration)
+	}
+	if finished := FinishNodeExpansion(pv, testStart.Add(5*time.Minute)); finished == nil || Get(pv)[0].Duration.Duration != 4*time.Minute {
+		t.Errorf("expected node expansion finished after 4m, got %q", pv.Annotations[Annotation])
+	}
+}
+
+func TestAppendDisabled(t *testing.T) {
+	pv := testPV("pv", "mock")
+	Append(pv, record(0, "1Gi", "2Gi", false), 0)
+	if _, ok := pv.Annotations[Annotation]; ok {
+		t.Errorf("expected no history with limit 0, got %q", pv.Annotations[Annotation])
+	}
+}
+
+func TestFinishNodeExpansion(t *testing.T) {
+	pv := testPV("pv", "mock")
+	if FinishNodeExpansion(pv, testStart) != nil {
+		t.Errorf("expected nothing to finish without history")
+	}
+	Append(pv, record(0, "1Gi", "2Gi", true), 5)
+	if !NodeExpansionPending(pv) {
+		t.Fatalf("expected node expansion pending")
+	}
+
+	finished := FinishNodeExpansion(pv, testStart.Add(90*time.Second))
+	if finished == nil || finished.Duration.Duration != 90*time.Second {
+		t.Errorf("expected expansion finished after 90s, got %+v", finished)
+	}
+	if NodeExpansionPending(pv) || FinishNodeExpansion(pv, testStart.Add(time.Hour)) != nil {
+		t.Errorf("expected no node expansion pending, got %q", pv.Annotations[Annotation])
+	}
+}
+
+func TestGetInvalid(t *testing.T) {
+	pv := testPV("pv", "mock")
+	metav1.SetMetaDataAnnotation(&pv.ObjectMeta, Annotation, "not json")
+	if records := Get(pv); records != nil {
+		t.Errorf("expected invalid history ignored, got %+v", records)
+	}
+	Append(pv, record(0, "1Gi", "2Gi", false), 5)
+	if records := Get(pv); len(records) != 1 {
+		t.Errorf("expected invalid history replaced, got %+v", records)
+	}
+}
+
+func TestDriver(t *testing.T) {
+	migrated := testPV("pv", "")
+	migrated.Spec.CSI = nil
+	migrated.Spec.AWSElasticBlockStore = &v1.AWSElasticBlockStoreVolumeSource{VolumeID: "vol"}
+	if name := driver(migrated); name != "ebs.csi.aws.com" {
+		t.Errorf("expected the driver that the in-tree volume is migrated to, got %q", name)
+	}
+	if name := driver(testPV("pv", "mock")); name != "mock" {
+		t.Errorf("expected the CSI driver, got %q", name)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	expanded, other, unexpanded := testPV("b", "mock"), testPV("c", "other"), testPV("d", "mock")
+	Append(expanded, record(0, "1Gi", "2Gi", false), 5)
+	Append(other, record(0, "1Gi", "2Gi", false), 5)
+	second := testPV("a", "mock")
+	Append(second, record(0, "1Gi", "2Gi", true), 5)
+	inTree := testPV("e", "")
+	inTree.Spec.CSI = nil
+	inTree.Spec.NFS = &v1.NFSVolumeSource{Server: "nfs", Path: "/e"}
+	Append(inTree, record(0, "1Gi", "2Gi", false), 5)
+
+	informerFactory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	pvInformer := informerFactory.Core().V1().PersistentVolumes()
+	for _, pv := range []*v1.PersistentVolume{expanded, other, unexpanded, second, inTree} {
+		pvInformer.Informer().GetStore().Add(pv)
+	}
+	handler := NewHandler(pvInformer.Lister(), "mock")
+
+	for query, expected := range map[string][]string{
+		"":      {"a", "b"},
+		"?pv=b": {"b"},
+		"?pv=c": {},
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/expansions"+query, nil))
+		var volumes []Volume
+		if err := json.Unmarshal(recorder.Body.Bytes(), &volumes); err != nil {
+			t.Fatalf("invalid response %q: %v", recorder.Body.String(), err)
+		}
+		var names []string
+		for _, volume := range volumes {
+			names = append(names, volume.PersistentVolume)
+			if volume.PersistentVolumeClaim != "default/claim-"+volume.PersistentVolume || len(volume.Expansions) != 1 {
+				t.Errorf("unexpected volume %+v", volume)
+			}
+		}
+		if !slices.Equal(names, expected) {
+			t.Errorf("query %q: expected %v, got %v", query, expected, names)
+		}
+	}
+}
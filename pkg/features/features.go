@@ -54,6 +54,11 @@ const (
 	//
 	// Releases leader election lease on sigterm / sigint.
 	ReleaseLeaderElectionOnExit featuregate.Feature = "ReleaseLeaderElectionOnExit"
+
+	// beta: v1.37
+	//
+	// Writes PVC status and PV fields with server-side apply, with a field manager per controller.
+	ServerSideApply featuregate.Feature = "ServerSideApply"
 )
 
 func init() {
@@ -65,6 +70,7 @@ var defaultResizerFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec
 	RecoverVolumeExpansionFailure: {Default: true, PreRelease: featuregate.GA},
 	VolumeAttributesClass:         {Default: true, PreRelease: featuregate.GA},
 	ReleaseLeaderElectionOnExit:   {Default: false, PreRelease: featuregate.Alpha},
+	ServerSideApply:               {Default: true, PreRelease: featuregate.Beta},
 }
 
 // IsVolumeAttributesClassV1Enabled checks if the VolumeAttributesClass v1 API is enabled.
@@ -22,17 +22,17 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/credentials"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/csi"
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 )
 
 const (
 	// annotations set by the external-provisioner when a modify secret is configured
-	modifySecretNameAnn      = "volume.kubernetes.io/controller-modify-secret-name"
-	modifySecretNamespaceAnn = "volume.kubernetes.io/controller-modify-secret-namespace"
+	ModifySecretNameAnn      = "volume.kubernetes.io/controller-modify-secret-name"
+	ModifySecretNamespaceAnn = "volume.kubernetes.io/controller-modify-secret-namespace"
 )
 
 var ModifyNotSupportErr = errors.New("CSI driver does not support controller modify")
@@ -43,7 +43,8 @@ func NewModifierFromClient(
 	k8sClient kubernetes.Interface,
 	informerFactory informers.SharedInformerFactory,
 	extraModifyMetadata bool,
-	driverName string) (Modifier, error) {
+	driverName string,
+	credentialProvider credentials.Provider) (Modifier, error) {
 
 	supported, err := supportsControllerModify(csiClient, timeout)
 	if err != nil {
@@ -53,13 +54,18 @@ func NewModifierFromClient(
 		return nil, ModifyNotSupportErr
 	}
 
+	if credentialProvider == nil {
+		credentialProvider = credentials.NewAPIProvider(k8sClient)
+	}
+
 	return &csiModifier{
 		name:                driverName,
 		client:              csiClient,
 		timeout:             timeout,
 		extraModifyMetadata: extraModifyMetadata,
 
-		k8sClient: k8sClient,
+		k8sClient:   k8sClient,
+		credentials: credentialProvider,
 	}, nil
 }
 
@@ -69,7 +75,8 @@ type csiModifier struct {
 	timeout             time.Duration
 	extraModifyMetadata bool
 
-	k8sClient kubernetes.Interface
+	k8sClient   kubernetes.Interface
+	credentials credentials.Provider
 }
 
 func (r *csiModifier) Name() string {
@@ -112,8 +119,8 @@ func (r *csiModifier) Modify(ctx context.Context, pv *v1.PersistentVolume, mutab
 // getModifyCredentials fetches the credential from the secret referenced in the annotations. When missing,
 // the default secretRef (CSIPersistentVolumeSource.ControllerExpandSecretRef) is used.
 func (r *csiModifier) getModifyCredentials(ctx context.Context, secretRef *v1.SecretReference, annotations map[string]string) (map[string]string, error) {
-	secretName := annotations[modifySecretNameAnn]
-	secretNamespace := annotations[modifySecretNamespaceAnn]
+	secretName := annotations[ModifySecretNameAnn]
+	secretNamespace := annotations[ModifySecretNamespaceAnn]
 	if secretNamespace == "" || secretName == "" {
 		if secretRef == nil {
 			return nil, nil
@@ -123,16 +130,7 @@ func (r *csiModifier) getModifyCredentials(ctx context.Context, secretRef *v1.Se
 		secretNamespace = secretRef.Namespace
 	}
 
-	secret, err := r.k8sClient.CoreV1().Secrets(secretNamespace).Get(ctx, secretName, metav1.GetOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("error getting secret %s in namespace %s: %v", secretName, secretNamespace, err)
-	}
-
-	credentials := map[string]string{}
-	for key, value := range secret.Data {
-		credentials[key] = string(value)
-	}
-	return credentials, nil
+	return r.credentials.GetCredentials(ctx, &v1.SecretReference{Name: secretName, Namespace: secretNamespace})
 }
 
 func supportsControllerModify(client csi.Client, timeout time.Duration) (bool, error) {
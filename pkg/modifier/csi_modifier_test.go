@@ -32,7 +32,7 @@ func TestNewModifier(t *testing.T) {
 		client := csi.NewMockClient("mock", false, false, c.SupportsControllerModify, false, false)
 		driverName := "mock-driver"
 		k8sClient, informerFactory := fakeK8s()
-		_, err := NewModifierFromClient(client, 0, k8sClient, informerFactory, false, driverName)
+		_, err := NewModifierFromClient(client, 0, k8sClient, informerFactory, false, driverName, nil)
 		if err != c.Error {
 			t.Errorf("Case %d: Unexpected error: wanted %v, got %v", i, c.Error, err)
 		}
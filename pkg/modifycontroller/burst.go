@@ -0,0 +1,151 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package modifycontroller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+)
+
+var burstAnnotations = []string{util.AnnBurstVAC, util.AnnBurstStart, util.AnnBurstEnd, util.AnnBurstRevertVAC}
+
+// burstChanged returns true if a temporary VolumeAttributesClass change of the PVC was requested, changed or cancelled.
+func burstChanged(oldPVC, newPVC *v1.PersistentVolumeClaim) bool {
+	for _, ann := range burstAnnotations {
+		if oldPVC.Annotations[ann] != newPVC.Annotations[ann] {
+			return true
+		}
+	}
+	return false
+}
+
+// syncBurst carries out a temporary VolumeAttributesClass change requested with the AnnBurstVAC,
+// AnnBurstStart and AnnBurstEnd annotations. At the start, it records the current VolumeAttributesClass
+// in AnnBurstRevertVAC and switches the PVC to the burst class. At the end, it switches the PVC back and
+// removes the annotations. All state is kept in the PVC, so that it survives restarts of the resizer.
+// If the VolumeAttributesClass was changed by someone else in the meantime, it is left alone.
+// The changes of spec.volumeAttributesClassName are then carried out by the normal modify path.
+// syncBurst returns the possibly updated PVC and the time left until the next step, or zero if there is none.
+func (ctrl *modifyController) syncBurst(pvc *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, time.Duration, error) {
+	burstVAC, ok := pvc.Annotations[util.AnnBurstVAC]
+	if !ok {
+		ctrl.invalidBursts.Delete(pvc.Namespace + "/" + pvc.Name)
+		return pvc, 0, nil
+	}
+
+	start, end, err := parseBurstWindow(pvc)
+	if err != nil || burstVAC == "" {
+		if err == nil {
+			err = fmt.Errorf("%s annotation is empty", util.AnnBurstVAC)
+		}
+		ctrl.warnInvalidBurst(pvc, fmt.Sprintf("Ignoring temporary VolumeAttributesClass change: %v", err))
+		return pvc, 0, nil
+	}
+
+	now := time.Now()
+	currentVAC := ptr.Deref(pvc.Spec.VolumeAttributesClassName, "")
+	revertVAC, started := pvc.Annotations[util.AnnBurstRevertVAC]
+
+	if now.Before(end) {
+		if started {
+			return pvc, end.Sub(now), nil
+		}
+		if now.Before(start) {
+			return pvc, start.Sub(now), nil
+		}
+		if currentVAC == "" || currentVAC == burstVAC {
+			ctrl.warnInvalidBurst(pvc, fmt.Sprintf(
+				"Ignoring temporary VolumeAttributesClass change: PVC must use a VolumeAttributesClass other than %q to revert to", burstVAC))
+			return pvc, 0, nil
+		}
+
+		newPVC := pvc.DeepCopy()
+		newPVC.Annotations[util.AnnBurstRevertVAC] = currentVAC
+		newPVC.Spec.VolumeAttributesClassName = &burstVAC
+		updatedPVC, err := util.PatchClaimMetadata(ctrl.kubeClient, pvc, newPVC, true)
+		if err != nil {
+			return pvc, 0, err
+		}
+		klog.V(2).InfoS("Started temporary VolumeAttributesClass change", "PVC", klog.KObj(pvc), "VAC", burstVAC, "revertVAC", currentVAC, "end", end)
+		ctrl.eventRecorder.Eventf(updatedPVC, v1.EventTypeNormal, util.VolumeModifyBurst,
+			"Changed VolumeAttributesClass from %s to %s until %s", currentVAC, burstVAC, end.Format(time.RFC3339))
+		return updatedPVC, end.Sub(now), nil
+	}
+
+	// The window is over.
+	newPVC := pvc.DeepCopy()
+	for _, ann := range burstAnnotations {
+		delete(newPVC.Annotations, ann)
+	}
+	var message string
+	switch {
+	case !started:
+		message = fmt.Sprintf("Skipped temporary change to VolumeAttributesClass %s, its end %s has passed", burstVAC, end.Format(time.RFC3339))
+	case currentVAC != burstVAC:
+		message = fmt.Sprintf("Not reverting VolumeAttributesClass to %s, it was changed to %s in the meantime", revertVAC, currentVAC)
+	default:
+		newPVC.Spec.VolumeAttributesClassName = &revertVAC
+		message = fmt.Sprintf("Reverted VolumeAttributesClass from %s to %s", burstVAC, revertVAC)
+	}
+	// The resource version check makes sure that changes made in the meantime are not overwritten.
+	updatedPVC, err := util.PatchClaimMetadata(ctrl.kubeClient, pvc, newPVC, true)
+	if err != nil {
+		return pvc, 0, err
+	}
+	klog.V(2).InfoS("Finished temporary VolumeAttributesClass change", "PVC", klog.KObj(pvc), "message", message)
+	ctrl.eventRecorder.Event(updatedPVC, v1.EventTypeNormal, util.VolumeModifyBurst, message)
+	return updatedPVC, 0, nil
+}
+
+// warnInvalidBurst records a warning about the temporary VolumeAttributesClass change of pvc, unless
+// the same warning was recorded already.
+func (ctrl *modifyController) warnInvalidBurst(pvc *v1.PersistentVolumeClaim, message string) {
+	if previous, warned := ctrl.invalidBursts.Swap(pvc.Namespace+"/"+pvc.Name, message); warned && previous == message {
+		klog.V(4).InfoS("Ignoring invalid temporary VolumeAttributesClass change", "PVC", klog.KObj(pvc))
+		return
+	}
+	ctrl.eventRecorder.Event(pvc, v1.EventTypeWarning, util.InvalidVolumeModifyBurst, message)
+}
+
+// parseBurstWindow returns the start and end time of a temporary VolumeAttributesClass change.
+// The start is optional.
+func parseBurstWindow(pvc *v1.PersistentVolumeClaim) (time.Time, time.Time, error) {
+	var start time.Time
+	if value, ok := pvc.Annotations[util.AnnBurstStart]; ok {
+		var err error
+		if start, err = time.Parse(time.RFC3339, value); err != nil {
+			return start, start, fmt.Errorf("invalid %s annotation %q: %v", util.AnnBurstStart, value, err)
+		}
+	}
+	value, ok := pvc.Annotations[util.AnnBurstEnd]
+	if !ok {
+		return start, start, fmt.Errorf("%s annotation is missing", util.AnnBurstEnd)
+	}
+	end, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return start, end, fmt.Errorf("invalid %s annotation %q: %v", util.AnnBurstEnd, value, err)
+	}
+	if !end.After(start) {
+		return start, end, fmt.Errorf("%s must be after %s", util.AnnBurstEnd, util.AnnBurstStart)
+	}
+	return start, end, nil
+}
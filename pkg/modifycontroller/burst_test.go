@@ -0,0 +1,152 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package modifycontroller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/csi"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+)
+
+func TestSyncBurst(t *testing.T) {
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	later := time.Now().Add(2 * time.Hour).Format(time.RFC3339)
+
+	for _, test := range []struct {
+		name        string
+		vacName     string
+		annotations map[string]string
+
+		expectDelay       bool
+		expectedVAC       string
+		expectAnnotations map[string]string
+		expectedEvent     string
+	}{
+		{
+			name:        "no burst",
+			vacName:     testVac,
+			expectedVAC: testVac,
+		},
+		{
+			name:              "start pending",
+			vacName:           testVac,
+			annotations:       map[string]string{util.AnnBurstVAC: targetVac, util.AnnBurstStart: future, util.AnnBurstEnd: later},
+			expectDelay:       true,
+			expectedVAC:       testVac,
+			expectAnnotations: map[string]string{util.AnnBurstVAC: targetVac, util.AnnBurstStart: future, util.AnnBurstEnd: later},
+		},
+		{
+			name:              "start",
+			vacName:           testVac,
+			annotations:       map[string]string{util.AnnBurstVAC: targetVac, util.AnnBurstEnd: future},
+			expectDelay:       true,
+			expectedVAC:       targetVac,
+			expectAnnotations: map[string]string{util.AnnBurstVAC: targetVac, util.AnnBurstEnd: future, util.AnnBurstRevertVAC: testVac},
+			expectedEvent:     "Normal VolumeModifyBurst Changed VolumeAttributesClass from " + testVac + " to " + targetVac,
+		},
+		{
+			name:              "in progress",
+			vacName:           targetVac,
+			annotations:       map[string]string{util.AnnBurstVAC: targetVac, util.AnnBurstEnd: future, util.AnnBurstRevertVAC: testVac},
+			expectDelay:       true,
+			expectedVAC:       targetVac,
+			expectAnnotations: map[string]string{util.AnnBurstVAC: targetVac, util.AnnBurstEnd: future, util.AnnBurstRevertVAC: testVac},
+		},
+		{
+			name:          "revert",
+			vacName:       targetVac,
+			annotations:   map[string]string{util.AnnBurstVAC: targetVac, util.AnnBurstEnd: past, util.AnnBurstRevertVAC: testVac},
+			expectedVAC:   testVac,
+			expectedEvent: "Normal VolumeModifyBurst Reverted VolumeAttributesClass from " + targetVac + " to " + testVac,
+		},
+		{
+			name:          "changed by user",
+			vacName:       "other",
+			annotations:   map[string]string{util.AnnBurstVAC: targetVac, util.AnnBurstEnd: past, util.AnnBurstRevertVAC: testVac},
+			expectedVAC:   "other",
+			expectedEvent: "Normal VolumeModifyBurst Not reverting VolumeAttributesClass to " + testVac + ", it was changed to other in the meantime",
+		},
+		{
+			name:          "window missed",
+			vacName:       testVac,
+			annotations:   map[string]string{util.AnnBurstVAC: targetVac, util.AnnBurstEnd: past},
+			expectedVAC:   testVac,
+			expectedEvent: "Normal VolumeModifyBurst Skipped temporary change to VolumeAttributesClass " + targetVac,
+		},
+		{
+			name:              "missing end",
+			vacName:           testVac,
+			annotations:       map[string]string{util.AnnBurstVAC: targetVac},
+			expectedVAC:       testVac,
+			expectAnnotations: map[string]string{util.AnnBurstVAC: targetVac},
+			expectedEvent:     "Warning InvalidVolumeModifyBurst Ignoring temporary VolumeAttributesClass change: resizer.csi.k8s.io/burst-end annotation is missing",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			pvc := createTestPVC(pvcName, test.vacName, test.vacName, "" /*targetVacName*/)
+			pvc.Annotations = test.annotations
+			pv := createTestPV(1, pvcName, pvcNamespace, "foobaz" /*pvcUID*/, &fsVolumeMode, test.vacName)
+
+			client := csi.NewMockClient(testDriverName, true, true, true, true, true)
+			ctrlInstance := setupFakeK8sEnvironment(t, client, []runtime.Object{testVacObject, targetVacObject, pvc, pv})
+			recorder := record.NewFakeRecorder(10)
+			ctrlInstance.eventRecorder = recorder
+
+			updatedPVC, delay, err := ctrlInstance.syncBurst(pvc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (delay > 0) != test.expectDelay {
+				t.Errorf("expected delay %v, got %s", test.expectDelay, delay)
+			}
+			if vac := ptr.Deref(updatedPVC.Spec.VolumeAttributesClassName, ""); vac != test.expectedVAC {
+				t.Errorf("expected VolumeAttributesClass %q, got %q", test.expectedVAC, vac)
+			}
+			for _, ann := range burstAnnotations {
+				if updatedPVC.Annotations[ann] != test.expectAnnotations[ann] {
+					t.Errorf("expected annotation %s=%q, got %q", ann, test.expectAnnotations[ann], updatedPVC.Annotations[ann])
+				}
+			}
+
+			select {
+			case event := <-recorder.Events:
+				if test.expectedEvent == "" || !strings.HasPrefix(event, test.expectedEvent) {
+					t.Errorf("expected event %q, got %q", test.expectedEvent, event)
+				}
+			default:
+				if test.expectedEvent != "" {
+					t.Errorf("expected event %q, got none", test.expectedEvent)
+				}
+			}
+
+			// Syncing again repeats neither the change nor a warning.
+			if _, _, err := ctrlInstance.syncBurst(updatedPVC); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(recorder.Events) != 0 {
+				t.Errorf("expected no event on the second sync, got %q", <-recorder.Events)
+			}
+		})
+	}
+}
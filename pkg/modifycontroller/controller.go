@@ -19,15 +19,26 @@ package modifycontroller
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/audit"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/coordinator"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/credentials"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/dispatcher"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/features"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/policy"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/pricing"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/sharding"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/vacschema"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/volumerequest"
 
 	"github.com/kubernetes-csi/csi-lib-utils/slowset"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/modifier"
 	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -45,6 +56,9 @@ import (
 	"k8s.io/utils/ptr"
 )
 
+// pendingVACIndex is the name of the PVC index by pending VolumeAttributesClass.
+const pendingVACIndex = "modify-pending-vac"
+
 // ModifyController watches PVCs and checks if they are requesting an modify operation.
 // If requested, it will modify the volume according to parameters in VolumeAttributesClass
 type ModifyController interface {
@@ -65,6 +79,10 @@ type modifyController struct {
 	vacLister           storagev1listers.VolumeAttributesClassLister
 	vacListerSynced     cache.InformerSynced
 	extraModifyMetadata bool
+	// extraMetadata selects the PVC labels and annotations passed with extraModifyMetadata.
+	extraMetadata util.ExtraMetadata
+	// policy restricts which VolumeAttributesClass changes are carried out, nil allows all.
+	policy *policy.ModifyPolicy
 	// uncertainPVCs tracks PVCs that failed with non-final errors.
 	// We must not change the target when retrying.
 	// All in-progress PVCs are added here on initialization.
@@ -72,6 +90,62 @@ type modifyController struct {
 	uncertainPVCs sync.Map
 	// slowSet tracks PVCs for which modification failed with infeasible error and should be retried at slower rate.
 	slowSet *slowset.SlowSet
+	// auditSink receives a record of every modification, nil disables auditing.
+	auditSink audit.Sink
+	// costEstimator annotates PVCs with the cost impact of modifications, nil disables it.
+	costEstimator *pricing.Estimator
+	// shards limits the controller to the PVCs of the shards this replica owns, nil handles all PVCs.
+	shards *sharding.Manager
+	// secretTemplates resolves modification secrets from class templates, nil uses the secret of the PV.
+	secretTemplates *credentials.TemplateResolver
+	// metadataParameters maps labels and annotations to modify parameters, nil disables it.
+	metadataParameters *util.MetadataParameters
+	// vacSchema validates the parameters of VolumeAttributesClasses, nil accepts all.
+	vacSchema vacschema.Source
+	// pvcIndexer indexes the PVCs waiting for a VAC that doesn't exist yet.
+	pvcIndexer cache.Indexer
+	// coordinator serializes expansion and modification of PVCs, nil lets them run independently.
+	coordinator *coordinator.Coordinator
+	// dispatcher runs the workers of the PVCs shared with the resize controller, nil runs them here.
+	dispatcher *dispatcher.Dispatcher
+	// drift configures when volumes are modified again with their current VAC.
+	drift DriftReconciliation
+	// driftPending maps keys of PVCs enqueued for drift reconciliation to the reason.
+	driftPending sync.Map
+	// vacUIDs tracks the UIDs of VACs of the driver by name, to notice when they are created again.
+	vacUIDs sync.Map
+	// history records each VolumeAttributesClass change in a VolumeModifyRequest, nil disables it.
+	history *volumerequest.ModifyHistory
+	// invalidBursts holds the last warning about an invalid temporary VolumeAttributesClass change, by PVC key.
+	invalidBursts sync.Map
+}
+
+// Options are the optional features of the modify controller. The zero value disables all of them.
+type Options struct {
+	// Policy restricts which VolumeAttributesClass changes are carried out, nil allows all.
+	Policy *policy.ModifyPolicy
+	// AuditSink receives a record of every modification, nil disables auditing.
+	AuditSink audit.Sink
+	// CostEstimator annotates PVCs with the cost impact of modifications, nil disables it.
+	CostEstimator *pricing.Estimator
+	// Shards limits the controller to the PVCs of the shards this replica owns, nil handles all PVCs.
+	Shards *sharding.Manager
+	// SecretTemplates resolves modification secrets from class templates, nil uses the secret of the PV.
+	SecretTemplates *credentials.TemplateResolver
+	// ExtraMetadata selects the PV and PVC metadata passed to the modifier.
+	ExtraMetadata util.ExtraMetadata
+	// MetadataParameters maps labels and annotations to modify parameters, nil disables it.
+	MetadataParameters *util.MetadataParameters
+	// VACSchema validates the parameters of VolumeAttributesClasses, nil accepts all.
+	VACSchema vacschema.Source
+	// Drift configures when volumes are modified again with their current VAC.
+	Drift DriftReconciliation
+	// Coordinator serializes expansion and modification of PVCs, nil lets them run independently.
+	Coordinator *coordinator.Coordinator
+	// Dispatcher runs the workers of the PVCs shared with the resize controller, nil runs them here.
+	Dispatcher *dispatcher.Dispatcher
+	// History records each VolumeAttributesClass change in a VolumeModifyRequest, nil disables it.
+	History *volumerequest.ModifyHistory
 }
 
 // NewModifyController returns a ModifyController.
@@ -83,7 +157,10 @@ func NewModifyController(
 	maxRetryInterval time.Duration,
 	extraModifyMetadata bool,
 	informerFactory informers.SharedInformerFactory,
-	pvcRateLimiter workqueue.TypedRateLimiter[string]) ModifyController {
+	pvcRateLimiter workqueue.TypedRateLimiter[string],
+	opts Options) ModifyController {
+	shardManager, coord, pvcDispatcher := opts.Shards, opts.Coordinator, opts.Dispatcher
+	metadataParameters, vacSchema := opts.MetadataParameters, opts.VACSchema
 	pvInformer := informerFactory.Core().V1().PersistentVolumes()
 	pvcInformer := informerFactory.Core().V1().PersistentVolumeClaims()
 	vacInformer := informerFactory.Storage().V1().VolumeAttributesClasses()
@@ -93,10 +170,15 @@ func NewModifyController(
 	eventRecorder := eventBroadcaster.NewRecorder(scheme.Scheme,
 		v1.EventSource{Component: fmt.Sprintf("external-resizer %s", name)})
 
-	claimQueue := workqueue.NewTypedRateLimitingQueueWithConfig(
-		pvcRateLimiter, workqueue.TypedRateLimitingQueueConfig[string]{
-			Name: fmt.Sprintf("%s-pvc", name),
-		})
+	var claimQueue workqueue.TypedRateLimitingInterface[string]
+	if pvcDispatcher != nil {
+		claimQueue = pvcDispatcher.Queue(coordinator.Modify, pvcRateLimiter)
+	} else {
+		claimQueue = workqueue.NewTypedRateLimitingQueueWithConfig(
+			pvcRateLimiter, workqueue.TypedRateLimitingQueueConfig[string]{
+				Name: fmt.Sprintf("%s-pvc", name),
+			})
+	}
 
 	ctrl := &modifyController{
 		name:                name,
@@ -106,28 +188,152 @@ func NewModifyController(
 		pvLister:            pvInformer.Lister(),
 		pvcListerSynced:     pvcInformer.Informer().HasSynced,
 		pvcLister:           pvcInformer.Lister(),
+		pvcIndexer:          pvcInformer.Informer().GetIndexer(),
 		vacListerSynced:     vacInformer.Informer().HasSynced,
 		vacLister:           vacInformer.Lister(),
 		claimQueue:          claimQueue,
 		eventRecorder:       eventRecorder,
 		extraModifyMetadata: extraModifyMetadata,
+		policy:              opts.Policy,
 		slowSet:             slowset.NewSlowSet(maxRetryInterval),
+		auditSink:           opts.AuditSink,
+		costEstimator:       opts.CostEstimator,
+		shards:              shardManager,
+		secretTemplates:     opts.SecretTemplates,
+		extraMetadata:       opts.ExtraMetadata,
+		metadataParameters:  metadataParameters,
+		vacSchema:           vacSchema,
+		drift:               opts.Drift,
+		coordinator:         coord,
+		dispatcher:          pvcDispatcher,
+		history:             opts.History,
+	}
+	shardManager.AddHandler(ctrl)
+	coord.Register(coordinator.Modify, claimQueue.Add)
+	if pvcDispatcher != nil {
+		pvcDispatcher.Register(coordinator.Modify, dispatcher.Handler{
+			AddPVC:    ctrl.addPVC,
+			UpdatePVC: ctrl.updatePVC,
+			DeletePVC: ctrl.deletePVC,
+			Sync:      ctrl.processPVC,
+		})
+	} else {
+		// Add a resync period as the PVC's request modify can be modified again when we are handling
+		// a previous modify request of the same PVC.
+		pvcInformer.Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
+			AddFunc:    ctrl.addPVC,
+			UpdateFunc: ctrl.updatePVC,
+			DeleteFunc: ctrl.deletePVC,
+		}, resyncPeriod)
 	}
-	// Add a resync period as the PVC's request modify can be modified again when we are handling
-	// a previous modify request of the same PVC.
-	pvcInformer.Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
-		AddFunc:    ctrl.addPVC,
-		UpdateFunc: ctrl.updatePVC,
-		DeleteFunc: ctrl.deletePVC,
-	}, resyncPeriod)
 
-	// Add a resync period as the VAC can be created after a PVC is created
-	// VAC is immutable
-	vacInformer.Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{}, resyncPeriod)
+	if err := pvcInformer.Informer().AddIndexers(cache.Indexers{pendingVACIndex: pendingVACIndexFunc}); err != nil {
+		klog.ErrorS(err, "Failed to index PVCs by pending VolumeAttributesClass")
+	}
+
+	if metadataParameters.WatchesPV() {
+		pvInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			UpdateFunc: ctrl.updatePV,
+		})
+	}
+
+	// PVCs waiting for a VAC are enqueued when it is created, a resync period
+	// covers the rest. VAC is immutable
+	vacInformer.Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
+		AddFunc:    ctrl.addVAC,
+		UpdateFunc: ctrl.updateVAC,
+	}, resyncPeriod)
+	if vacSchema != nil {
+		vacSchema.AddHandler(ctrl.schemaChanged)
+	}
 
 	return ctrl
 }
 
+func (ctrl *modifyController) addVAC(obj interface{}) {
+	vac, ok := obj.(*storagev1.VolumeAttributesClass)
+	if !ok || vac.DriverName != ctrl.name {
+		return
+	}
+	ctrl.validateVAC(vac)
+	ctrl.enqueuePendingPVCs(vac.Name)
+	// The UID is kept after the VAC is deleted, so a VAC that is created again is noticed whether
+	// the informer reports its deletion or, after a relist, only the new object.
+	if uid, loaded := ctrl.vacUIDs.Swap(vac.Name, vac.UID); loaded && uid != vac.UID && ctrl.drift.OnVACRecreate {
+		// The parameters may have changed, apply them to all volumes of the VAC.
+		ctrl.enqueueForDrift(driftReasonVACRecreated, vac.Name)
+	}
+}
+
+func (ctrl *modifyController) updateVAC(oldObj, newObj interface{}) {
+	oldVAC, ok := oldObj.(*storagev1.VolumeAttributesClass)
+	if !ok || oldVAC == nil {
+		return
+	}
+	// Parameters are immutable, only check the VAC again if it was recreated.
+	if newVAC, ok := newObj.(*storagev1.VolumeAttributesClass); ok && newVAC.UID != oldVAC.UID {
+		ctrl.addVAC(newVAC)
+	}
+}
+
+// schemaChanged checks the VACs of the driver against the changed parameter schema and enqueues
+// the PVCs that wait for them, which may be valid now.
+func (ctrl *modifyController) schemaChanged() {
+	vacs, err := ctrl.vacLister.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Failed to list VolumeAttributesClasses after the parameter schema changed")
+		return
+	}
+	for _, vac := range vacs {
+		if vac.DriverName != ctrl.name {
+			continue
+		}
+		ctrl.validateVAC(vac)
+		ctrl.enqueuePendingPVCs(vac.Name)
+	}
+}
+
+// pendingVACIndexFunc indexes PVCs whose modification is Pending by the name of the requested VAC.
+func pendingVACIndexFunc(obj interface{}) ([]string, error) {
+	pvc, ok := obj.(*v1.PersistentVolumeClaim)
+	if !ok || pvc.Status.ModifyVolumeStatus == nil || pvc.Status.ModifyVolumeStatus.Status != v1.PersistentVolumeClaimModifyVolumePending {
+		return nil, nil
+	}
+	if vacName := ptr.Deref(pvc.Spec.VolumeAttributesClassName, ""); vacName != "" {
+		return []string{vacName}, nil
+	}
+	return nil, nil
+}
+
+// enqueuePendingPVCs enqueues the PVCs whose modification to vacName is Pending,
+// they may have been waiting for the VAC to be created.
+func (ctrl *modifyController) enqueuePendingPVCs(vacName string) {
+	objs, err := ctrl.pvcIndexer.ByIndex(pendingVACIndex, vacName)
+	if err != nil {
+		klog.ErrorS(err, "Failed to list PVCs waiting for VolumeAttributesClass", "VAC", vacName)
+		return
+	}
+	for _, obj := range objs {
+		klog.V(4).InfoS("Enqueueing PVC waiting for VolumeAttributesClass", "PVC", klog.KObj(obj.(*v1.PersistentVolumeClaim)), "VAC", vacName)
+		ctrl.addPVC(obj)
+	}
+}
+
+// validateVAC returns a description of the parameters of vac that do not match the schema of the driver,
+// and records it in a warning event on the VAC. It returns "" if there is no schema or vac is valid.
+func (ctrl *modifyController) validateVAC(vac *storagev1.VolumeAttributesClass) string {
+	if ctrl.vacSchema == nil {
+		return ""
+	}
+	errs := ctrl.vacSchema.Schema().Validate(vac.Parameters)
+	if len(errs) == 0 {
+		return ""
+	}
+	msg := fmt.Sprintf("VolumeAttributesClass %q does not match the parameter schema of driver %s: %s", vac.Name, ctrl.name, strings.Join(errs, "; "))
+	ctrl.eventRecorder.Event(vac, v1.EventTypeWarning, util.InvalidVolumeAttributesClass, msg)
+	return msg
+}
+
 func (ctrl *modifyController) initUncertainPVCs() error {
 	allPVCs, err := ctrl.pvcLister.List(labels.Everything())
 	if err != nil {
@@ -152,6 +358,9 @@ func (ctrl *modifyController) addPVC(obj interface{}) {
 	if err != nil {
 		return
 	}
+	if !ctrl.shards.Owns(objKey) {
+		return
+	}
 	ctrl.claimQueue.Add(objKey)
 }
 
@@ -165,13 +374,20 @@ func (ctrl *modifyController) updatePVC(oldObj, newObj interface{}) {
 	if !ok || newPVC == nil {
 		return
 	}
+	if !ctrl.shards.Owns(newPVC.Namespace + "/" + newPVC.Name) {
+		return
+	}
 
 	// Only trigger modify volume if the following conditions are met
-	// 1. VAC changed or modify finished (check pending modify request while we are modifying)
+	// 1. VAC changed, modify finished (check pending modify request while we are modifying),
+	//    the modification was approved, a temporary VAC change was requested
+	//    or labels or annotations mapped to modify parameters changed
 	// 2. PVC is in Bound state
 	oldVacName := ptr.Deref(oldPVC.Spec.VolumeAttributesClassName, "")
 	newVacName := ptr.Deref(newPVC.Spec.VolumeAttributesClassName, "")
-	if (newVacName != oldVacName || newPVC.Status.ModifyVolumeStatus == nil) && newPVC.Status.Phase == v1.ClaimBound {
+	approvalChanged := oldPVC.Annotations[policy.AnnApprovedVAC] != newPVC.Annotations[policy.AnnApprovedVAC]
+	metadataChanged := ctrl.metadataParameters.Changed(oldPVC, newPVC)
+	if (newVacName != oldVacName || newPVC.Status.ModifyVolumeStatus == nil || approvalChanged || metadataChanged || burstChanged(oldPVC, newPVC)) && newPVC.Status.Phase == v1.ClaimBound {
 		_, err := ctrl.pvLister.Get(oldPVC.Spec.VolumeName)
 		if err != nil {
 			klog.Errorf("Get PV %q of pvc %q in PVInformer cache failed: %v", oldPVC.Spec.VolumeName, klog.KObj(oldPVC), err)
@@ -185,17 +401,47 @@ func (ctrl *modifyController) updatePVC(oldObj, newObj interface{}) {
 	}
 }
 
+// updatePV enqueues the PVC of a PV when labels or annotations of the PV that are mapped to
+// modify parameters changed.
+func (ctrl *modifyController) updatePV(oldObj, newObj interface{}) {
+	oldPV, ok := oldObj.(*v1.PersistentVolume)
+	if !ok || oldPV == nil {
+		return
+	}
+	newPV, ok := newObj.(*v1.PersistentVolume)
+	if !ok || newPV == nil || newPV.Spec.ClaimRef == nil {
+		return
+	}
+	if !ctrl.metadataParameters.PVChanged(oldPV, newPV) {
+		return
+	}
+	key := newPV.Spec.ClaimRef.Namespace + "/" + newPV.Spec.ClaimRef.Name
+	if !ctrl.shards.Owns(key) {
+		return
+	}
+	klog.V(4).InfoS("Enqueueing PVC for modify after labels or annotations of its PV changed", "PV", klog.KObj(newPV), "PVC", key)
+	ctrl.claimQueue.Add(key)
+}
+
 func (ctrl *modifyController) deletePVC(obj interface{}) {
 	objKey, err := util.GetObjectKey(obj)
 	if err != nil {
 		return
 	}
 	ctrl.claimQueue.Forget(objKey)
+	ctrl.driftPending.Delete(objKey)
+	ctrl.coordinator.Forget(objKey)
+	ctrl.history.Forget(objKey)
+	ctrl.invalidBursts.Delete(objKey)
 }
 
 func (ctrl *modifyController) init(ctx context.Context) bool {
-	if !cache.WaitForCacheSync(ctx.Done(), ctrl.pvListerSynced, ctrl.pvcListerSynced, ctrl.vacListerSynced) {
-		klog.ErrorS(nil, "Cannot sync pod, pv, pvc or vac caches")
+	synced := []cache.InformerSynced{ctrl.pvListerSynced, ctrl.pvcListerSynced, ctrl.vacListerSynced, ctrl.secretTemplates.HasSynced}
+	if ctrl.vacSchema != nil {
+		synced = append(synced, ctrl.vacSchema.HasSynced)
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		klog.ErrorS(nil, "Cannot sync pod, pv, pvc, vac or parameter schema caches")
 		return false
 	}
 
@@ -223,6 +469,13 @@ func (ctrl *modifyController) Run(
 
 	// Starts go-routine that deletes expired slowSet entries.
 	go ctrl.slowSet.Run(stopCh)
+	go ctrl.runDriftReconciliation(ctx)
+
+	if ctrl.dispatcher != nil {
+		ctrl.dispatcher.Ready(coordinator.Modify)
+		<-stopCh
+		return
+	}
 
 	if utilfeature.DefaultFeatureGate.Enabled(features.ReleaseLeaderElectionOnExit) {
 		for range workers {
@@ -249,13 +502,34 @@ func (ctrl *modifyController) sync() {
 	}
 	defer ctrl.claimQueue.Done(key)
 
-	if err := ctrl.syncPVC(key); err != nil {
-		// Put PVC back to the queue so that we can retry later.
-		klog.ErrorS(err, "Error syncing PVC")
-		ctrl.claimQueue.AddRateLimited(key)
+	ctrl.processPVC(key)
+}
+
+// processPVC syncs a PVC and enqueues it again if needed. It returns the error of the sync.
+func (ctrl *modifyController) processPVC(key string) error {
+	done, owned := ctrl.shards.Begin(key)
+	if !owned {
+		// Another replica handles this PVC now.
+		ctrl.claimQueue.Forget(key)
+		return nil
+	}
+	defer done()
+
+	err := ctrl.syncPVC(key)
+	if err != nil {
+		if util.IsDelayRetryError(err) {
+			// If the error is a DelayRetryError, we should requeue the PVC with a delay.
+			delayRetryError := err.(*util.DelayRetryError)
+			ctrl.claimQueue.AddAfter(key, delayRetryError.TryAfter())
+		} else {
+			// Put PVC back to the queue so that we can retry later.
+			klog.ErrorS(err, "Error syncing PVC")
+			ctrl.claimQueue.AddRateLimited(key)
+		}
 	} else {
 		ctrl.claimQueue.Forget(key)
 	}
+	return err
 }
 
 // syncPVC checks if a pvc requests modification, and execute the ModifyVolume operation if requested.
@@ -295,13 +569,81 @@ func (ctrl *modifyController) syncPVC(key string) error {
 	}
 
 	if pvc.Status.Phase == v1.ClaimBound {
-		_, _, err, _ := ctrl.modify(pvc, pv)
+		pvc, burstIn, err := ctrl.syncBurst(pvc)
 		if err != nil {
 			return err
 		}
+		// Modifying a volume again with its current class also must not overlap with a resize.
+		if coordinator.Requested(pvc, coordinator.Modify) || ctrl.remodifyDue(pvc, pv, key) {
+			done, wait, changed := ctrl.coordinator.Begin(pvc, coordinator.Modify)
+			if wait != "" {
+				if changed {
+					ctrl.eventRecorder.Event(pvc, v1.EventTypeNormal, util.VolumeOperationWaiting, wait)
+				}
+				return util.NewDelayRetryError(wait, coordinator.RetryInterval)
+			}
+			defer done()
+		}
+		_, _, err, _ = ctrl.modify(pvc, pv)
+		if err != nil {
+			return err
+		}
+		if burstIn > 0 {
+			return util.NewDelayRetryError(fmt.Sprintf("temporary VolumeAttributesClass change of PVC %s is due in %s", klog.KObj(pvc), burstIn), burstIn)
+		}
 	} else {
 		klog.V(4).InfoS("No need to modify PV", "PV", klog.KObj(pv))
 	}
 
 	return nil
 }
+
+// ShardAcquired rebuilds the uncertain state of PVCs of the shard from their status,
+// like on startup, and enqueues them.
+func (ctrl *modifyController) ShardAcquired(shard int) {
+	ctrl.forgetShard(shard)
+	pvcs, err := ctrl.pvcLister.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Failed to list PVCs of acquired shard", "shard", shard)
+		return
+	}
+	for _, pvc := range pvcs {
+		key := pvc.Namespace + "/" + pvc.Name
+		if ctrl.shards.Shard(key) != shard {
+			continue
+		}
+		if pvc.Status.ModifyVolumeStatus != nil && pvc.Status.ModifyVolumeStatus.Status == v1.PersistentVolumeClaimModifyVolumeInProgress {
+			ctrl.uncertainPVCs.Store(key, pvc)
+		}
+		ctrl.claimQueue.Add(key)
+	}
+}
+
+// ShardReleased drops state about PVCs of the shard, another replica takes over from their status.
+func (ctrl *modifyController) ShardReleased(shard int) {
+	ctrl.forgetShard(shard)
+}
+
+func (ctrl *modifyController) forgetShard(shard int) {
+	ctrl.uncertainPVCs.Range(func(k, _ any) bool {
+		if key := k.(string); ctrl.shards.Shard(key) == shard {
+			ctrl.uncertainPVCs.Delete(key)
+		}
+		return true
+	})
+	ctrl.driftPending.Range(func(k, _ any) bool {
+		if key := k.(string); ctrl.shards.Shard(key) == shard {
+			ctrl.driftPending.Delete(key)
+		}
+		return true
+	})
+	pvcs, err := ctrl.pvcLister.List(labels.Everything())
+	if err != nil {
+		return
+	}
+	for _, pvc := range pvcs {
+		if key := pvc.Namespace + "/" + pvc.Name; ctrl.shards.Shard(key) == shard {
+			ctrl.slowSet.Remove(key)
+		}
+	}
+}
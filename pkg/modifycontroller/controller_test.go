@@ -8,18 +8,23 @@ import (
 	"testing"
 	"time"
 
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/coordinator"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/csi"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/dispatcher"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/features"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/modifier"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	featuregatetesting "k8s.io/component-base/featuregate/testing"
 )
@@ -405,6 +410,111 @@ func TestConcurrentSync(t *testing.T) {
 	}
 }
 
+func TestEnqueuePendingPVCsOnVACCreation(t *testing.T) {
+	pendingPVC := createTestPVC(pvcName, targetVac /*vacName*/, testVac /*curVacName*/, targetVac /*targetVacName*/)
+	pendingPVC.Status.ModifyVolumeStatus.Status = v1.PersistentVolumeClaimModifyVolumePending
+	otherPVC := createTestPVC("other", testVac /*vacName*/, testVac /*curVacName*/, "" /*targetVacName*/)
+	basePV := createTestPV(1, pvcName, pvcNamespace, "foobaz" /*pvcUID*/, &fsVolumeMode, testVac)
+
+	client := csi.NewMockClient(testDriverName, true, true, true, true, true)
+	initialObjects := []runtime.Object{testVacObject, pendingPVC, otherPVC, basePV}
+	ctrlInstance := setupFakeK8sCaches(t, client, initialObjects)
+
+	ctrlInstance.addVAC(testVacObject)
+	if ctrlInstance.claimQueue.Len() != 0 {
+		t.Fatalf("expected no PVC to be enqueued for %s, got %d", testVac, ctrlInstance.claimQueue.Len())
+	}
+
+	ctrlInstance.addVAC(targetVacObject)
+	if ctrlInstance.claimQueue.Len() != 1 {
+		t.Fatalf("expected pending PVC to be enqueued, got %d", ctrlInstance.claimQueue.Len())
+	}
+	key, _ := ctrlInstance.claimQueue.Get()
+	if key != pvcNamespace+"/"+pvcName {
+		t.Errorf("expected %s/%s to be enqueued, got %s", pvcNamespace, pvcName, key)
+	}
+}
+
+func TestEnqueuePendingPVCsOnSchemaChange(t *testing.T) {
+	pendingPVC := createTestPVC(pvcName, targetVac /*vacName*/, testVac /*curVacName*/, targetVac /*targetVacName*/)
+	pendingPVC.Status.ModifyVolumeStatus.Status = v1.PersistentVolumeClaimModifyVolumePending
+	basePV := createTestPV(1, pvcName, pvcNamespace, "foobaz" /*pvcUID*/, &fsVolumeMode, testVac)
+
+	client := csi.NewMockClient(testDriverName, true, true, true, true, true)
+	initialObjects := []runtime.Object{testVacObject, targetVacObject, pendingPVC, basePV}
+	ctrlInstance := setupFakeK8sCaches(t, client, initialObjects)
+
+	ctrlInstance.schemaChanged()
+	if ctrlInstance.claimQueue.Len() != 1 {
+		t.Fatalf("expected pending PVC to be enqueued, got %d", ctrlInstance.claimQueue.Len())
+	}
+}
+
+func TestSyncPVCWaitsForResize(t *testing.T) {
+	basePVC := createTestPVC(pvcName, targetVac /*vacName*/, testVac /*curVacName*/, "" /*targetVacName*/)
+	basePVC.Spec.Resources.Requests[v1.ResourceStorage] = resource.MustParse("4Gi")
+	basePV := createTestPV(1, pvcName, pvcNamespace, "foobaz" /*pvcUID*/, &fsVolumeMode, testVac)
+
+	client := csi.NewMockClient(testDriverName, true, true, true, true, true)
+	initialObjects := []runtime.Object{testVacObject, targetVacObject, basePVC, basePV}
+	ctrlInstance := setupFakeK8sEnvironment(t, client, initialObjects)
+	ctrlInstance.coordinator = coordinator.New(coordinator.OrderResizeFirst, nil)
+	recorder := record.NewFakeRecorder(10)
+	ctrlInstance.eventRecorder = recorder
+
+	for range 2 {
+		err := ctrlInstance.syncPVC(pvcNamespace + "/" + pvcName)
+		if !util.IsDelayRetryError(err) {
+			t.Errorf("expected delayed retry while the expansion is pending, got %v", err)
+		}
+	}
+	if client.GetModifyCount() != 0 {
+		t.Errorf("expected no modify call before the expansion")
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected one %s event for the wait, got %d", util.VolumeOperationWaiting, len(recorder.Events))
+	}
+
+	ctrlInstance.coordinator = coordinator.New(coordinator.OrderModifyFirst, nil)
+	if err := ctrlInstance.syncPVC(pvcNamespace + "/" + pvcName); err != nil {
+		t.Errorf("expected modify to go first, got %v", err)
+	}
+	if client.GetModifyCount() != 1 {
+		t.Errorf("expected modify call, got %d", client.GetModifyCount())
+	}
+}
+
+func TestRunWithDispatcher(t *testing.T) {
+	featuregatetesting.SetFeatureGateDuringTest(t, utilfeature.DefaultFeatureGate, features.VolumeAttributesClass, true)
+
+	basePVC := createTestPVC(pvcName, targetVac /*vacName*/, testVac /*curVacName*/, "" /*targetVacName*/)
+	basePV := createTestPV(1, pvcName, pvcNamespace, "foobaz" /*pvcUID*/, &fsVolumeMode, testVac)
+	client := csi.NewMockClient(testDriverName, true, true, true, true, true)
+	kubeClient, informerFactory := fakeK8s([]runtime.Object{testVacObject, targetVacObject, basePVC, basePV})
+
+	ctx := t.Context()
+	csiModifier, err := modifier.NewModifierFromClient(client, 15*time.Second, kubeClient, informerFactory, false, testDriverName, nil)
+	if err != nil {
+		t.Fatalf("Unable to create modifier: %v", err)
+	}
+	pvcDispatcher := dispatcher.New(testDriverName, informerFactory.Core().V1().PersistentVolumeClaims(), 0, nil)
+	controller := NewModifyController(testDriverName,
+		csiModifier, kubeClient,
+		0 /* resyncPeriod */, 2*time.Minute, false, informerFactory,
+		workqueue.DefaultTypedControllerRateLimiter[string](), Options{Dispatcher: pvcDispatcher})
+	informerFactory.Start(ctx.Done())
+
+	go controller.Run(1, ctx, nil)
+	go pvcDispatcher.Run(1, ctx, nil)
+
+	err = wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		return client.GetModifyCount() == 1, nil
+	})
+	if err != nil {
+		t.Fatalf("expected the dispatcher to modify the volume: %v", err)
+	}
+}
+
 func waitForErrorOnPVCStatus(t *testing.T, ctrlInstance *modifyController, pvcName string, expectdTargetVac string) {
 	ctx := t.Context()
 	err := wait.PollUntilContextTimeout(ctx, 100*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
@@ -424,6 +534,44 @@ func waitForErrorOnPVCStatus(t *testing.T, ctrlInstance *modifyController, pvcNa
 	}
 }
 
+// setupFakeK8sCaches creates a ModifyController like setupFakeK8sEnvironment, but fills the informer
+// caches with initialObjects instead of starting the informers. Tests can change the controller
+// and call its event handlers without racing with the handlers called by the informers.
+func setupFakeK8sCaches(t *testing.T, client *csi.MockClient, initialObjects []runtime.Object) *modifyController {
+	t.Helper()
+
+	featuregatetesting.SetFeatureGateDuringTest(t, utilfeature.DefaultFeatureGate, features.VolumeAttributesClass, true)
+	kubeClient, informerFactory := fakeK8s(initialObjects)
+	driverName, _ := client.GetDriverName(t.Context())
+
+	csiModifier, err := modifier.NewModifierFromClient(client, 15*time.Second, kubeClient, informerFactory, false, driverName, nil)
+	if err != nil {
+		t.Fatalf("Test %s: Unable to create modifier: %v", t.Name(), err)
+	}
+	controller := NewModifyController(driverName,
+		csiModifier, kubeClient,
+		0 /* resyncPeriod */, 2*time.Minute, false, informerFactory,
+		workqueue.DefaultTypedControllerRateLimiter[string](), Options{})
+
+	for _, obj := range initialObjects {
+		var informer cache.SharedIndexInformer
+		switch obj.(type) {
+		case *v1.PersistentVolumeClaim:
+			informer = informerFactory.Core().V1().PersistentVolumeClaims().Informer()
+		case *v1.PersistentVolume:
+			informer = informerFactory.Core().V1().PersistentVolumes().Informer()
+		case *storagev1.VolumeAttributesClass:
+			informer = informerFactory.Storage().V1().VolumeAttributesClasses().Informer()
+		default:
+			t.Fatalf("Test %s: Unexpected object %T", t.Name(), obj)
+		}
+		if err := informer.GetStore().Add(obj); err != nil {
+			t.Fatalf("Test %s: Unable to add %T to the informer cache: %v", t.Name(), obj, err)
+		}
+	}
+	return controller.(*modifyController)
+}
+
 // setupFakeK8sEnvironment creates fake K8s environment and starts Informers and ModifyController
 func setupFakeK8sEnvironment(t *testing.T, client *csi.MockClient, initialObjects []runtime.Object) *modifyController {
 	t.Helper()
@@ -436,7 +584,7 @@ func setupFakeK8sEnvironment(t *testing.T, client *csi.MockClient, initialObject
 	ctx := t.Context()
 	driverName, _ := client.GetDriverName(ctx)
 
-	csiModifier, err := modifier.NewModifierFromClient(client, 15*time.Second, kubeClient, informerFactory, false, driverName)
+	csiModifier, err := modifier.NewModifierFromClient(client, 15*time.Second, kubeClient, informerFactory, false, driverName, nil)
 	if err != nil {
 		t.Fatalf("Test %s: Unable to create modifier: %v", t.Name(), err)
 	}
@@ -444,7 +592,7 @@ func setupFakeK8sEnvironment(t *testing.T, client *csi.MockClient, initialObject
 	controller := NewModifyController(driverName,
 		csiModifier, kubeClient,
 		0 /* resyncPeriod */, 2*time.Minute, false, informerFactory,
-		workqueue.DefaultTypedControllerRateLimiter[string]())
+		workqueue.DefaultTypedControllerRateLimiter[string](), Options{})
 
 	/* Start informers and ModifyController*/
 	informerFactory.Start(ctx.Done())
@@ -0,0 +1,148 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package modifycontroller
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+)
+
+// DriftReconciliation configures how volumes are modified again with their current VolumeAttributesClass,
+// to undo changes of the backend that were made out-of-band. ControllerModifyVolume is idempotent, so this
+// is harmless for volumes that did not drift. The zero value disables it.
+type DriftReconciliation struct {
+	// Interval is how often all volumes are modified again, zero disables it.
+	Interval time.Duration
+	// OnVACRecreate modifies the volumes of a VolumeAttributesClass again when it was deleted and created again.
+	OnVACRecreate bool
+}
+
+// Reasons of drift reconciliation.
+const (
+	driftReasonPeriodic     = "periodic"
+	driftReasonVACRecreated = "vac_recreated"
+)
+
+var driftReconciliations = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Subsystem:      "csi_resizer",
+		Name:           "modify_drift_reconciliations_total",
+		Help:           "Number of volumes modified again with their current VolumeAttributesClass, by reason and result.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"reason", "result"},
+)
+
+func init() {
+	legacyregistry.MustRegister(driftReconciliations)
+}
+
+// runDriftReconciliation enqueues all PVCs for drift reconciliation every Interval until ctx is done.
+func (ctrl *modifyController) runDriftReconciliation(ctx context.Context) {
+	if ctrl.drift.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(ctrl.drift.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ctrl.enqueueForDrift(driftReasonPeriodic, "")
+		}
+	}
+}
+
+// enqueueForDrift enqueues the PVCs of the driver whose modification is complete for drift reconciliation.
+// If vacName is not empty, only the PVCs of this VolumeAttributesClass are enqueued.
+// Periodic reconciliation is spread over the interval, so that the driver is not called for all volumes at once.
+func (ctrl *modifyController) enqueueForDrift(reason, vacName string) {
+	pvcs, err := ctrl.pvcLister.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Failed to list PVCs for drift reconciliation")
+		return
+	}
+	count := 0
+	for _, pvc := range pvcs {
+		curVacName := ptr.Deref(pvc.Status.CurrentVolumeAttributesClassName, "")
+		if curVacName == "" || pvc.Status.ModifyVolumeStatus != nil || ptr.Deref(pvc.Spec.VolumeAttributesClassName, "") != curVacName {
+			continue
+		}
+		if vacName != "" && curVacName != vacName {
+			continue
+		}
+		key := pvc.Namespace + "/" + pvc.Name
+		if !ctrl.shards.Owns(key) {
+			continue
+		}
+		pv, err := ctrl.pvLister.Get(pvc.Spec.VolumeName)
+		if err != nil || pv.Spec.CSI == nil || pv.Spec.CSI.Driver != ctrl.name {
+			continue
+		}
+		ctrl.driftPending.Store(key, reason)
+		if reason == driftReasonPeriodic {
+			ctrl.claimQueue.AddAfter(key, rand.N(ctrl.drift.Interval))
+		} else {
+			ctrl.claimQueue.Add(key)
+		}
+		count++
+	}
+	klog.V(2).InfoS("Enqueued PVCs for drift reconciliation", "reason", reason, "count", count)
+}
+
+// reconcileDrift modifies the volume of a PVC whose modification is complete again with its current
+// VolumeAttributesClass. The status of the PVC is not changed, failures are reported by events and metrics.
+func (ctrl *modifyController) reconcileDrift(
+	ctx context.Context,
+	pvc *v1.PersistentVolumeClaim,
+	pv *v1.PersistentVolume,
+	pvcKey, reason string) (*v1.PersistentVolumeClaim, *v1.PersistentVolume, error, bool) {
+
+	vac, err := ctrl.getTargetVAC(pvc, *pvc.Status.CurrentVolumeAttributesClassName)
+	if err != nil {
+		return pvc, pv, err, false
+	}
+	if err := ctrl.modifyVolume(ctx, pvc, pv, vac); err != nil {
+		driftReconciliations.WithLabelValues(reason, "failure").Inc()
+		ctrl.eventRecorder.Eventf(pvc, v1.EventTypeWarning, util.VolumeModifyDriftFailed,
+			"Failed to apply vac %s to volume %s again: %v", vac.Name, pvc.Name, err)
+		if reason != driftReasonPeriodic {
+			// Periodic reconciliation is retried with the next period.
+			ctrl.driftPending.Store(pvcKey, reason)
+		}
+		return pvc, pv, err, false
+	}
+	driftReconciliations.WithLabelValues(reason, "success").Inc()
+	if reason == driftReasonVACRecreated {
+		ctrl.eventRecorder.Eventf(pvc, v1.EventTypeNormal, util.VolumeModifyDriftReconciled,
+			"external resizer applied vac %s to volume %s again because the vac was recreated", vac.Name, pvc.Name)
+	} else {
+		ctrl.eventRecorder.Eventf(pvc, v1.EventTypeNormal, util.VolumeModifyDriftReconciled,
+			"external resizer applied vac %s to volume %s again", vac.Name, pvc.Name)
+	}
+	return pvc, pv, nil, true
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package modifycontroller
 
 import (
+	"context"
 	"fmt"
 	"slices"
 
@@ -27,8 +28,21 @@ import (
 	"k8s.io/utils/ptr"
 )
 
+// modifyOwner is the part of PVCs and PVs that the modify controller writes with server-side apply.
+var modifyOwner = util.Owner{
+	FieldManager: util.ModifyFieldManager,
+	Modify:       true,
+	Conditions: []v1.PersistentVolumeClaimConditionType{
+		v1.PersistentVolumeClaimVolumeModifyingVolume,
+		v1.PersistentVolumeClaimVolumeModifyVolumeError,
+		util.ModifyVolumePending,
+	},
+	PVAnnotations: []string{util.AnnModifyMetadataParameters},
+}
+
 // markControllerModifyVolumeStatus will mark ModifyVolumeStatus other than completed in the PVC
 func (ctrl *modifyController) markControllerModifyVolumeStatus(
+	ctx context.Context,
 	pvc *v1.PersistentVolumeClaim,
 	modifyVolumeStatus v1.PersistentVolumeClaimModifyVolumeStatus,
 	err error) (*v1.PersistentVolumeClaim, error) {
@@ -70,19 +84,56 @@ func (ctrl *modifyController) markControllerModifyVolumeStatus(
 				LastProbeTime: now,
 			})
 		}
-		newPVC.Status.Conditions = util.MergePVCConditions(newPVC.Status.Conditions, conditions)
+		newPVC.Status.Conditions = util.MergePVCConditions(removeModifyVolumePendingCondition(newPVC.Status.Conditions), conditions)
 	}
 
-	updatedPVC, err := util.PatchClaim(ctrl.kubeClient, pvc, newPVC, true /* addResourceVersionCheck */)
+	updatedPVC, err := util.ApplyClaimStatus(ctrl.kubeClient, modifyOwner, pvc, newPVC, true /* addResourceVersionCheck */)
 	if err != nil {
 		return pvc, fmt.Errorf("mark PVC %q as modify volume failed, errored with: %v", pvc.Name, err)
 	}
+	ctrl.history.Observe(ctx, updatedPVC)
 	return updatedPVC, nil
 }
 
+// markControllerModifyVolumePendingPolicy marks ModifyVolumeStatus as Pending because the modify
+// policy does not allow the change yet, and records the reason in a ModifyVolumePending condition.
+func (ctrl *modifyController) markControllerModifyVolumePendingPolicy(
+	ctx context.Context,
+	pvc *v1.PersistentVolumeClaim,
+	reason, message string) (*v1.PersistentVolumeClaim, error) {
+
+	newPVC := pvc.DeepCopy()
+	newPVC.Status.ModifyVolumeStatus = &v1.ModifyVolumeStatus{
+		TargetVolumeAttributesClassName: ptr.Deref(pvc.Spec.VolumeAttributesClassName, ""),
+		Status:                          v1.PersistentVolumeClaimModifyVolumePending,
+	}
+	newPVC.Status.Conditions = util.MergePVCConditions(newPVC.Status.Conditions, []v1.PersistentVolumeClaimCondition{{
+		Type:          util.ModifyVolumePending,
+		Status:        v1.ConditionTrue,
+		Reason:        reason,
+		Message:       message,
+		LastProbeTime: metav1.Now(),
+	}})
+
+	updatedPVC, err := util.ApplyClaimStatus(ctrl.kubeClient, modifyOwner, pvc, newPVC, true /* addResourceVersionCheck */)
+	if err != nil {
+		return pvc, fmt.Errorf("mark PVC %q as modify volume pending failed, errored with: %v", pvc.Name, err)
+	}
+	ctrl.history.Observe(ctx, updatedPVC)
+	return updatedPVC, nil
+}
+
+// pendingConditionChanged returns true if pvc is not held in Pending state for reason and message yet,
+// to report why a modification is held only once instead of on every retry.
+func pendingConditionChanged(pvc *v1.PersistentVolumeClaim, reason, message string) bool {
+	return !slices.ContainsFunc(pvc.Status.Conditions, func(c v1.PersistentVolumeClaimCondition) bool {
+		return c.Type == util.ModifyVolumePending && c.Reason == reason && c.Message == message
+	})
+}
+
 // markControllerModifyVolumeStatus will mark ModifyVolumeStatus as completed in the PVC
 // and update CurrentVolumeAttributesClassName, clear the conditions
-func (ctrl *modifyController) markControllerModifyVolumeCompleted(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) (*v1.PersistentVolumeClaim, *v1.PersistentVolume, error) {
+func (ctrl *modifyController) markControllerModifyVolumeCompleted(ctx context.Context, pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) (*v1.PersistentVolumeClaim, *v1.PersistentVolume, error) {
 	modifiedVacName := pvc.Status.ModifyVolumeStatus.TargetVolumeAttributesClassName
 
 	// Update PVC
@@ -100,17 +151,22 @@ func (ctrl *modifyController) markControllerModifyVolumeCompleted(pvc *v1.Persis
 	// Update PV
 	newPV := pv.DeepCopy()
 	newPV.Spec.VolumeAttributesClassName = &modifiedVacName
+	if ctrl.metadataParameters != nil {
+		// Record the parameters from labels and annotations that were passed, to notice when they change.
+		metav1.SetMetaDataAnnotation(&newPV.ObjectMeta, util.AnnModifyMetadataParameters, util.HashParameters(ctrl.metadataParameters.Parameters(pvc, pv)))
+	}
 
 	// Update PV before PVC to avoid PV not getting updated but PVC did
-	updatedPV, err := util.PatchPersistentVolume(ctrl.kubeClient, pv, newPV)
+	updatedPV, err := util.ApplyPersistentVolume(ctrl.kubeClient, modifyOwner, pv, newPV)
 	if err != nil {
 		return pvc, pv, fmt.Errorf("update pv.Spec.VolumeAttributesClassName for PVC %q failed, errored with: %v", pvc.Name, err)
 	}
-	updatedPVC, err := util.PatchClaim(ctrl.kubeClient, pvc, newPVC, false /* addResourceVersionCheck */)
+	updatedPVC, err := util.ApplyClaimStatus(ctrl.kubeClient, modifyOwner, pvc, newPVC, false /* addResourceVersionCheck */)
 
 	if err != nil {
 		return pvc, pv, fmt.Errorf("mark PVC %q as ModifyVolumeCompleted failed, errored with: %v", pvc.Name, err)
 	}
+	ctrl.history.Observe(ctx, updatedPVC)
 
 	return updatedPVC, updatedPV, nil
 }
@@ -119,19 +175,29 @@ func (ctrl *modifyController) markControllerModifyVolumeCompleted(pvc *v1.Persis
 // leave other condition types
 func clearModifyVolumeConditions(conditions []v1.PersistentVolumeClaimCondition) []v1.PersistentVolumeClaimCondition {
 	return slices.DeleteFunc(conditions, func(c v1.PersistentVolumeClaimCondition) bool {
-		return c.Type == v1.PersistentVolumeClaimVolumeModifyVolumeError || c.Type == v1.PersistentVolumeClaimVolumeModifyingVolume
+		return c.Type == v1.PersistentVolumeClaimVolumeModifyVolumeError || c.Type == v1.PersistentVolumeClaimVolumeModifyingVolume ||
+			c.Type == util.ModifyVolumePending
+	})
+}
+
+// removeModifyVolumePendingCondition drops the ModifyVolumePending condition once the modification
+// is allowed to proceed
+func removeModifyVolumePendingCondition(conditions []v1.PersistentVolumeClaimCondition) []v1.PersistentVolumeClaimCondition {
+	return slices.DeleteFunc(conditions, func(c v1.PersistentVolumeClaimCondition) bool {
+		return c.Type == util.ModifyVolumePending
 	})
 }
 
 // markRolledBack will clear the modifying conditions
-func (ctrl *modifyController) markRolledBack(pvc *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, error) {
+func (ctrl *modifyController) markRolledBack(ctx context.Context, pvc *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, error) {
 	newPVC := pvc.DeepCopy()
 	newPVC.Status.Conditions = slices.DeleteFunc(newPVC.Status.Conditions, func(condition v1.PersistentVolumeClaimCondition) bool {
-		return condition.Type == v1.PersistentVolumeClaimVolumeModifyingVolume
+		return condition.Type == v1.PersistentVolumeClaimVolumeModifyingVolume || condition.Type == util.ModifyVolumePending
 	})
-	newPVC, err := util.PatchClaim(ctrl.kubeClient, pvc, newPVC, false /* addResourceVersionCheck */)
+	newPVC, err := util.ApplyClaimStatus(ctrl.kubeClient, modifyOwner, pvc, newPVC, false /* addResourceVersionCheck */)
 	if err != nil {
 		return nil, fmt.Errorf("mark PVC %q as rolled back failed: %v", pvc.Name, err)
 	}
+	ctrl.history.Observe(ctx, newPVC)
 	return newPVC, nil
 }
@@ -104,7 +104,7 @@ func TestMarkControllerModifyVolumeStatus(t *testing.T) {
 			expectedConditions: pvcConditionInProgress,
 			expectedErr:        nil,
 			testFunc: func(pvc *v1.PersistentVolumeClaim, ctrl *modifyController) (*v1.PersistentVolumeClaim, error) {
-				return ctrl.markControllerModifyVolumeStatus(pvc, v1.PersistentVolumeClaimModifyVolumeInProgress, nil)
+				return ctrl.markControllerModifyVolumeStatus(context.TODO(), pvc, v1.PersistentVolumeClaimModifyVolumeInProgress, nil)
 			},
 		},
 		{
@@ -114,7 +114,7 @@ func TestMarkControllerModifyVolumeStatus(t *testing.T) {
 			expectedConditions: pvcConditionError,
 			expectedErr:        nil,
 			testFunc: func(pvc *v1.PersistentVolumeClaim, ctrl *modifyController) (*v1.PersistentVolumeClaim, error) {
-				return ctrl.markControllerModifyVolumeStatus(pvc, v1.PersistentVolumeClaimModifyVolumeInProgress, finalErr)
+				return ctrl.markControllerModifyVolumeStatus(context.TODO(), pvc, v1.PersistentVolumeClaimModifyVolumeInProgress, finalErr)
 			},
 		},
 		{
@@ -124,7 +124,7 @@ func TestMarkControllerModifyVolumeStatus(t *testing.T) {
 			expectedConditions: pvcConditionUncertain,
 			expectedErr:        nil,
 			testFunc: func(pvc *v1.PersistentVolumeClaim, ctrl *modifyController) (*v1.PersistentVolumeClaim, error) {
-				return ctrl.markControllerModifyVolumeStatus(pvc, v1.PersistentVolumeClaimModifyVolumeInProgress, nonFinalErr)
+				return ctrl.markControllerModifyVolumeStatus(context.TODO(), pvc, v1.PersistentVolumeClaimModifyVolumeInProgress, nonFinalErr)
 			},
 		},
 		{
@@ -134,7 +134,7 @@ func TestMarkControllerModifyVolumeStatus(t *testing.T) {
 			expectedConditions: pvcConditionInfeasible,
 			expectedErr:        infeasibleErr,
 			testFunc: func(pvc *v1.PersistentVolumeClaim, ctrl *modifyController) (*v1.PersistentVolumeClaim, error) {
-				return ctrl.markControllerModifyVolumeStatus(pvc, v1.PersistentVolumeClaimModifyVolumeInfeasible, infeasibleErr)
+				return ctrl.markControllerModifyVolumeStatus(context.TODO(), pvc, v1.PersistentVolumeClaimModifyVolumeInfeasible, infeasibleErr)
 			},
 		},
 		{
@@ -144,7 +144,7 @@ func TestMarkControllerModifyVolumeStatus(t *testing.T) {
 			expectedConditions: pvcConditionInfeasible, // not touched
 			expectedErr:        nil,
 			testFunc: func(pvc *v1.PersistentVolumeClaim, ctrl *modifyController) (*v1.PersistentVolumeClaim, error) {
-				return ctrl.markControllerModifyVolumeStatus(pvc, v1.PersistentVolumeClaimModifyVolumePending, nil)
+				return ctrl.markControllerModifyVolumeStatus(context.TODO(), pvc, v1.PersistentVolumeClaimModifyVolumePending, nil)
 			},
 		},
 	}
@@ -163,14 +163,14 @@ func TestMarkControllerModifyVolumeStatus(t *testing.T) {
 
 			kubeClient, informerFactory := fakeK8s(initialObjects)
 
-			csiModifier, err := modifier.NewModifierFromClient(client, 15*time.Second, kubeClient, informerFactory, false, driverName)
+			csiModifier, err := modifier.NewModifierFromClient(client, 15*time.Second, kubeClient, informerFactory, false, driverName, nil)
 			if err != nil {
 				t.Fatalf("Test %s: Unable to create modifier: %v", test.name, err)
 			}
 			controller := NewModifyController(driverName,
 				csiModifier, kubeClient,
 				time.Second, 2*time.Minute, false, informerFactory,
-				workqueue.DefaultTypedControllerRateLimiter[string]())
+				workqueue.DefaultTypedControllerRateLimiter[string](), Options{})
 
 			ctrlInstance, _ := controller.(*modifyController)
 
@@ -238,18 +238,18 @@ func TestMarkControllerModifyVolumeCompleted(t *testing.T) {
 
 			kubeClient, informerFactory := fakeK8s(initialObjects)
 
-			csiModifier, err := modifier.NewModifierFromClient(client, 15*time.Second, kubeClient, informerFactory, false, driverName)
+			csiModifier, err := modifier.NewModifierFromClient(client, 15*time.Second, kubeClient, informerFactory, false, driverName, nil)
 			if err != nil {
 				t.Fatalf("Test %s: Unable to create modifier: %v", test.name, err)
 			}
 			controller := NewModifyController(driverName,
 				csiModifier, kubeClient,
 				time.Second, 2*time.Minute, false, informerFactory,
-				workqueue.DefaultTypedControllerRateLimiter[string]())
+				workqueue.DefaultTypedControllerRateLimiter[string](), Options{})
 
 			ctrlInstance, _ := controller.(*modifyController)
 
-			actualPVC, pv, err := ctrlInstance.markControllerModifyVolumeCompleted(tc.pvc, tc.pv)
+			actualPVC, pv, err := ctrlInstance.markControllerModifyVolumeCompleted(context.TODO(), tc.pvc, tc.pv)
 			if err != nil && !reflect.DeepEqual(tc.expectedErr, err) {
 				t.Errorf("Expected error to be %v but got %v", tc.expectedErr, err)
 			}
@@ -258,11 +258,16 @@ func TestMarkControllerModifyVolumeCompleted(t *testing.T) {
 				actualPVC.Status.Conditions = []v1.PersistentVolumeClaimCondition{}
 			}
 
-			if diff := cmp.Diff(tc.expectedPVC, actualPVC); diff != "" {
+			// Objects written with server-side apply come back with their type and formatted quantities.
+			actualPVC.TypeMeta = metav1.TypeMeta{}
+			pv.TypeMeta = metav1.TypeMeta{}
+			quantities := cmp.Comparer(func(a, b resource.Quantity) bool { return a.Cmp(b) == 0 })
+
+			if diff := cmp.Diff(tc.expectedPVC, actualPVC, quantities); diff != "" {
 				t.Errorf("expected pvc %+v got %+v, diff is: %v", tc.expectedPVC, actualPVC, diff)
 			}
 
-			if diff := cmp.Diff(tc.expectedPV, pv); diff != "" {
+			if diff := cmp.Diff(tc.expectedPV, pv, quantities); diff != "" {
 				t.Errorf("expected pvc %+v got %+v, diff is: %v", tc.expectedPV, pv, diff)
 			}
 		})
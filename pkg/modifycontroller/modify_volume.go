@@ -24,25 +24,31 @@ import (
 	"time"
 
 	"github.com/kubernetes-csi/csi-lib-utils/slowset"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/audit"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/credentials"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/modifier"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/policy"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/pricing"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/vacschema"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	v1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
 )
 
-const (
-	pvcNameKey      = "csi.storage.k8s.io/pvc/name"
-	pvcNamespaceKey = "csi.storage.k8s.io/pvc/namespace"
-	pvNameKey       = "csi.storage.k8s.io/pv/name"
-)
+// invalidVACRetryInterval is how often a PVC whose VolumeAttributesClass does not match the
+// parameter schema of the driver is checked again, in case the schema was fixed.
+const invalidVACRetryInterval = time.Minute
 
 // The return value bool is only used as a sentinel value when function returns without actually performing modification
 func (ctrl *modifyController) modify(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) (*v1.PersistentVolumeClaim, *v1.PersistentVolume, error, bool) {
+	ctx := context.TODO()
 	pvcKey, err := cache.MetaNamespaceKeyFunc(pvc)
 	if err != nil {
 		return pvc, pv, err, false
@@ -59,6 +65,21 @@ func (ctrl *modifyController) modify(pvc *v1.PersistentVolumeClaim, pv *v1.Persi
 	status := pvc.Status.ModifyVolumeStatus
 
 	if status == nil && pvcSpecVacName == curVacName {
+		if _, recorded := pv.Annotations[util.AnnModifyMetadataParameters]; pvcSpecVacName != "" && ctrl.metadataParameters != nil && !recorded {
+			// Don't modify all volumes at once when the parameters are enabled, assume the
+			// volume has the current ones.
+			updatedPV, err := ctrl.recordMetadataParameters(pvc, pv)
+			if err != nil {
+				return pvc, pv, err, false
+			}
+			pv = updatedPV
+		} else if pvcSpecVacName != "" && ctrl.metadataParametersChanged(pvc, pv) {
+			ctrl.driftPending.Delete(pvcKey)
+			return ctrl.remodifyVolume(ctx, pvc, pv)
+		}
+		if reason, ok := ctrl.driftPending.LoadAndDelete(pvcKey); ok && pvcSpecVacName != "" {
+			return ctrl.reconcileDrift(ctx, pvc, pv, pvcKey, reason.(string))
+		}
 		// No modification required, already reached target state
 		return pvc, pv, nil, false
 	}
@@ -70,11 +91,10 @@ func (ctrl *modifyController) modify(pvc *v1.PersistentVolumeClaim, pv *v1.Persi
 		// User don't care the target state, and we've reached a relatively stable state. Just keep it here.
 		// Note: APIServer generally not allowing setting pvcSpecVacName to empty when curVacName is not empty.
 		klog.V(4).InfoS("stop reconcile for rolled back PVC", "PV", klog.KObj(pv))
-		pvc, err := ctrl.rolledBack(pvc)
+		pvc, err := ctrl.rolledBack(ctx, pvc)
 		return pvc, pv, err, false
 	}
 
-	ctx := context.TODO()
 	inUncertainState := false
 	if inProgress {
 		_, inUncertainState = ctrl.uncertainPVCs.Load(pvcKey)
@@ -97,12 +117,12 @@ func (ctrl *modifyController) modify(pvc *v1.PersistentVolumeClaim, pv *v1.Persi
 	return ctrl.validateVACAndModifyVolumeWithTarget(ctx, pvc, pv)
 }
 
-func (ctrl *modifyController) rolledBack(pvc *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, error) {
+func (ctrl *modifyController) rolledBack(ctx context.Context, pvc *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, error) {
 	if slices.ContainsFunc(pvc.Status.Conditions, func(condition v1.PersistentVolumeClaimCondition) bool {
 		return condition.Type == v1.PersistentVolumeClaimVolumeModifyingVolume
 	}) {
 		ctrl.eventRecorder.Eventf(pvc, v1.EventTypeNormal, util.VolumeModifyCancelled, "Cancelled modify.")
-		return ctrl.markRolledBack(pvc)
+		return ctrl.markRolledBack(ctx, pvc)
 	}
 	// Don't try to revert Status.ModifyVolumeStatus here, because we only record the result of the last modification.
 	// We don't know what happened before. User can switch between InProgress/Infeasible/Pending status
@@ -123,7 +143,8 @@ func (ctrl *modifyController) getTargetVAC(pvc *v1.PersistentVolumeClaim, vacNam
 }
 
 // func validateVACAndModifyVolumeWithTarget validate the VAC. The function sets pvc.Status.ModifyVolumeStatus
-// to Pending if VAC does not exist and proceeds to trigger ModifyVolume if VAC exists
+// to Pending if VAC does not exist or the change is not allowed by the modify policy yet,
+// and proceeds to trigger ModifyVolume otherwise
 func (ctrl *modifyController) validateVACAndModifyVolumeWithTarget(
 	ctx context.Context,
 	pvc *v1.PersistentVolumeClaim,
@@ -132,21 +153,106 @@ func (ctrl *modifyController) validateVACAndModifyVolumeWithTarget(
 	vac, err := ctrl.getTargetVAC(pvc, *pvc.Spec.VolumeAttributesClassName)
 	if err != nil {
 		// Mark pvc.Status.ModifyVolumeStatus as pending
-		pvc, err = ctrl.markControllerModifyVolumeStatus(pvc, v1.PersistentVolumeClaimModifyVolumePending, nil)
+		pvc, err = ctrl.markControllerModifyVolumeStatus(ctx, pvc, v1.PersistentVolumeClaimModifyVolumePending, nil)
+		return pvc, pv, err, false
+	}
+
+	// Don't call the driver with a VAC that it is going to reject, wait until the
+	// schema is fixed or the PVC uses another VAC.
+	if msg := ctrl.validateVAC(vac); msg != "" {
+		if pendingConditionChanged(pvc, vacschema.ReasonInvalidParameters, msg) {
+			ctrl.eventRecorder.Event(pvc, v1.EventTypeWarning, util.InvalidVolumeAttributesClass, msg)
+		}
+		pvc, err = ctrl.markControllerModifyVolumePendingPolicy(ctx, pvc, vacschema.ReasonInvalidParameters, msg)
+		if err != nil {
+			return pvc, pv, err, false
+		}
+		return pvc, pv, util.NewDelayRetryError(msg, invalidVACRetryInterval), false
+	}
+
+	// Hold the modification until the policy allows it. The PVC is enqueued again
+	// when the approval annotation changes.
+	if reason, msg := ctrl.policy.CheckModify(pvc); reason != "" {
+		if pendingConditionChanged(pvc, reason, msg) {
+			ctrl.eventRecorder.Event(pvc, v1.EventTypeWarning, util.VolumeModifyPending, msg)
+		}
+		pvc, err = ctrl.markControllerModifyVolumePendingPolicy(ctx, pvc, reason, msg)
 		return pvc, pv, err, false
 	}
 
+	pvc, costNote := ctrl.annotateCostEstimate(pvc, vac.Name)
+
 	// Mark pvc.Status.ModifyVolumeStatus as in progress
-	pvc, err = ctrl.markControllerModifyVolumeStatus(pvc, v1.PersistentVolumeClaimModifyVolumeInProgress, nil)
+	pvc, err = ctrl.markControllerModifyVolumeStatus(ctx, pvc, v1.PersistentVolumeClaimModifyVolumeInProgress, nil)
 	if err != nil {
 		return pvc, pv, err, false
 	}
 	// Record an event to indicate that external resizer is modifying this volume.
 	ctrl.eventRecorder.Event(pvc, v1.EventTypeNormal, util.VolumeModify,
-		fmt.Sprintf("external resizer is modifying volume %s with vac %s", pvc.Name, vac.Name))
+		fmt.Sprintf("external resizer is modifying volume %s with vac %s%s", pvc.Name, vac.Name, costNote))
 	return ctrl.controllerModifyVolumeWithTarget(ctx, pvc, pv, vac)
 }
 
+// func metadataParametersChanged returns true if the parameters from labels and annotations of pvc and pv
+// differ from the ones passed to the last successful modification of pv.
+func (ctrl *modifyController) metadataParametersChanged(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) bool {
+	if ctrl.metadataParameters == nil {
+		return false
+	}
+	return util.HashParameters(ctrl.metadataParameters.Parameters(pvc, pv)) != pv.Annotations[util.AnnModifyMetadataParameters]
+}
+
+// func remodifyDue returns true if the volume of a PVC whose modification is complete is going to be
+// modified again with its current VolumeAttributesClass, for drift reconciliation or because the
+// parameters from labels and annotations changed.
+func (ctrl *modifyController) remodifyDue(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume, pvcKey string) bool {
+	vacName := ptr.Deref(pvc.Spec.VolumeAttributesClassName, "")
+	if vacName == "" || pvc.Status.ModifyVolumeStatus != nil || vacName != ptr.Deref(pvc.Status.CurrentVolumeAttributesClassName, "") {
+		return false
+	}
+	_, drift := ctrl.driftPending.Load(pvcKey)
+	return drift || ctrl.metadataParametersChanged(pvc, pv)
+}
+
+// func recordMetadataParameters records the hash of the parameters from labels and annotations of pvc and pv
+// in the annotation of pv.
+func (ctrl *modifyController) recordMetadataParameters(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) (*v1.PersistentVolume, error) {
+	newPV := pv.DeepCopy()
+	metav1.SetMetaDataAnnotation(&newPV.ObjectMeta, util.AnnModifyMetadataParameters, util.HashParameters(ctrl.metadataParameters.Parameters(pvc, pv)))
+	updatedPV, err := util.ApplyPersistentVolume(ctrl.kubeClient, modifyOwner, pv, newPV)
+	if err != nil {
+		return nil, fmt.Errorf("update annotation %s of PV %q failed: %v", util.AnnModifyMetadataParameters, pv.Name, err)
+	}
+	return updatedPV, nil
+}
+
+// func remodifyVolume modifies the volume again with its current VolumeAttributesClass, because the
+// parameters from labels and annotations changed. The modify policy is not checked, the class does not change.
+// Like drift reconciliation, it does not change the status of the PVC, failures are reported by events
+// and retried.
+func (ctrl *modifyController) remodifyVolume(
+	ctx context.Context,
+	pvc *v1.PersistentVolumeClaim,
+	pv *v1.PersistentVolume) (*v1.PersistentVolumeClaim, *v1.PersistentVolume, error, bool) {
+
+	vac, err := ctrl.getTargetVAC(pvc, *pvc.Spec.VolumeAttributesClassName)
+	if err != nil {
+		return pvc, pv, err, false
+	}
+	if err := ctrl.modifyVolume(ctx, pvc, pv, vac); err != nil {
+		ctrl.eventRecorder.Eventf(pvc, v1.EventTypeWarning, util.VolumeModifyFailed,
+			"Failed to modify volume %s with vac %s after its labels or annotations changed: %v", pvc.Name, vac.Name, err)
+		return pvc, pv, err, false
+	}
+	updatedPV, err := ctrl.recordMetadataParameters(pvc, pv)
+	if err != nil {
+		return pvc, pv, err, true
+	}
+	ctrl.eventRecorder.Eventf(pvc, v1.EventTypeNormal, util.VolumeModifySuccess,
+		"external resizer modified volume %s with vac %s successfully because its labels or annotations changed", pvc.Name, vac.Name)
+	return pvc, updatedPV, nil, true
+}
+
 // func controllerModifyVolumeWithTarget trigger the CSI ControllerModifyVolume API call
 // and handle both success and error scenarios
 func (ctrl *modifyController) controllerModifyVolumeWithTarget(
@@ -184,7 +290,7 @@ func (ctrl *modifyController) controllerModifyVolumeWithTarget(
 				ctrl.uncertainPVCs.Delete(pvcKey)
 			}
 			var markErr error
-			pvc, markErr = ctrl.markControllerModifyVolumeStatus(pvc, targetStatus, err)
+			pvc, markErr = ctrl.markControllerModifyVolumeStatus(ctx, pvc, targetStatus, err)
 			if markErr != nil {
 				return pvc, pv, markErr, false
 			}
@@ -203,28 +309,106 @@ func (ctrl *modifyController) callModifyVolumeOnPlugin(
 	pvc *v1.PersistentVolumeClaim,
 	pv *v1.PersistentVolume,
 	vac *storagev1.VolumeAttributesClass) (*v1.PersistentVolumeClaim, *v1.PersistentVolume, error) {
-	parameters := vac.Parameters
+	if err := ctrl.modifyVolume(ctx, pvc, pv, vac); err != nil {
+		return pvc, pv, err
+	}
+
+	pvc, pv, err := ctrl.markControllerModifyVolumeCompleted(ctx, pvc, pv)
+	if err != nil {
+		return pvc, pv, fmt.Errorf("modify volume failed to mark pvc %s modify volume completed: %v ", pvc.Name, err)
+	}
+	return ctrl.clearApproval(pvc), pv, nil
+}
+
+// func clearApproval removes the approval annotation of the modify policy once the approved
+// change is done, so that it does not approve a later change back to the same class.
+// Failures don't fail the modification.
+func (ctrl *modifyController) clearApproval(pvc *v1.PersistentVolumeClaim) *v1.PersistentVolumeClaim {
+	approved, ok := pvc.Annotations[policy.AnnApprovedVAC]
+	if !ok || approved != ptr.Deref(pvc.Status.CurrentVolumeAttributesClassName, "") {
+		return pvc
+	}
+	newPVC := pvc.DeepCopy()
+	delete(newPVC.Annotations, policy.AnnApprovedVAC)
+	updatedPVC, err := util.PatchClaimMetadata(ctrl.kubeClient, pvc, newPVC, false /* addResourceVersionCheck */)
+	if err != nil {
+		klog.ErrorS(err, "Failed to remove approval annotation", "PVC", klog.KObj(pvc))
+		return pvc
+	}
+	return updatedPVC
+}
+
+// func modifyVolume calls ControllerModifyVolume with the parameters of vac, without changing the PVC or PV.
+func (ctrl *modifyController) modifyVolume(
+	ctx context.Context,
+	pvc *v1.PersistentVolumeClaim,
+	pv *v1.PersistentVolume,
+	vac *storagev1.VolumeAttributesClass) error {
+	modifyPV, err := ctrl.withSecretTemplate(pvc, pv, vac)
+	if err != nil {
+		return err
+	}
+	// Secret templates are never passed to the driver, whether or not they are resolved.
+	parameters := credentials.StripSecretParameters(vac.Parameters)
+	if metadataParameters := ctrl.metadataParameters.Parameters(pvc, pv); len(metadataParameters) > 0 {
+		// Parameters of the VolumeAttributesClass take precedence over labels and annotations.
+		maps.Copy(metadataParameters, parameters)
+		parameters = metadataParameters
+	}
 	if ctrl.extraModifyMetadata {
-		if len(parameters) == 0 {
-			parameters = make(map[string]string, 3)
-		} else {
+		metadata := ctrl.extraMetadata.Parameters(pvc, pv)
+		if len(parameters) > 0 {
 			parameters = maps.Clone(parameters)
+			maps.Copy(parameters, metadata)
+		} else {
+			parameters = metadata
 		}
-		parameters[pvcNameKey] = pvc.GetName()
-		parameters[pvcNamespaceKey] = pvc.GetNamespace()
-		parameters[pvNameKey] = pv.GetName()
 	}
-	err := ctrl.modifier.Modify(ctx, pv, parameters)
+	record := audit.NewRecord(audit.OperationModify, ctrl.name, pvc, pv, ptr.Deref(pvc.Status.CurrentVolumeAttributesClassName, ""), vac.Name)
+	err = ctrl.modifier.Modify(ctx, modifyPV, parameters)
+	audit.Emit(ctrl.auditSink, record.Finish("", err))
+	ctrl.history.Attempted(ctx, pvc, vac.Name, err)
+	return err
+}
 
+// withSecretTemplate returns pv with the modification secret resolved from the templates in vac or
+// the StorageClass of pv. The returned PV is only passed to the modifier and must not be saved.
+func (ctrl *modifyController) withSecretTemplate(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume, vac *storagev1.VolumeAttributesClass) (*v1.PersistentVolume, error) {
+	ref, err := ctrl.secretTemplates.ModifySecretRef(pv, pvc, vac)
 	if err != nil {
-		return pvc, pv, err
+		ctrl.eventRecorder.Event(pvc, v1.EventTypeWarning, util.InvalidSecretTemplate, err.Error())
+		return pv, fmt.Errorf("failed to resolve modification secret of volume %q: %v", pv.Name, err)
+	}
+	if ref == nil {
+		return pv, nil
 	}
+	pv = pv.DeepCopy()
+	if pv.Annotations == nil {
+		pv.Annotations = map[string]string{}
+	}
+	pv.Annotations[modifier.ModifySecretNameAnn] = ref.Name
+	pv.Annotations[modifier.ModifySecretNamespaceAnn] = ref.Namespace
+	return pv, nil
+}
 
-	pvc, pv, err = ctrl.markControllerModifyVolumeCompleted(pvc, pv)
+// func annotateCostEstimate sets the estimated monthly cost change of modifying pvc to targetVAC on the PVC,
+// and returns a note for the modifying event. Failures don't block the modification.
+func (ctrl *modifyController) annotateCostEstimate(pvc *v1.PersistentVolumeClaim, targetVAC string) (*v1.PersistentVolumeClaim, string) {
+	if ctrl.costEstimator == nil {
+		return pvc, ""
+	}
+	estimate, err := ctrl.costEstimator.EstimateModify(pvc, ptr.Deref(pvc.Status.CurrentVolumeAttributesClassName, ""), targetVAC)
 	if err != nil {
-		return pvc, pv, fmt.Errorf("modify volume failed to mark pvc %s modify volume completed: %v ", pvc.Name, err)
+		klog.ErrorS(err, "Failed to estimate cost of modification", "PVC", klog.KObj(pvc))
+		return pvc, ""
+	}
+	costNote := fmt.Sprintf(", estimated monthly cost change %s", estimate)
+	updatedPVC, err := pricing.Annotate(ctrl.kubeClient, pvc, estimate)
+	if err != nil {
+		klog.ErrorS(err, "Failed to annotate PVC with cost estimate", "PVC", klog.KObj(pvc))
+		return pvc, costNote
 	}
-	return pvc, pv, nil
+	return updatedPVC, costNote
 }
 
 // func delayModificationIfRecentlyInfeasible returns a delayRetryError if PVC modification recently failed with
@@ -4,10 +4,19 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/coordinator"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/credentials"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/csi"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/policy"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/vacschema"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	v1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -16,6 +25,7 @@ import (
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 )
 
@@ -230,6 +240,372 @@ func TestCancel(t *testing.T) {
 	}
 }
 
+func TestModifyRequiresApproval(t *testing.T) {
+	basePVC := createTestPVC(pvcName, targetVac /*vacName*/, testVac /*curVacName*/, "" /*targetVacName*/)
+	basePV := createTestPV(1, pvcName, pvcNamespace, "foobaz" /*pvcUID*/, &fsVolumeMode, testVac)
+
+	client := csi.NewMockClient(testDriverName, true, true, true, true, true)
+	initialObjects := []runtime.Object{testVacObject, targetVacObject, basePVC, basePV}
+	ctrlInstance := setupFakeK8sEnvironment(t, client, initialObjects)
+	ctrlInstance.policy = &policy.ModifyPolicy{RequireApproval: true}
+
+	recorder := record.NewFakeRecorder(10)
+	ctrlInstance.eventRecorder = recorder
+
+	pvc, pv, err, modifyCalled := ctrlInstance.modify(basePVC, basePV)
+	if err != nil {
+		t.Fatalf("modify failed with %v", err)
+	}
+	if modifyCalled || client.GetModifyCount() != 0 {
+		t.Fatalf("expected no modify call without approval")
+	}
+	// The reason is reported once, not on every retry.
+	if pvc, _, err, _ = ctrlInstance.modify(pvc, pv); err != nil {
+		t.Fatalf("modify failed with %v", err)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected one %s event, got %d", util.VolumeModifyPending, len(recorder.Events))
+	}
+	expectedStatus := &v1.ModifyVolumeStatus{
+		TargetVolumeAttributesClassName: targetVac,
+		Status:                          v1.PersistentVolumeClaimModifyVolumePending,
+	}
+	if diff := cmp.Diff(expectedStatus, pvc.Status.ModifyVolumeStatus); diff != "" {
+		t.Errorf("unexpected modify volume status (-want +got):\n%s", diff)
+	}
+	idx := slices.IndexFunc(pvc.Status.Conditions, func(c v1.PersistentVolumeClaimCondition) bool {
+		return c.Type == util.ModifyVolumePending
+	})
+	if idx < 0 || pvc.Status.Conditions[idx].Reason != policy.ReasonApprovalRequired {
+		t.Fatalf("expected %s condition with reason %s, got %v", util.ModifyVolumePending, policy.ReasonApprovalRequired, pvc.Status.Conditions)
+	}
+
+	approvedPVC := pvc.DeepCopy()
+	approvedPVC.Annotations = map[string]string{policy.AnnApprovedVAC: targetVac}
+	pvc, err = util.PatchClaimMetadata(ctrlInstance.kubeClient, pvc, approvedPVC, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pvc, _, err, modifyCalled = ctrlInstance.modify(pvc, pv)
+	if err != nil {
+		t.Fatalf("modify failed with %v", err)
+	}
+	if !modifyCalled {
+		t.Fatalf("expected modify call after approval")
+	}
+	if pvc.Status.ModifyVolumeStatus != nil || len(pvc.Status.Conditions) != 0 {
+		t.Errorf("expected modify status and conditions to be cleared, got %v, %v", pvc.Status.ModifyVolumeStatus, pvc.Status.Conditions)
+	}
+	if _, ok := pvc.Annotations[policy.AnnApprovedVAC]; ok {
+		t.Errorf("expected approval annotation removed after the change")
+	}
+}
+
+func TestModifyPendingApprovalCancelled(t *testing.T) {
+	basePVC := createTestPVC(pvcName, targetVac /*vacName*/, testVac /*curVacName*/, "" /*targetVacName*/)
+	basePV := createTestPV(1, pvcName, pvcNamespace, "foobaz" /*pvcUID*/, &fsVolumeMode, testVac)
+
+	client := csi.NewMockClient(testDriverName, true, true, true, true, true)
+	initialObjects := []runtime.Object{testVacObject, targetVacObject, basePVC, basePV}
+	ctrlInstance := setupFakeK8sEnvironment(t, client, initialObjects)
+	ctrlInstance.policy = &policy.ModifyPolicy{
+		RequireApproval: true,
+		StorageClasses: map[string]policy.StorageClassModifyPolicy{
+			"": {Transitions: map[string][]string{testVac: {targetVac}}},
+		},
+	}
+
+	pvc, pv, err, _ := ctrlInstance.modify(basePVC, basePV)
+	if err != nil {
+		t.Fatalf("modify failed with %v", err)
+	}
+	if pvc.Status.ModifyVolumeStatus == nil || pvc.Status.ModifyVolumeStatus.Status != v1.PersistentVolumeClaimModifyVolumePending {
+		t.Fatalf("expected modification held in Pending, got %v", pvc.Status.ModifyVolumeStatus)
+	}
+
+	// Going back to the current class needs neither approval nor a self transition.
+	pvc.Spec.VolumeAttributesClassName = ptr.To(testVac)
+	pvc, _, err, _ = ctrlInstance.modify(pvc, pv)
+	if err != nil {
+		t.Fatalf("modify failed with %v", err)
+	}
+	if pvc.Status.ModifyVolumeStatus != nil || len(pvc.Status.Conditions) != 0 {
+		t.Errorf("expected modification cancelled, got %v, %v", pvc.Status.ModifyVolumeStatus, pvc.Status.Conditions)
+	}
+}
+
+func TestModifyMetadataParameters(t *testing.T) {
+	basePVC := createTestPVC(pvcName, testVac /*vacName*/, testVac /*curVacName*/, "" /*targetVacName*/)
+	basePVC.Labels = map[string]string{"cost-center": "1234", "iops": "100000"}
+	basePV := createTestPV(1, pvcName, pvcNamespace, "foobaz" /*pvcUID*/, &fsVolumeMode, testVac)
+
+	client := csi.NewMockClient(testDriverName, true, true, true, true, true)
+	initialObjects := []runtime.Object{testVacObject, basePVC, basePV}
+	ctrlInstance := setupFakeK8sEnvironment(t, client, initialObjects)
+	ctrlInstance.metadataParameters = &util.MetadataParameters{PVCLabels: []string{"cost-center", "iops"}}
+
+	// The volume was modified before the parameters were enabled, its current labels are recorded
+	// without calling the driver.
+	pvc, pv, err, modifyCalled := ctrlInstance.modify(basePVC, basePV)
+	if err != nil || modifyCalled || client.GetModifyCount() != 0 {
+		t.Fatalf("expected no modify call, got %v", err)
+	}
+	if pv.Annotations[util.AnnModifyMetadataParameters] == "" {
+		t.Fatalf("expected %s annotation on PV", util.AnnModifyMetadataParameters)
+	}
+
+	_, _, err, modifyCalled = ctrlInstance.modify(pvc, pv)
+	if err != nil || modifyCalled || client.GetModifyCount() != 0 {
+		t.Fatalf("expected no modify call for unchanged labels, got %v", err)
+	}
+
+	pvc = pvc.DeepCopy()
+	pvc.Labels["cost-center"] = "5678"
+	pvc, err = ctrlInstance.kubeClient.CoreV1().PersistentVolumeClaims(pvcNamespace).Update(t.Context(), pvc, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("failed to update PVC: %v", err)
+	}
+	if !ctrlInstance.metadataParameters.Changed(basePVC, pvc) {
+		t.Errorf("expected changed label to be noticed")
+	}
+	pvc, pv, err, modifyCalled = ctrlInstance.modify(pvc, pv)
+	if err != nil || !modifyCalled {
+		t.Fatalf("expected modify call for changed label, got %v", err)
+	}
+	expectedParams := map[string]string{"cost-center": "5678", "iops": "3000"}
+	if diff := cmp.Diff(expectedParams, client.GetModifiedParameters()); diff != "" {
+		t.Errorf("unexpected parameters (-want +got):\n%s", diff)
+	}
+	if pvc.Status.ModifyVolumeStatus != nil || ptr.Deref(pvc.Status.CurrentVolumeAttributesClassName, "") != testVac {
+		t.Errorf("expected unchanged status, got %+v", pvc.Status)
+	}
+
+	// A failed modification is retried and leaves the status alone.
+	recorded := pv.Annotations[util.AnnModifyMetadataParameters]
+	client.SetModifyError(status.Error(codes.InvalidArgument, "invalid cost center"))
+	pvc = pvc.DeepCopy()
+	pvc.Labels["cost-center"] = "invalid"
+	_, _, err, _ = ctrlInstance.modify(pvc, pv)
+	if err == nil {
+		t.Fatalf("expected modify error")
+	}
+	pvc, err = ctrlInstance.kubeClient.CoreV1().PersistentVolumeClaims(pvcNamespace).Get(t.Context(), pvcName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pvc.Status.ModifyVolumeStatus != nil || len(pvc.Status.Conditions) != 0 {
+		t.Errorf("expected unchanged status after failure, got %+v", pvc.Status)
+	}
+	if pv.Annotations[util.AnnModifyMetadataParameters] != recorded {
+		t.Errorf("expected the recorded parameters unchanged after failure")
+	}
+}
+
+func TestUpdatePVEnqueuesForMetadataParameters(t *testing.T) {
+	basePVC := createTestPVC(pvcName, testVac /*vacName*/, testVac /*curVacName*/, "" /*targetVacName*/)
+	basePV := createTestPV(1, pvcName, pvcNamespace, "foobaz" /*pvcUID*/, &fsVolumeMode, testVac)
+	client := csi.NewMockClient(testDriverName, true, true, true, true, true)
+	ctrlInstance := setupFakeK8sEnvironment(t, client, []runtime.Object{testVacObject, basePVC, basePV})
+	ctrlInstance.metadataParameters = &util.MetadataParameters{PVLabels: []string{"tier"}}
+	for ctrlInstance.claimQueue.Len() > 0 {
+		key, _ := ctrlInstance.claimQueue.Get()
+		ctrlInstance.claimQueue.Done(key)
+		ctrlInstance.claimQueue.Forget(key)
+	}
+
+	basePV.Spec.ClaimRef = &v1.ObjectReference{Namespace: pvcNamespace, Name: pvcName}
+	newPV := basePV.DeepCopy()
+	newPV.Labels = map[string]string{"other": "x"}
+	ctrlInstance.updatePV(basePV, newPV)
+	if ctrlInstance.claimQueue.Len() != 0 {
+		t.Errorf("expected no PVC enqueued for an unlisted label")
+	}
+	newPV.Labels["tier"] = "gold"
+	ctrlInstance.updatePV(basePV, newPV)
+	if ctrlInstance.claimQueue.Len() != 1 {
+		t.Errorf("expected the PVC enqueued for a listed label")
+	}
+}
+
+func TestModifyStripsSecretParameters(t *testing.T) {
+	basePVC := createTestPVC(pvcName, targetVac /*vacName*/, testVac /*curVacName*/, "" /*targetVacName*/)
+	basePV := createTestPV(1, pvcName, pvcNamespace, "foobaz" /*pvcUID*/, &fsVolumeMode, testVac)
+	vac := targetVacObject.DeepCopy()
+	vac.Parameters = map[string]string{
+		"iops":                               "4567",
+		credentials.ModifySecretNameKey:      "modify-secret",
+		credentials.ModifySecretNamespaceKey: "default",
+	}
+
+	client := csi.NewMockClient(testDriverName, true, true, true, true, true)
+	initialObjects := []runtime.Object{testVacObject, vac, basePVC, basePV}
+	ctrlInstance := setupFakeK8sEnvironment(t, client, initialObjects)
+
+	// Without --resolve-secret-templates.
+	_, _, err, modifyCalled := ctrlInstance.modify(basePVC, basePV)
+	if err != nil || !modifyCalled {
+		t.Fatalf("expected modify call, got %v", err)
+	}
+	if diff := cmp.Diff(map[string]string{"iops": "4567"}, client.GetModifiedParameters()); diff != "" {
+		t.Errorf("unexpected parameters (-want +got):\n%s", diff)
+	}
+}
+
+func TestModifyInvalidVAC(t *testing.T) {
+	basePVC := createTestPVC(pvcName, targetVac /*vacName*/, testVac /*curVacName*/, "" /*targetVacName*/)
+	basePV := createTestPV(1, pvcName, pvcNamespace, "foobaz" /*pvcUID*/, &fsVolumeMode, testVac)
+
+	client := csi.NewMockClient(testDriverName, true, true, true, true, true)
+	initialObjects := []runtime.Object{testVacObject, targetVacObject, basePVC, basePV}
+	ctrlInstance := setupFakeK8sCaches(t, client, initialObjects)
+	recorder := record.NewFakeRecorder(10)
+	ctrlInstance.eventRecorder = recorder
+	ctrlInstance.vacSchema = vacschema.NewAnnotationSource(func() (string, bool) {
+		return "parameters: {iops: {type: integer, maximum: '1000'}}", true
+	})
+
+	pvc, _, err, modifyCalled := ctrlInstance.modify(basePVC, basePV)
+	if !util.IsDelayRetryError(err) {
+		t.Errorf("expected delayed retry, got %v", err)
+	}
+	if modifyCalled || client.GetModifyCount() != 0 {
+		t.Fatalf("expected no modify call for invalid VAC")
+	}
+	expectedStatus := &v1.ModifyVolumeStatus{
+		TargetVolumeAttributesClassName: targetVac,
+		Status:                          v1.PersistentVolumeClaimModifyVolumePending,
+	}
+	if diff := cmp.Diff(expectedStatus, pvc.Status.ModifyVolumeStatus); diff != "" {
+		t.Errorf("unexpected modify volume status (-want +got):\n%s", diff)
+	}
+	idx := slices.IndexFunc(pvc.Status.Conditions, func(c v1.PersistentVolumeClaimCondition) bool {
+		return c.Type == util.ModifyVolumePending
+	})
+	if idx < 0 || pvc.Status.Conditions[idx].Reason != vacschema.ReasonInvalidParameters {
+		t.Fatalf("expected %s condition with reason %s, got %v", util.ModifyVolumePending, vacschema.ReasonInvalidParameters, pvc.Status.Conditions)
+	}
+	for range 2 {
+		if event := <-recorder.Events; !strings.Contains(event, util.InvalidVolumeAttributesClass) || !strings.Contains(event, `parameter "iops"`) {
+			t.Errorf("unexpected event %q", event)
+		}
+	}
+
+	// Classes are checked when they appear.
+	ctrlInstance.addVAC(testVacObject)
+	if event := <-recorder.Events; !strings.Contains(event, util.InvalidVolumeAttributesClass) {
+		t.Errorf("unexpected event %q", event)
+	}
+	ctrlInstance.addVAC(&storagev1.VolumeAttributesClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "valid"},
+		DriverName: testDriverName,
+		Parameters: map[string]string{"iops": "500"},
+	})
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("unexpected event %q for valid VAC", event)
+	default:
+	}
+}
+
+func TestModifyDriftOnVACRecreate(t *testing.T) {
+	basePVC := createTestPVC(pvcName, testVac /*vacName*/, testVac /*curVacName*/, "" /*targetVacName*/)
+	basePV := createTestPV(1, pvcName, pvcNamespace, "foobaz" /*pvcUID*/, &fsVolumeMode, testVac)
+
+	client := csi.NewMockClient(testDriverName, true, true, true, true, true)
+	initialObjects := []runtime.Object{testVacObject, basePVC, basePV}
+	ctrlInstance := setupFakeK8sEnvironment(t, client, initialObjects)
+	recorder := record.NewFakeRecorder(10)
+	ctrlInstance.eventRecorder = recorder
+	ctrlInstance.drift = DriftReconciliation{OnVACRecreate: true}
+
+	_, _, err, modifyCalled := ctrlInstance.modify(basePVC, basePV)
+	if err != nil || modifyCalled {
+		t.Fatalf("expected no modify call for completed modification, got %v", err)
+	}
+
+	recreatedVAC := testVacObject.DeepCopy()
+	recreatedVAC.UID = "recreated"
+	ctrlInstance.updateVAC(testVacObject, recreatedVAC)
+	if ctrlInstance.claimQueue.Len() != 1 {
+		t.Fatalf("expected PVC to be enqueued, got queue length %d", ctrlInstance.claimQueue.Len())
+	}
+
+	pvc, _, err, modifyCalled := ctrlInstance.modify(basePVC, basePV)
+	if err != nil || !modifyCalled {
+		t.Fatalf("expected modify call for recreated VAC, got %v", err)
+	}
+	if diff := cmp.Diff(map[string]string{"iops": "3000"}, client.GetModifiedParameters()); diff != "" {
+		t.Errorf("unexpected parameters (-want +got):\n%s", diff)
+	}
+	if pvc.Status.ModifyVolumeStatus != nil || ptr.Deref(pvc.Status.CurrentVolumeAttributesClassName, "") != testVac {
+		t.Errorf("expected status to be unchanged, got %+v", pvc.Status)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, util.VolumeModifyDriftReconciled) {
+			t.Errorf("expected %s event, got %q", util.VolumeModifyDriftReconciled, event)
+		}
+	default:
+		t.Errorf("expected %s event", util.VolumeModifyDriftReconciled)
+	}
+
+	_, _, err, modifyCalled = ctrlInstance.modify(basePVC, basePV)
+	if err != nil || modifyCalled || client.GetModifyCount() != 1 {
+		t.Fatalf("expected no further modify call, got %v", err)
+	}
+}
+
+func TestModifyDriftPeriodic(t *testing.T) {
+	basePVC := createTestPVC(pvcName, testVac /*vacName*/, testVac /*curVacName*/, "" /*targetVacName*/)
+	basePVC.Spec.Resources.Requests[v1.ResourceStorage] = resource.MustParse("4Gi")
+	basePV := createTestPV(1, pvcName, pvcNamespace, "foobaz" /*pvcUID*/, &fsVolumeMode, testVac)
+	foreignPVC := createTestPVC("foreign", testVac /*vacName*/, testVac /*curVacName*/, "" /*targetVacName*/)
+	foreignPVC.Spec.VolumeName = "foreign"
+	foreignPV := basePV.DeepCopy()
+	foreignPV.Name = "foreign"
+	foreignPV.Spec.CSI.Driver = "other"
+
+	client := csi.NewMockClient(testDriverName, true, true, true, true, true)
+	initialObjects := []runtime.Object{testVacObject, basePVC, basePV, foreignPVC, foreignPV}
+	ctrlInstance := setupFakeK8sEnvironment(t, client, initialObjects)
+	recorder := record.NewFakeRecorder(10)
+	ctrlInstance.eventRecorder = recorder
+	ctrlInstance.drift = DriftReconciliation{Interval: time.Hour}
+	ctrlInstance.coordinator = coordinator.New(coordinator.OrderResizeFirst, nil)
+
+	ctrlInstance.enqueueForDrift(driftReasonPeriodic, "")
+	key := pvcNamespace + "/" + pvcName
+	if _, ok := ctrlInstance.driftPending.Load(key); !ok {
+		t.Fatalf("expected PVC of the driver pending for drift reconciliation")
+	}
+	if _, ok := ctrlInstance.driftPending.Load(pvcNamespace + "/foreign"); ok {
+		t.Errorf("expected PVC of another driver not to be enqueued")
+	}
+
+	// The expansion goes first.
+	if err := ctrlInstance.syncPVC(key); !util.IsDelayRetryError(err) {
+		t.Errorf("expected delayed retry while the expansion is pending, got %v", err)
+	}
+	if client.GetModifyCount() != 0 {
+		t.Errorf("expected no modify call before the expansion")
+	}
+
+	ctrlInstance.coordinator = nil
+	if err := ctrlInstance.syncPVC(key); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.GetModifyCount() != 1 {
+		t.Errorf("expected modify call, got %d", client.GetModifyCount())
+	}
+	var reconciled bool
+	for len(recorder.Events) > 0 {
+		reconciled = reconciled || strings.Contains(<-recorder.Events, util.VolumeModifyDriftReconciled)
+	}
+	if !reconciled {
+		t.Errorf("expected %s event", util.VolumeModifyDriftReconciled)
+	}
+}
+
 func createTestPVC(pvcName string, vacName string, curVacName string, targetVacName string) *v1.PersistentVolumeClaim {
 	pvc := &v1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{Name: pvcName, Namespace: pvcNamespace},
@@ -0,0 +1,576 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nodeexpansion watches PVCs whose volume was expanded by the controller and waits for
+// the expansion on the node, and escalates when that takes too long.
+package nodeexpansion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/features"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/resizer"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/sharding"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// AnnPodPending is set on pods to the comma separated names of the PVCs they use whose
+	// expansion on the node is overdue.
+	AnnPodPending = "resizer.csi.k8s.io/node-expansion-pending"
+	// AnnRestartWorkloads opts the PVCs of a StorageClass in to the restart of the workloads
+	// that use them, when their expansion on the node is overdue at the last threshold.
+	AnnRestartWorkloads = "resizer.csi.k8s.io/restart-on-overdue-node-expansion"
+	// annRestartedAt is the pod template annotation that "kubectl rollout restart" sets.
+	annRestartedAt = "kubectl.kubernetes.io/restartedAt"
+)
+
+var (
+	overduePVCs = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      "csi_resizer",
+			Name:           "node_expansion_overdue_pvcs",
+			Help:           "Number of PVCs whose expansion on the node is overdue, by allocated resource status.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"status"},
+	)
+	escalations = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      "csi_resizer",
+			Name:           "node_expansion_escalations_total",
+			Help:           "Number of times the expansion of a volume on the node became overdue, by escalation level.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"level"},
+	)
+	workloadRestarts = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      "csi_resizer",
+			Name:           "node_expansion_workload_restarts_total",
+			Help:           "Number of workloads restarted to finish the expansion of a volume on the node, by kind and result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"kind", "result"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(overduePVCs, escalations, workloadRestarts)
+}
+
+// Config configures the watchdog.
+type Config struct {
+	// Thresholds are the escalation levels, in ascending order. A Warning event is recorded
+	// each time a PVC waits for the expansion on the node longer than the next threshold.
+	Thresholds []time.Duration
+	// AnnotatePods sets AnnPodPending on the pods that use PVCs past the first threshold.
+	AnnotatePods bool
+	// RestartWorkloads restarts the Deployments, StatefulSets and DaemonSets whose pods use PVCs
+	// past the last threshold, if the StorageClass of the PVC opts in with AnnRestartWorkloads.
+	RestartWorkloads bool
+}
+
+// overdue is the state of a PVC past the first threshold.
+type overdue struct {
+	status v1.ClaimResourceStatus
+	// since is when the PVC started to wait for the expansion on the node.
+	since time.Time
+	// level is the last escalation level that was reported.
+	level int
+	// restarted is true once the workloads of the PVC were restarted.
+	restarted bool
+	// restartedWorkloads are the "kind/name" of the workloads that were already restarted, so that
+	// a retry after a partial failure does not roll them out again.
+	restartedWorkloads sets.Set[string]
+}
+
+// Watchdog records escalating events for PVCs of a driver that wait for the expansion of their
+// volume on the node longer than the configured thresholds.
+type Watchdog struct {
+	name          string
+	resizer       resizer.Resizer
+	kubeClient    kubernetes.Interface
+	queue         workqueue.TypedRateLimitingInterface[string]
+	eventRecorder record.EventRecorder
+	pvcLister     corelisters.PersistentVolumeClaimLister
+	pvLister      corelisters.PersistentVolumeLister
+	podLister     corelisters.PodLister
+	synced        []cache.InformerSynced
+	// scLister is only set with RestartWorkloads.
+	scLister storagelisters.StorageClassLister
+	config   Config
+	// shards limits the watchdog to the PVCs of the shards this replica owns, nil handles all PVCs.
+	shards *sharding.Manager
+	now    func() time.Time
+
+	mu sync.Mutex
+	// pvcs maps keys of PVCs waiting for the expansion on the node to their state.
+	pvcs map[string]*overdue
+}
+
+// NewWatchdog returns a Watchdog of the PVCs of the driver of resizer.
+func NewWatchdog(
+	name string,
+	resizer resizer.Resizer,
+	kubeClient kubernetes.Interface,
+	informerFactory informers.SharedInformerFactory,
+	config Config,
+	shardManager *sharding.Manager) *Watchdog {
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartStructuredLogging(0)
+	eventBroadcaster.StartRecordingToSink(&corev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events(v1.NamespaceAll)})
+	eventRecorder := eventBroadcaster.NewRecorder(scheme.Scheme,
+		v1.EventSource{Component: fmt.Sprintf("external-resizer %s", name)})
+
+	pvcInformer := informerFactory.Core().V1().PersistentVolumeClaims()
+	pvInformer := informerFactory.Core().V1().PersistentVolumes()
+	podInformer := informerFactory.Core().V1().Pods()
+	w := &Watchdog{
+		name:          name,
+		resizer:       resizer,
+		kubeClient:    kubeClient,
+		eventRecorder: eventRecorder,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](), workqueue.TypedRateLimitingQueueConfig[string]{
+				Name: fmt.Sprintf("%s-node-expansion", name),
+			}),
+		pvcLister: pvcInformer.Lister(),
+		pvLister:  pvInformer.Lister(),
+		podLister: podInformer.Lister(),
+		synced:    []cache.InformerSynced{pvcInformer.Informer().HasSynced, pvInformer.Informer().HasSynced, podInformer.Informer().HasSynced},
+		config:    config,
+		shards:    shardManager,
+		now:       time.Now,
+		pvcs:      map[string]*overdue{},
+	}
+	if config.RestartWorkloads {
+		scInformer := informerFactory.Storage().V1().StorageClasses()
+		w.scLister = scInformer.Lister()
+		w.synced = append(w.synced, scInformer.Informer().HasSynced)
+	}
+
+	pvcInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		// All PVCs are checked once, to clean up after PVCs that finished while the watchdog was not running.
+		AddFunc:    w.enqueue,
+		UpdateFunc: func(_, newObj interface{}) { w.enqueueIfWaiting(newObj) },
+		DeleteFunc: w.deletePVC,
+	})
+	return w
+}
+
+func (w *Watchdog) enqueue(obj interface{}) {
+	key, err := util.GetObjectKey(obj)
+	if err != nil {
+		return
+	}
+	w.queue.Add(key)
+}
+
+// enqueueIfWaiting enqueues PVCs that wait for the expansion on the node, or that stopped waiting.
+func (w *Watchdog) enqueueIfWaiting(obj interface{}) {
+	pvc, ok := obj.(*v1.PersistentVolumeClaim)
+	if !ok {
+		return
+	}
+	key, err := util.GetObjectKey(pvc)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	_, tracked := w.pvcs[key]
+	w.mu.Unlock()
+	if tracked || waiting(pvc) != "" {
+		w.queue.Add(key)
+	}
+}
+
+func (w *Watchdog) deletePVC(obj interface{}) {
+	key, err := util.GetObjectKey(obj)
+	if err != nil {
+		return
+	}
+	w.forget(key)
+	w.queue.Forget(key)
+	// Remove the deleted PVC from the annotation of its pods.
+	w.queue.Add(key)
+}
+
+// Run starts the watchdog.
+func (w *Watchdog) Run(workers int, ctx context.Context, wg *sync.WaitGroup) {
+	defer w.queue.ShutDown()
+
+	klog.InfoS("Starting node expansion watchdog", "controller", w.name)
+	defer klog.InfoS("Shutting down node expansion watchdog", "controller", w.name)
+
+	stopCh := ctx.Done()
+	if !cache.WaitForCacheSync(stopCh, w.synced...) {
+		klog.ErrorS(nil, "Cannot sync pod, pv, pvc or storage class caches")
+		return
+	}
+
+	worker := func() {
+		for w.processNext(ctx) {
+		}
+	}
+	if utilfeature.DefaultFeatureGate.Enabled(features.ReleaseLeaderElectionOnExit) {
+		for range workers {
+			wg.Go(func() {
+				wait.Until(worker, 0, stopCh)
+			})
+		}
+	} else {
+		for range workers {
+			go wait.Until(worker, 0, stopCh)
+		}
+	}
+
+	<-stopCh
+}
+
+func (w *Watchdog) processNext(ctx context.Context) bool {
+	key, quit := w.queue.Get()
+	if quit {
+		return false
+	}
+	defer w.queue.Done(key)
+
+	if err := w.sync(ctx, key); err != nil {
+		klog.ErrorS(err, "Error checking node expansion of PVC", "key", key)
+		w.queue.AddRateLimited(key)
+		return true
+	}
+	w.queue.Forget(key)
+	return true
+}
+
+// sync checks how long a PVC waits for the expansion on the node and escalates when the next
+// threshold passed.
+func (w *Watchdog) sync(ctx context.Context, key string) error {
+	if !w.shards.Owns(key) {
+		w.forget(key)
+		return nil
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	pvc, err := w.pvcLister.PersistentVolumeClaims(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		w.forget(key)
+		deleted := &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+		return w.annotatePods(ctx, deleted, false)
+	}
+	if err != nil {
+		return err
+	}
+	if pvc.Spec.VolumeName == "" {
+		return nil
+	}
+	pv, err := w.pvLister.Get(pvc.Spec.VolumeName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !w.resizer.CanSupport(pv, pvc) {
+		return nil
+	}
+
+	status := waiting(pvc)
+	if status == "" {
+		w.forget(key)
+		// The expansion finished, remove the PVC from the annotation of its pods.
+		return w.annotatePods(ctx, pvc, false)
+	}
+
+	w.mu.Lock()
+	state, ok := w.pvcs[key]
+	if !ok {
+		state = &overdue{since: w.now(), restartedWorkloads: sets.New[string]()}
+		w.pvcs[key] = state
+	}
+	if since := waitingSince(pvc); !since.IsZero() {
+		state.since = since
+	}
+	state.status = status
+	elapsed := w.now().Sub(state.since)
+	level := 0
+	for level < len(w.config.Thresholds) && elapsed >= w.config.Thresholds[level] {
+		level++
+	}
+	reported := state.level
+	state.level = max(level, state.level)
+	restart := w.config.RestartWorkloads && level == len(w.config.Thresholds) && !state.restarted
+	since := state.since
+	restarted := state.restartedWorkloads.Clone()
+	w.mu.Unlock()
+	w.updateMetrics()
+
+	if level < len(w.config.Thresholds) {
+		w.queue.AddAfter(key, w.config.Thresholds[level]-elapsed)
+	}
+	if level == 0 {
+		return nil
+	}
+	if level > reported {
+		escalations.WithLabelValues(fmt.Sprint(level)).Inc()
+		w.eventRecorder.Eventf(pvc, v1.EventTypeWarning, util.NodeExpansionOverdue,
+			"Expansion of the volume on the node has been %s for %s (level %d of %d). It finishes when a pod that uses the PVC is started or restarted.",
+			status, elapsed.Round(time.Minute), level, len(w.config.Thresholds))
+	}
+	if err := w.annotatePods(ctx, pvc, true); err != nil {
+		return err
+	}
+	if restart && w.restartOptedIn(pv) {
+		if err := w.restartWorkloads(ctx, key, pvc, since, restarted); err != nil {
+			return err
+		}
+		w.mu.Lock()
+		if state, ok := w.pvcs[key]; ok {
+			state.restarted = true
+		}
+		w.mu.Unlock()
+	}
+	return nil
+}
+
+func (w *Watchdog) forget(key string) {
+	w.mu.Lock()
+	_, ok := w.pvcs[key]
+	delete(w.pvcs, key)
+	w.mu.Unlock()
+	if ok {
+		w.updateMetrics()
+	}
+}
+
+// updateMetrics sets the number of PVCs past the first threshold.
+func (w *Watchdog) updateMetrics() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	counts := map[v1.ClaimResourceStatus]int{
+		v1.PersistentVolumeClaimNodeResizePending:    0,
+		v1.PersistentVolumeClaimNodeResizeInProgress: 0,
+	}
+	for _, state := range w.pvcs {
+		if state.level > 0 {
+			counts[state.status]++
+		}
+	}
+	for status, count := range counts {
+		overduePVCs.WithLabelValues(string(status)).Set(float64(count))
+	}
+}
+
+// annotatePods adds pvc to or removes it from the AnnPodPending annotation of the pods that use it.
+func (w *Watchdog) annotatePods(ctx context.Context, pvc *v1.PersistentVolumeClaim, add bool) error {
+	if !w.config.AnnotatePods {
+		return nil
+	}
+	pods, err := w.pods(pvc)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		names := splitNames(pod.Annotations[AnnPodPending])
+		if slices.Contains(names, pvc.Name) == add {
+			continue
+		}
+		if add {
+			names = append(names, pvc.Name)
+			slices.Sort(names)
+		} else {
+			names = slices.DeleteFunc(names, func(name string) bool { return name == pvc.Name })
+		}
+		var value any
+		if len(names) > 0 {
+			value = strings.Join(names, ",")
+		}
+		patch, err := json.Marshal(map[string]any{"metadata": map[string]any{"annotations": map[string]any{AnnPodPending: value}}})
+		if err != nil {
+			return err
+		}
+		_, err = w.kubeClient.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to annotate pod %s: %w", klog.KObj(pod), err)
+		}
+	}
+	return nil
+}
+
+// pods returns the pods that use pvc and are not terminated.
+func (w *Watchdog) pods(pvc *v1.PersistentVolumeClaim) ([]*v1.Pod, error) {
+	pods, err := w.podLister.Pods(pvc.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var result []*v1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		if slices.ContainsFunc(pod.Spec.Volumes, func(volume v1.Volume) bool {
+			return volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == pvc.Name
+		}) {
+			result = append(result, pod)
+		}
+	}
+	return result, nil
+}
+
+func (w *Watchdog) restartOptedIn(pv *v1.PersistentVolume) bool {
+	if pv.Spec.StorageClassName == "" {
+		return false
+	}
+	sc, err := w.scLister.Get(pv.Spec.StorageClassName)
+	if err != nil {
+		return false
+	}
+	return sc.Annotations[AnnRestartWorkloads] == "true"
+}
+
+// restartWorkloads restarts the workloads of the pods of pvc that started before the PVC began to
+// wait for the expansion on the node, like "kubectl rollout restart" does. Workloads in restarted
+// are skipped, and every restarted workload is recorded in the state of key.
+func (w *Watchdog) restartWorkloads(ctx context.Context, key string, pvc *v1.PersistentVolumeClaim, since time.Time, restarted sets.Set[string]) error {
+	pods, err := w.pods(pvc)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		if pod.Status.StartTime == nil || !pod.Status.StartTime.Time.Before(since) {
+			// The pod was started while the PVC waited, restarting it again does not help.
+			continue
+		}
+		kind, name, err := w.workload(ctx, pod)
+		if err != nil {
+			return err
+		}
+		if kind == "" {
+			w.eventRecorder.Eventf(pvc, v1.EventTypeWarning, util.NodeExpansionRestartFailed,
+				"Pod %s that uses the PVC is not managed by a Deployment, StatefulSet or DaemonSet and must be restarted manually", pod.Name)
+			continue
+		}
+		workload := kind + "/" + name
+		if restarted.Has(workload) {
+			continue
+		}
+
+		patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`, annRestartedAt, w.now().Format(time.RFC3339))
+		apps := w.kubeClient.AppsV1()
+		switch kind {
+		case "Deployment":
+			_, err = apps.Deployments(pod.Namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+		case "StatefulSet":
+			_, err = apps.StatefulSets(pod.Namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+		case "DaemonSet":
+			_, err = apps.DaemonSets(pod.Namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+		}
+		if err != nil {
+			workloadRestarts.WithLabelValues(kind, "error").Inc()
+			w.eventRecorder.Eventf(pvc, v1.EventTypeWarning, util.NodeExpansionRestartFailed, "Failed to restart %s %s: %v", kind, name, err)
+			return fmt.Errorf("failed to restart %s %s/%s: %w", kind, pod.Namespace, name, err)
+		}
+		restarted.Insert(workload)
+		w.mu.Lock()
+		if state, ok := w.pvcs[key]; ok {
+			state.restartedWorkloads.Insert(workload)
+		}
+		w.mu.Unlock()
+		workloadRestarts.WithLabelValues(kind, "success").Inc()
+		klog.V(2).InfoS("Restarted workload to finish node expansion", "PVC", klog.KObj(pvc), "kind", kind, "name", name)
+		w.eventRecorder.Eventf(pvc, v1.EventTypeNormal, util.NodeExpansionWorkloadRestarted,
+			"Restarted %s %s to finish the expansion of the volume on the node", kind, name)
+	}
+	return nil
+}
+
+// workload returns the kind and name of the Deployment, StatefulSet or DaemonSet of pod, or an
+// empty kind if it has none.
+func (w *Watchdog) workload(ctx context.Context, pod *v1.Pod) (string, string, error) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return "", "", nil
+	}
+	switch ref.Kind {
+	case "StatefulSet", "DaemonSet":
+		return ref.Kind, ref.Name, nil
+	case "ReplicaSet":
+		rs, err := w.kubeClient.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get ReplicaSet of pod %s: %w", klog.KObj(pod), err)
+		}
+		if ref := metav1.GetControllerOf(rs); ref != nil && ref.Kind == "Deployment" {
+			return ref.Kind, ref.Name, nil
+		}
+	}
+	return "", "", nil
+}
+
+// waiting returns the allocated resource status of pvc if it waits for the expansion on the node.
+func waiting(pvc *v1.PersistentVolumeClaim) v1.ClaimResourceStatus {
+	switch status := pvc.Status.AllocatedResourceStatuses[v1.ResourceStorage]; status {
+	case v1.PersistentVolumeClaimNodeResizePending, v1.PersistentVolumeClaimNodeResizeInProgress:
+		return status
+	}
+	return ""
+}
+
+// waitingSince returns when the resize controller marked pvc for expansion on the node, or zero if unknown.
+func waitingSince(pvc *v1.PersistentVolumeClaim) time.Time {
+	for _, c := range pvc.Status.Conditions {
+		if c.Type == v1.PersistentVolumeClaimFileSystemResizePending {
+			return c.LastTransitionTime.Time
+		}
+	}
+	return time.Time{}
+}
+
+func splitNames(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,397 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeexpansion
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/controller"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/csi"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/resizer"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/testutil"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/ptr"
+)
+
+const driverName = "mock"
+
+var testNow = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// waitingPVC returns a PVC that waits for the expansion on the node since waitingFor before testNow.
+func waitingPVC(waitingFor time.Duration) *v1.PersistentVolumeClaim {
+	pvc := testutil.GetTestPVC("pv", "2Gi", "1Gi", "2Gi", v1.PersistentVolumeClaimNodeResizePending)
+	pvc.Status.Conditions = []v1.PersistentVolumeClaimCondition{{
+		Type:               v1.PersistentVolumeClaimFileSystemResizePending,
+		Status:             v1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow.Add(-waitingFor)),
+	}}
+	return pvc
+}
+
+func testPV() *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv"},
+		Spec: v1.PersistentVolumeSpec{
+			Capacity:         v1.ResourceList{v1.ResourceStorage: resource.MustParse("2Gi")},
+			StorageClassName: "fast",
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: "vol"},
+			},
+		},
+	}
+}
+
+func testStorageClass(optIn bool) *storagev1.StorageClass {
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}, Provisioner: driverName}
+	if optIn {
+		sc.Annotations = map[string]string{AnnRestartWorkloads: "true"}
+	}
+	return sc
+}
+
+// testPod returns a pod of Deployment "web" that uses claim01 and was started at startTime.
+func testPod(startTime time.Time) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "web-1",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-abc", Controller: ptr.To(true)}},
+		},
+		Spec: v1.PodSpec{Volumes: []v1.Volume{{
+			Name:         "data",
+			VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "claim01"}},
+		}}},
+		Status: v1.PodStatus{Phase: v1.PodRunning, StartTime: ptr.To(metav1.NewTime(startTime))},
+	}
+}
+
+func testWorkload() []runtime.Object {
+	return []runtime.Object{
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name:            "web-abc",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Controller: ptr.To(true)}},
+		}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+	}
+}
+
+func newTestWatchdog(t *testing.T, config Config, objs ...runtime.Object) (*Watchdog, *fake.Clientset, *record.FakeRecorder) {
+	t.Helper()
+	kubeClient := fake.NewSimpleClientset(objs...)
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	csiResizer, err := resizer.NewResizerFromClient(csi.NewMockClient(driverName, true, true, false, true, true),
+		15*time.Second, kubeClient, driverName, nil, resizer.MetadataNone)
+	if err != nil {
+		t.Fatalf("Unable to create resizer: %v", err)
+	}
+	w := NewWatchdog(driverName, csiResizer, kubeClient, informerFactory, config, nil /* shardManager */)
+	t.Cleanup(w.queue.ShutDown)
+	for _, obj := range objs {
+		var store cache.Store
+		switch obj.(type) {
+		case *v1.PersistentVolumeClaim:
+			store = informerFactory.Core().V1().PersistentVolumeClaims().Informer().GetStore()
+		case *v1.PersistentVolume:
+			store = informerFactory.Core().V1().PersistentVolumes().Informer().GetStore()
+		case *v1.Pod:
+			store = informerFactory.Core().V1().Pods().Informer().GetStore()
+		case *storagev1.StorageClass:
+			store = informerFactory.Storage().V1().StorageClasses().Informer().GetStore()
+		default:
+			continue
+		}
+		store.Add(obj)
+	}
+	recorder := record.NewFakeRecorder(10)
+	w.eventRecorder = recorder
+	w.now = func() time.Time { return testNow }
+	return w, kubeClient, recorder
+}
+
+func events(recorder *record.FakeRecorder) []string {
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	return events
+}
+
+func TestEscalation(t *testing.T) {
+	config := Config{Thresholds: []time.Duration{time.Hour, 24 * time.Hour}, AnnotatePods: true}
+	w, kubeClient, recorder := newTestWatchdog(t, config, waitingPVC(30*time.Minute), testPV(), testPod(testNow.Add(-time.Hour)))
+
+	// Below the first threshold.
+	if err := w.sync(context.TODO(), "default/claim01"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if events := events(recorder); len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+
+	for i, now := range []time.Time{testNow.Add(time.Hour), testNow.Add(2 * time.Hour), testNow.Add(24 * time.Hour)} {
+		w.now = func() time.Time { return now }
+		if err := w.sync(context.TODO(), "default/claim01"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		events := events(recorder)
+		switch i {
+		case 0, 2:
+			if len(events) != 1 || !strings.HasPrefix(events[0], "Warning "+util.NodeExpansionOverdue) {
+				t.Errorf("expected %s event, got %v", util.NodeExpansionOverdue, events)
+			}
+		case 1:
+			// The level did not change.
+			if len(events) != 0 {
+				t.Errorf("expected no events, got %v", events)
+			}
+		}
+	}
+	pod, err := kubeClient.CoreV1().Pods("default").Get(context.TODO(), "web-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pod.Annotations[AnnPodPending] != "claim01" {
+		t.Errorf("expected pod annotated with claim01, got %q", pod.Annotations[AnnPodPending])
+	}
+}
+
+func TestFinishedRemovesPodAnnotation(t *testing.T) {
+	pvc := testutil.GetTestPVC("pv", "2Gi", "2Gi", "2Gi", "")
+	pod := testPod(testNow.Add(-time.Hour))
+	pod.Annotations = map[string]string{AnnPodPending: "claim01,other"}
+	config := Config{Thresholds: []time.Duration{time.Hour}, AnnotatePods: true}
+	w, kubeClient, _ := newTestWatchdog(t, config, pvc, testPV(), pod)
+	w.pvcs["default/claim01"] = &overdue{status: v1.PersistentVolumeClaimNodeResizePending, level: 1}
+
+	if err := w.sync(context.TODO(), "default/claim01"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := w.pvcs["default/claim01"]; ok {
+		t.Errorf("expected finished PVC forgotten")
+	}
+	pod, err := kubeClient.CoreV1().Pods("default").Get(context.TODO(), "web-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pod.Annotations[AnnPodPending] != "other" {
+		t.Errorf("expected only the other PVC in the annotation, got %q", pod.Annotations[AnnPodPending])
+	}
+}
+
+func TestRestartWorkloads(t *testing.T) {
+	tests := []struct {
+		name            string
+		optIn           bool
+		podStart        time.Time
+		expectedRestart bool
+	}{
+		{
+			name:            "opted in",
+			optIn:           true,
+			podStart:        testNow.Add(-48 * time.Hour),
+			expectedRestart: true,
+		},
+		{
+			name:     "StorageClass not opted in",
+			podStart: testNow.Add(-48 * time.Hour),
+		},
+		{
+			name:     "pod started while waiting",
+			optIn:    true,
+			podStart: testNow.Add(-time.Minute),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Config{Thresholds: []time.Duration{time.Hour, 2 * time.Hour}, RestartWorkloads: true}
+			objs := append(testWorkload(), waitingPVC(3*time.Hour), testPV(), testPod(test.podStart), testStorageClass(test.optIn))
+			w, kubeClient, recorder := newTestWatchdog(t, config, objs...)
+
+			for range 2 {
+				if err := w.sync(context.TODO(), "default/claim01"); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			deployment, err := kubeClient.AppsV1().Deployments("default").Get(context.TODO(), "web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, restarted := deployment.Spec.Template.Annotations[annRestartedAt]
+			if restarted != test.expectedRestart {
+				t.Errorf("expected restart %t, got annotations %v", test.expectedRestart, deployment.Spec.Template.Annotations)
+			}
+			var restartEvents int
+			for _, event := range events(recorder) {
+				if strings.HasPrefix(event, "Normal "+util.NodeExpansionWorkloadRestarted) {
+					restartEvents++
+				}
+			}
+			if restarted && restartEvents != 1 {
+				t.Errorf("expected the workload restarted once, got %d events", restartEvents)
+			}
+		})
+	}
+}
+
+// TestRestartWorkloadsPartialFailure checks that a retry after a failed restart only restarts the
+// workloads that were not restarted yet.
+func TestRestartWorkloadsPartialFailure(t *testing.T) {
+	config := Config{Thresholds: []time.Duration{time.Hour, 2 * time.Hour}, RestartWorkloads: true}
+	dbPod := testPod(testNow.Add(-48 * time.Hour))
+	dbPod.Name = "db-0"
+	dbPod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "db", Controller: ptr.To(true)}}
+	objs := append(testWorkload(), &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}},
+		waitingPVC(3*time.Hour), testPV(), testPod(testNow.Add(-48*time.Hour)), dbPod, testStorageClass(true))
+	w, kubeClient, recorder := newTestWatchdog(t, config, objs...)
+	// The second restart fails once.
+	patches := 0
+	kubeClient.PrependReactor("patch", "*", func(action core.Action) (bool, runtime.Object, error) {
+		if action.GetResource().Group != "apps" {
+			return false, nil, nil
+		}
+		patches++
+		if patches == 2 {
+			return true, nil, errors.New("unavailable")
+		}
+		return false, nil, nil
+	})
+
+	if err := w.sync(context.TODO(), "default/claim01"); err == nil {
+		t.Fatal("expected error for the failed restart")
+	}
+	if err := w.sync(context.TODO(), "default/claim01"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patches != 3 {
+		t.Errorf("expected 3 restart attempts, got %d", patches)
+	}
+	deployment, err := kubeClient.AppsV1().Deployments("default").Get(context.TODO(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statefulSet, err := kubeClient.AppsV1().StatefulSets("default").Get(context.TODO(), "db", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, annotations := range []map[string]string{deployment.Spec.Template.Annotations, statefulSet.Spec.Template.Annotations} {
+		if _, ok := annotations[annRestartedAt]; !ok {
+			t.Errorf("expected both workloads restarted, got annotations %v", annotations)
+		}
+	}
+	var restartEvents int
+	for _, event := range events(recorder) {
+		if strings.HasPrefix(event, "Normal "+util.NodeExpansionWorkloadRestarted) {
+			restartEvents++
+		}
+	}
+	if restartEvents != 2 {
+		t.Errorf("expected each workload restarted once, got %d events", restartEvents)
+	}
+}
+
+func TestDeletedPVCRemovesPodAnnotation(t *testing.T) {
+	pod := testPod(testNow.Add(-time.Hour))
+	pod.Annotations = map[string]string{AnnPodPending: "claim01"}
+	config := Config{Thresholds: []time.Duration{time.Hour}, AnnotatePods: true}
+	w, kubeClient, _ := newTestWatchdog(t, config, testPV(), pod)
+	w.pvcs["default/claim01"] = &overdue{status: v1.PersistentVolumeClaimNodeResizePending, level: 1}
+
+	if err := w.sync(context.TODO(), "default/claim01"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pod, err := kubeClient.CoreV1().Pods("default").Get(context.TODO(), "web-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pod.Annotations[AnnPodPending]; ok {
+		t.Errorf("expected the annotation removed, got %q", pod.Annotations[AnnPodPending])
+	}
+}
+
+// TestSharedPodInformer runs the watchdog on the same informer factory as the resize controller,
+// which reduces the cached pods to what the in-use check needs.
+func TestSharedPodInformer(t *testing.T) {
+	config := Config{Thresholds: []time.Duration{time.Hour, 2 * time.Hour}, AnnotatePods: true, RestartWorkloads: true}
+	objs := append(testWorkload(), waitingPVC(3*time.Hour), testPV(), testPod(testNow.Add(-48*time.Hour)), testStorageClass(true))
+	kubeClient := fake.NewSimpleClientset(objs...)
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	csiResizer, err := resizer.NewResizerFromClient(csi.NewMockClient(driverName, true, true, false, true, true),
+		15*time.Second, kubeClient, driverName, nil, resizer.MetadataNone)
+	if err != nil {
+		t.Fatalf("Unable to create resizer: %v", err)
+	}
+	controller.NewResizeController(driverName, csiResizer, kubeClient, time.Second, informerFactory,
+		workqueue.DefaultTypedControllerRateLimiter[string](), true /* handleVolumeInUseError */, 2*time.Minute, controller.Options{})
+	w := NewWatchdog(driverName, csiResizer, kubeClient, informerFactory, config, nil /* shardManager */)
+	t.Cleanup(w.queue.ShutDown)
+	w.eventRecorder = record.NewFakeRecorder(10)
+	w.now = func() time.Time { return testNow }
+
+	informerFactory.Start(t.Context().Done())
+	t.Cleanup(informerFactory.Shutdown)
+	if !cache.WaitForCacheSync(t.Context().Done(), w.synced...) {
+		t.Fatal("failed to sync caches")
+	}
+
+	sync := func() {
+		t.Helper()
+		if err := w.sync(context.TODO(), "default/claim01"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	sync()
+	deployment, err := kubeClient.AppsV1().Deployments("default").Get(context.TODO(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := deployment.Spec.Template.Annotations[annRestartedAt]; !ok {
+		t.Errorf("expected the deployment restarted, got annotations %v", deployment.Spec.Template.Annotations)
+	}
+
+	// Wait for the annotated pod in the cache, then the pod must not be patched again.
+	if err := wait.PollUntilContextTimeout(t.Context(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		pod, err := w.podLister.Pods("default").Get("web-1")
+		return err == nil && pod.Annotations[AnnPodPending] == "claim01", nil
+	}); err != nil {
+		t.Fatalf("expected the pod annotated in the cache: %v", err)
+	}
+	kubeClient.ClearActions()
+	sync()
+	for _, action := range kubeClient.Actions() {
+		if action.Matches("patch", "pods") {
+			t.Errorf("expected the annotated pod not patched again, got %v", action)
+		}
+	}
+}
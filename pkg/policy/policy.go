@@ -0,0 +1,178 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// AnnApprovedVAC is set on a PVC by a privileged actor to approve a change to the
+	// VolumeAttributesClass named in its value. Who may set it should be restricted with
+	// RBAC or an admission policy.
+	AnnApprovedVAC = "resizer.csi.k8s.io/approved-vac"
+
+	// AnyVAC matches any VolumeAttributesClass name in a transition graph.
+	AnyVAC = "*"
+)
+
+// Policy holds the operator supplied rules that the controllers consult
+// before they call the CSI driver.
+type Policy struct {
+	// Modify restricts VolumeAttributesClass changes.
+	Modify ModifyPolicy `json:"modify"`
+	// Resize restricts expansions requested with VolumeResizeRequests.
+	Resize ResizePolicy `json:"resize"`
+}
+
+// ModifyPolicy restricts which VolumeAttributesClass changes are carried out.
+type ModifyPolicy struct {
+	// RequireApproval is the default for StorageClasses that don't set their own.
+	RequireApproval bool `json:"requireApproval"`
+	// StorageClasses holds per StorageClass rules, keyed by StorageClass name.
+	StorageClasses map[string]StorageClassModifyPolicy `json:"storageClasses,omitempty"`
+}
+
+// StorageClassModifyPolicy holds the modify rules of a single StorageClass.
+type StorageClassModifyPolicy struct {
+	// RequireApproval overrides ModifyPolicy.RequireApproval when set.
+	RequireApproval *bool `json:"requireApproval,omitempty"`
+	// Transitions maps the current VolumeAttributesClass name ("" for none) to the
+	// names it may be changed to. AnyVAC matches every name on either side.
+	// When nil, all transitions are allowed.
+	Transitions map[string][]string `json:"transitions,omitempty"`
+}
+
+// ResizePolicy restricts the sizes that VolumeResizeRequests may request.
+type ResizePolicy struct {
+	// MaxSize is the default for StorageClasses that don't set their own. Unlimited when nil.
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+	// MaxIncrease limits how much a single request may add to the requested size of a PVC. Unlimited when nil.
+	MaxIncrease *resource.Quantity `json:"maxIncrease,omitempty"`
+	// StorageClasses holds per StorageClass rules, keyed by StorageClass name.
+	StorageClasses map[string]StorageClassResizePolicy `json:"storageClasses,omitempty"`
+}
+
+// StorageClassResizePolicy holds the resize rules of a single StorageClass.
+type StorageClassResizePolicy struct {
+	// MaxSize overrides ResizePolicy.MaxSize when set.
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+	// MaxIncrease overrides ResizePolicy.MaxIncrease when set.
+	MaxIncrease *resource.Quantity `json:"maxIncrease,omitempty"`
+}
+
+// Load reads a Policy from a YAML or JSON file.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file %s: %v", path, err)
+	}
+	p := &Policy{}
+	if err := yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %v", path, err)
+	}
+	return p, nil
+}
+
+// CheckModify returns an empty string if the PVC may be modified from its current to its
+// requested VolumeAttributesClass. Otherwise it returns a reason and a human readable
+// message explaining what is missing. Going back to the current class is always allowed,
+// so that a held or failed change can be cancelled.
+func (p *ModifyPolicy) CheckModify(pvc *v1.PersistentVolumeClaim) (string, string) {
+	if p == nil {
+		return "", ""
+	}
+	from := ptr.Deref(pvc.Status.CurrentVolumeAttributesClassName, "")
+	to := ptr.Deref(pvc.Spec.VolumeAttributesClassName, "")
+	if from == to {
+		return "", ""
+	}
+	scName := ptr.Deref(pvc.Spec.StorageClassName, "")
+
+	requireApproval := p.RequireApproval
+	if sc, ok := p.StorageClasses[scName]; ok {
+		if sc.RequireApproval != nil {
+			requireApproval = *sc.RequireApproval
+		}
+		if !sc.transitionAllowed(from, to) {
+			return ReasonTransitionNotAllowed, fmt.Sprintf("Changing VolumeAttributesClass from %q to %q is not allowed for StorageClass %q.", from, to, scName)
+		}
+	}
+
+	if requireApproval && pvc.Annotations[AnnApprovedVAC] != to {
+		return ReasonApprovalRequired, fmt.Sprintf("Changing VolumeAttributesClass to %q requires annotation %s=%s.", to, AnnApprovedVAC, to)
+	}
+	return "", ""
+}
+
+func (sc StorageClassModifyPolicy) transitionAllowed(from, to string) bool {
+	if sc.Transitions == nil {
+		return true
+	}
+	for _, key := range []string{from, AnyVAC} {
+		targets, ok := sc.Transitions[key]
+		if ok && (slices.Contains(targets, to) || slices.Contains(targets, AnyVAC)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckResize returns an empty string if the requested size of the PVC may be changed to size.
+// Otherwise it returns a reason and a human readable message.
+func (p *ResizePolicy) CheckResize(pvc *v1.PersistentVolumeClaim, size resource.Quantity) (string, string) {
+	if p == nil {
+		return "", ""
+	}
+	scName := ptr.Deref(pvc.Spec.StorageClassName, "")
+	maxSize, maxIncrease := p.MaxSize, p.MaxIncrease
+	if sc, ok := p.StorageClasses[scName]; ok {
+		if sc.MaxSize != nil {
+			maxSize = sc.MaxSize
+		}
+		if sc.MaxIncrease != nil {
+			maxIncrease = sc.MaxIncrease
+		}
+	}
+
+	if maxSize != nil && size.Cmp(*maxSize) > 0 {
+		return ReasonSizeAboveLimit, fmt.Sprintf("Size %s is larger than the maximum size %s of StorageClass %q.", size.String(), maxSize.String(), scName)
+	}
+	if maxIncrease != nil {
+		increase := size.DeepCopy()
+		increase.Sub(pvc.Spec.Resources.Requests[v1.ResourceStorage])
+		if increase.Cmp(*maxIncrease) > 0 {
+			return ReasonIncreaseAboveLimit, fmt.Sprintf("Increase by %s is larger than the maximum increase %s of StorageClass %q.", increase.String(), maxIncrease.String(), scName)
+		}
+	}
+	return "", ""
+}
+
+// Reasons returned by the policy checks.
+const (
+	ReasonApprovalRequired     = "ApprovalRequired"
+	ReasonTransitionNotAllowed = "TransitionNotAllowed"
+	ReasonSizeAboveLimit       = "SizeAboveLimit"
+	ReasonIncreaseAboveLimit   = "IncreaseAboveLimit"
+)
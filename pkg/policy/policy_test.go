@@ -0,0 +1,215 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+const testPolicy = `
+modify:
+  requireApproval: false
+  storageClasses:
+    premium:
+      requireApproval: true
+      transitions:
+        "": ["silver"]
+        silver: ["gold"]
+        gold: ["*"]
+    open:
+      requireApproval: false
+`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(testPolicy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sc, ok := p.Modify.StorageClasses["premium"]
+	if !ok || !ptr.Deref(sc.RequireApproval, false) || len(sc.Transitions) != 3 {
+		t.Errorf("unexpected premium policy: %+v", sc)
+	}
+
+	if err := os.WriteFile(path, []byte("modify:\n  unknownField: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Errorf("expected error for unknown field")
+	}
+}
+
+func TestCheckModify(t *testing.T) {
+	p := &ModifyPolicy{
+		StorageClasses: map[string]StorageClassModifyPolicy{
+			"premium": {
+				RequireApproval: ptr.To(true),
+				Transitions: map[string][]string{
+					"":       {"silver"},
+					"silver": {"gold"},
+					"gold":   {AnyVAC},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		sc             string
+		from, to       string
+		approvedVAC    string
+		expectedReason string
+	}{
+		{
+			name: "StorageClass without rules",
+			sc:   "standard",
+			from: "silver",
+			to:   "bronze",
+		},
+		{
+			name:           "allowed transition without approval",
+			sc:             "premium",
+			from:           "silver",
+			to:             "gold",
+			expectedReason: ReasonApprovalRequired,
+		},
+		{
+			name:           "allowed transition approved for another class",
+			sc:             "premium",
+			from:           "silver",
+			to:             "gold",
+			approvedVAC:    "silver",
+			expectedReason: ReasonApprovalRequired,
+		},
+		{
+			name:        "allowed transition with approval",
+			sc:          "premium",
+			from:        "silver",
+			to:          "gold",
+			approvedVAC: "gold",
+		},
+		{
+			name:        "first transition",
+			sc:          "premium",
+			from:        "",
+			to:          "silver",
+			approvedVAC: "silver",
+		},
+		{
+			name:        "wildcard target",
+			sc:          "premium",
+			from:        "gold",
+			to:          "bronze",
+			approvedVAC: "bronze",
+		},
+		{
+			name: "back to the current class",
+			sc:   "premium",
+			from: "silver",
+			to:   "silver",
+		},
+		{
+			name:           "transition not in graph",
+			sc:             "premium",
+			from:           "silver",
+			to:             "bronze",
+			approvedVAC:    "bronze",
+			expectedReason: ReasonTransitionNotAllowed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pvc := &v1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+				Spec: v1.PersistentVolumeClaimSpec{
+					StorageClassName:          &test.sc,
+					VolumeAttributesClassName: &test.to,
+				},
+				Status: v1.PersistentVolumeClaimStatus{
+					CurrentVolumeAttributesClassName: &test.from,
+				},
+			}
+			if test.approvedVAC != "" {
+				pvc.Annotations = map[string]string{AnnApprovedVAC: test.approvedVAC}
+			}
+			reason, msg := p.CheckModify(pvc)
+			if reason != test.expectedReason {
+				t.Errorf("expected reason %q, got %q (%s)", test.expectedReason, reason, msg)
+			}
+			var nilPolicy *ModifyPolicy
+			if reason, _ := nilPolicy.CheckModify(pvc); reason != "" {
+				t.Errorf("expected nil policy to allow modification, got reason %q", reason)
+			}
+		})
+	}
+}
+
+func TestCheckResize(t *testing.T) {
+	p := &ResizePolicy{
+		MaxSize: ptr.To(resource.MustParse("1Ti")),
+		StorageClasses: map[string]StorageClassResizePolicy{
+			"small": {
+				MaxSize:     ptr.To(resource.MustParse("100Gi")),
+				MaxIncrease: ptr.To(resource.MustParse("10Gi")),
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		sc             string
+		size           string
+		expectedReason string
+	}{
+		{name: "below default limit", sc: "standard", size: "500Gi"},
+		{name: "above default limit", sc: "standard", size: "2Ti", expectedReason: ReasonSizeAboveLimit},
+		{name: "within StorageClass limits", sc: "small", size: "60Gi"},
+		{name: "above StorageClass limit", sc: "small", size: "200Gi", expectedReason: ReasonSizeAboveLimit},
+		{name: "increase too large", sc: "small", size: "80Gi", expectedReason: ReasonIncreaseAboveLimit},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pvc := &v1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+				Spec: v1.PersistentVolumeClaimSpec{
+					StorageClassName: &test.sc,
+					Resources: v1.VolumeResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("50Gi")},
+					},
+				},
+			}
+			reason, msg := p.CheckResize(pvc, resource.MustParse(test.size))
+			if reason != test.expectedReason {
+				t.Errorf("expected reason %q, got %q (%s)", test.expectedReason, reason, msg)
+			}
+			var nilPolicy *ResizePolicy
+			if reason, _ := nilPolicy.CheckResize(pvc, resource.MustParse(test.size)); reason != "" {
+				t.Errorf("expected nil policy to allow resize, got reason %q", reason)
+			}
+		})
+	}
+}
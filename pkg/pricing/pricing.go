@@ -0,0 +1,180 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// AnnEstimatedMonthlyCostDelta is set on a PVC before a resize or modify operation is carried
+	// out, with the estimated change of the monthly cost of the volume, e.g. "+12.50 USD".
+	AnnEstimatedMonthlyCostDelta = "resizer.csi.k8s.io/estimated-monthly-cost-delta"
+
+	OperationResize = "resize"
+	OperationModify = "modify"
+)
+
+var costDelta = metrics.NewHistogramVec(
+	&metrics.HistogramOpts{
+		Subsystem:      "csi_resizer",
+		Name:           "estimated_monthly_cost_delta",
+		Help:           "Estimated change of the monthly cost of volumes by resize and modify operations, in the currency of the price table.",
+		Buckets:        []float64{-1000, -100, -10, -1, 0, 1, 10, 100, 1000},
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"operation", "storage_class", "currency"},
+)
+
+func init() {
+	legacyregistry.MustRegister(costDelta)
+}
+
+// Volume describes what a volume is priced by.
+type Volume struct {
+	StorageClass                    string
+	StorageClassParameters          map[string]string
+	VolumeAttributesClass           string
+	VolumeAttributesClassParameters map[string]string
+	Size                            resource.Quantity
+}
+
+// Pricer returns the monthly cost of a volume.
+type Pricer interface {
+	// MonthlyCost returns the monthly cost of the volume and the currency it is expressed in.
+	MonthlyCost(v Volume) (float64, string, error)
+}
+
+// Estimate is the estimated change of the monthly cost of a volume.
+type Estimate struct {
+	Operation    string
+	StorageClass string
+	Delta        float64
+	Currency     string
+}
+
+// String formats the estimate for the AnnEstimatedMonthlyCostDelta annotation.
+func (e Estimate) String() string {
+	return fmt.Sprintf("%+.2f %s", e.Delta, e.Currency)
+}
+
+// Estimator estimates the cost impact of resize and modify operations on PVCs.
+type Estimator struct {
+	pricer    Pricer
+	scLister  storagelisters.StorageClassLister
+	vacLister storagelisters.VolumeAttributesClassLister
+}
+
+// NewEstimator returns an Estimator that looks up StorageClass and VolumeAttributesClass parameters
+// with the given listers. vacLister may be nil when VolumeAttributesClasses are not available.
+func NewEstimator(pricer Pricer, scLister storagelisters.StorageClassLister, vacLister storagelisters.VolumeAttributesClassLister) *Estimator {
+	return &Estimator{pricer: pricer, scLister: scLister, vacLister: vacLister}
+}
+
+// EstimateResize returns the cost change of resizing the volume of pvc from one size to another.
+func (e *Estimator) EstimateResize(pvc *v1.PersistentVolumeClaim, from, to resource.Quantity) (Estimate, error) {
+	vacName := ptr.Deref(pvc.Status.CurrentVolumeAttributesClassName, "")
+	oldVolume, err := e.volume(pvc, vacName, from)
+	if err != nil {
+		return Estimate{}, err
+	}
+	newVolume := oldVolume
+	newVolume.Size = to
+	return e.estimate(OperationResize, oldVolume, newVolume)
+}
+
+// EstimateModify returns the cost change of changing the VolumeAttributesClass of pvc.
+func (e *Estimator) EstimateModify(pvc *v1.PersistentVolumeClaim, fromVAC, toVAC string) (Estimate, error) {
+	size := pvc.Status.Capacity[v1.ResourceStorage]
+	oldVolume, err := e.volume(pvc, fromVAC, size)
+	if err != nil {
+		return Estimate{}, err
+	}
+	newVolume, err := e.volume(pvc, toVAC, size)
+	if err != nil {
+		return Estimate{}, err
+	}
+	return e.estimate(OperationModify, oldVolume, newVolume)
+}
+
+func (e *Estimator) estimate(operation string, oldVolume, newVolume Volume) (Estimate, error) {
+	oldCost, currency, err := e.pricer.MonthlyCost(oldVolume)
+	if err != nil {
+		return Estimate{}, err
+	}
+	newCost, _, err := e.pricer.MonthlyCost(newVolume)
+	if err != nil {
+		return Estimate{}, err
+	}
+	// Round to cents so that floating point noise does not change the annotation.
+	delta := math.Round((newCost-oldCost)*100) / 100
+	return Estimate{Operation: operation, StorageClass: newVolume.StorageClass, Delta: delta, Currency: currency}, nil
+}
+
+func (e *Estimator) volume(pvc *v1.PersistentVolumeClaim, vacName string, size resource.Quantity) (Volume, error) {
+	v := Volume{
+		StorageClass:          ptr.Deref(pvc.Spec.StorageClassName, ""),
+		VolumeAttributesClass: vacName,
+		Size:                  size,
+	}
+	if v.StorageClass != "" {
+		sc, err := e.scLister.Get(v.StorageClass)
+		if err != nil {
+			return v, fmt.Errorf("failed to get StorageClass %s: %v", v.StorageClass, err)
+		}
+		v.StorageClassParameters = sc.Parameters
+	}
+	if v.VolumeAttributesClass != "" && e.vacLister != nil {
+		vac, err := e.vacLister.Get(v.VolumeAttributesClass)
+		if err != nil {
+			return v, fmt.Errorf("failed to get VolumeAttributesClass %s: %v", v.VolumeAttributesClass, err)
+		}
+		v.VolumeAttributesClassParameters = vac.Parameters
+	}
+	return v, nil
+}
+
+// Annotate sets AnnEstimatedMonthlyCostDelta on pvc to the estimate, unless it is already set to it.
+// Only estimates that change the annotation are counted in the metrics, so that retries of the same
+// operation are not counted again.
+func Annotate(kubeClient kubernetes.Interface, pvc *v1.PersistentVolumeClaim, estimate Estimate) (*v1.PersistentVolumeClaim, error) {
+	value := estimate.String()
+	if pvc.Annotations[AnnEstimatedMonthlyCostDelta] == value {
+		return pvc, nil
+	}
+	newPVC := pvc.DeepCopy()
+	if newPVC.Annotations == nil {
+		newPVC.Annotations = map[string]string{}
+	}
+	newPVC.Annotations[AnnEstimatedMonthlyCostDelta] = value
+	updatedPVC, err := util.PatchClaimMetadata(kubeClient, pvc, newPVC, false)
+	if err != nil {
+		return pvc, err
+	}
+	costDelta.WithLabelValues(estimate.Operation, estimate.StorageClass, estimate.Currency).Observe(estimate.Delta)
+	return updatedPVC, nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+)
+
+const testPriceTable = `
+currency: USD
+rules:
+- storageClass: premium
+  perGiBMonth: 0.10
+- volumeAttributesClassParameters:
+    iops: "16000"
+  perMonth: 65
+`
+
+func TestParsePriceTable(t *testing.T) {
+	if _, err := ParsePriceTable([]byte(testPriceTable)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := ParsePriceTable([]byte("rules: []")); err == nil {
+		t.Errorf("expected error for missing currency")
+	}
+	if _, err := ParsePriceTable([]byte("currency: USD\nunknown: 1")); err == nil {
+		t.Errorf("expected error for unknown field")
+	}
+}
+
+func testEstimator(t *testing.T) *Estimator {
+	table, err := ParsePriceTable([]byte(testPriceTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	scIndexer.Add(&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "premium"}})
+	vacIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	vacIndexer.Add(&storagev1.VolumeAttributesClass{ObjectMeta: metav1.ObjectMeta{Name: "silver"}, Parameters: map[string]string{"iops": "3000"}})
+	vacIndexer.Add(&storagev1.VolumeAttributesClass{ObjectMeta: metav1.ObjectMeta{Name: "gold"}, Parameters: map[string]string{"iops": "16000"}})
+	return NewEstimator(table, storagelisters.NewStorageClassLister(scIndexer), storagelisters.NewVolumeAttributesClassLister(vacIndexer))
+}
+
+func testPVC() *v1.PersistentVolumeClaim {
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec:       v1.PersistentVolumeClaimSpec{StorageClassName: ptr.To("premium")},
+		Status: v1.PersistentVolumeClaimStatus{
+			Capacity:                         v1.ResourceList{v1.ResourceStorage: resource.MustParse("10Gi")},
+			CurrentVolumeAttributesClassName: ptr.To("silver"),
+		},
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	e := testEstimator(t)
+	tests := []struct {
+		name     string
+		estimate func() (Estimate, error)
+		expected string
+	}{
+		{
+			name: "resize",
+			estimate: func() (Estimate, error) {
+				return e.EstimateResize(testPVC(), resource.MustParse("10Gi"), resource.MustParse("135Gi"))
+			},
+			expected: "+12.50 USD",
+		},
+		{
+			name:     "modify to more expensive class",
+			estimate: func() (Estimate, error) { return e.EstimateModify(testPVC(), "silver", "gold") },
+			expected: "+65.00 USD",
+		},
+		{
+			name:     "modify to cheaper class",
+			estimate: func() (Estimate, error) { return e.EstimateModify(testPVC(), "gold", "silver") },
+			expected: "-65.00 USD",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			estimate, err := test.estimate()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if estimate.String() != test.expected {
+				t.Errorf("expected estimate %s, got %s", test.expected, estimate)
+			}
+		})
+	}
+
+	if _, err := e.EstimateModify(testPVC(), "silver", "missing"); err == nil {
+		t.Errorf("expected error for missing VolumeAttributesClass")
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	pvc := testPVC()
+	client := fake.NewSimpleClientset(pvc)
+	estimate := Estimate{Operation: OperationModify, StorageClass: "premium", Delta: 65, Currency: "USD"}
+
+	updated, err := Annotate(client, pvc, estimate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := updated.Annotations[AnnEstimatedMonthlyCostDelta]; got != "+65.00 USD" {
+		t.Errorf("expected annotation +65.00 USD, got %q", got)
+	}
+
+	actions := len(client.Actions())
+	if _, err := Annotate(client, updated, estimate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.Actions()) != actions {
+		t.Errorf("expected no API call for unchanged estimate")
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// PriceTableKey is the key of the price table in the ConfigMap.
+const PriceTableKey = "prices.yaml"
+
+// PriceTable is a static Pricer. The monthly cost of a volume is the sum of all rules that match it.
+type PriceTable struct {
+	Currency string      `json:"currency"`
+	Rules    []PriceRule `json:"rules"`
+}
+
+// PriceRule prices volumes that match all of its non-empty selectors.
+type PriceRule struct {
+	StorageClass                    string            `json:"storageClass,omitempty"`
+	StorageClassParameters          map[string]string `json:"storageClassParameters,omitempty"`
+	VolumeAttributesClass           string            `json:"volumeAttributesClass,omitempty"`
+	VolumeAttributesClassParameters map[string]string `json:"volumeAttributesClassParameters,omitempty"`
+	// PerGiBMonth is the monthly price of a GiB of capacity.
+	PerGiBMonth float64 `json:"perGiBMonth,omitempty"`
+	// PerMonth is a fixed monthly price.
+	PerMonth float64 `json:"perMonth,omitempty"`
+}
+
+// ParsePriceTable parses a YAML or JSON price table.
+func ParsePriceTable(data []byte) (*PriceTable, error) {
+	t := &PriceTable{}
+	if err := yaml.UnmarshalStrict(data, t); err != nil {
+		return nil, fmt.Errorf("failed to parse price table: %v", err)
+	}
+	if t.Currency == "" {
+		return nil, errors.New("price table does not set a currency")
+	}
+	return t, nil
+}
+
+func (t *PriceTable) MonthlyCost(v Volume) (float64, string, error) {
+	gib := float64(v.Size.Value()) / (1 << 30)
+	cost := 0.0
+	for _, r := range t.Rules {
+		if r.matches(v) {
+			cost += r.PerMonth + r.PerGiBMonth*gib
+		}
+	}
+	return cost, t.Currency, nil
+}
+
+func (r PriceRule) matches(v Volume) bool {
+	if r.StorageClass != "" && r.StorageClass != v.StorageClass {
+		return false
+	}
+	if r.VolumeAttributesClass != "" && r.VolumeAttributesClass != v.VolumeAttributesClass {
+		return false
+	}
+	return subset(r.StorageClassParameters, v.StorageClassParameters) &&
+		subset(r.VolumeAttributesClassParameters, v.VolumeAttributesClassParameters)
+}
+
+func subset(selector, parameters map[string]string) bool {
+	for k, val := range selector {
+		if got, ok := parameters[k]; !ok || got != val {
+			return false
+		}
+	}
+	return true
+}
+
+// ConfigMapPricer is a Pricer backed by a PriceTable stored in a ConfigMap under PriceTableKey.
+// It follows changes of the ConfigMap.
+type ConfigMapPricer struct {
+	namespace string
+	name      string
+	factory   informers.SharedInformerFactory
+	synced    cache.InformerSynced
+	table     atomic.Pointer[PriceTable]
+}
+
+// NewConfigMapPricer returns a ConfigMapPricer that watches only the named ConfigMap.
+func NewConfigMapPricer(kubeClient kubernetes.Interface, namespace, name string, resyncPeriod time.Duration) (*ConfigMapPricer, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(kubeClient, resyncPeriod,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	p := &ConfigMapPricer{namespace: namespace, name: name, factory: factory}
+	informer := factory.Core().V1().ConfigMaps().Informer()
+	p.synced = informer.HasSynced
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    p.update,
+		UpdateFunc: func(_, obj interface{}) { p.update(obj) },
+		DeleteFunc: func(interface{}) { p.table.Store(nil) },
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Run starts watching the ConfigMap.
+func (p *ConfigMapPricer) Run(ctx context.Context) {
+	p.factory.Start(ctx.Done())
+	cache.WaitForCacheSync(ctx.Done(), p.synced)
+}
+
+func (p *ConfigMapPricer) update(obj interface{}) {
+	cm, ok := obj.(*v1.ConfigMap)
+	if !ok {
+		return
+	}
+	table, err := ParsePriceTable([]byte(cm.Data[PriceTableKey]))
+	if err != nil {
+		klog.ErrorS(err, "Ignoring invalid price table", "configMap", klog.KObj(cm))
+		return
+	}
+	klog.V(2).InfoS("Loaded price table", "configMap", klog.KObj(cm), "rules", len(table.Rules))
+	p.table.Store(table)
+}
+
+func (p *ConfigMapPricer) MonthlyCost(v Volume) (float64, string, error) {
+	table := p.table.Load()
+	if table == nil {
+		return 0, "", fmt.Errorf("no valid price table in ConfigMap %s/%s", p.namespace, p.name)
+	}
+	return table.MonthlyCost(v)
+}
@@ -20,22 +20,38 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"strconv"
+	"strings"
 	"time"
 
 	csilib "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-csi/csi-lib-utils/accessmodes"
 	"github.com/kubernetes-csi/csi-lib-utils/connection"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/credentials"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/csi"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	grpcmetadata "google.golang.org/grpc/metadata"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	csitrans "k8s.io/csi-translation-lib"
 	"k8s.io/klog/v2"
 )
 
+// MetadataMode selects how the metadata of the PV and PVC is passed to ControllerExpandVolume,
+// which has no parameters of its own.
+type MetadataMode string
+
+const (
+	// MetadataNone passes no metadata.
+	MetadataNone MetadataMode = ""
+	// MetadataGRPC passes the metadata as gRPC request headers.
+	MetadataGRPC MetadataMode = "grpc-metadata"
+	// MetadataSecrets adds the metadata to the secrets of the request.
+	MetadataSecrets MetadataMode = "secrets"
+)
+
 var (
 	controllerServiceNotSupportErr = errors.New("CSI driver does not support controller service")
 	ResizeNotSupportErr            = errors.New("CSI driver neither supports controller resize nor node resize")
@@ -45,7 +61,15 @@ func NewResizerFromClient(
 	csiClient csi.Client,
 	timeout time.Duration,
 	k8sClient kubernetes.Interface,
-	driverName string) (Resizer, error) {
+	driverName string,
+	credentialProvider credentials.Provider,
+	metadataMode MetadataMode) (Resizer, error) {
+
+	switch metadataMode {
+	case MetadataNone, MetadataGRPC, MetadataSecrets:
+	default:
+		return nil, fmt.Errorf("unknown metadata mode %q", metadataMode)
+	}
 
 	supportControllerService, err := supportsPluginControllerService(csiClient, timeout)
 	if err != nil {
@@ -78,12 +102,18 @@ func NewResizerFromClient(
 		return nil, fmt.Errorf("failed to check if plugin supports the SINGLE_NODE_MULTI_WRITER capability: %v", err)
 	}
 
+	if credentialProvider == nil {
+		credentialProvider = credentials.NewAPIProvider(k8sClient)
+	}
+
 	return &csiResizer{
 		name:    driverName,
 		client:  csiClient,
 		timeout: timeout,
 
-		k8sClient: k8sClient,
+		k8sClient:    k8sClient,
+		credentials:  credentialProvider,
+		metadataMode: metadataMode,
 	}, nil
 }
 
@@ -92,7 +122,9 @@ type csiResizer struct {
 	client  csi.Client
 	timeout time.Duration
 
-	k8sClient kubernetes.Interface
+	k8sClient    kubernetes.Interface
+	credentials  credentials.Provider
+	metadataMode MetadataMode
 }
 
 func (r *csiResizer) Name() string {
@@ -129,7 +161,7 @@ func (r *csiResizer) DriverSupportsControlPlaneExpansion() bool {
 
 // Resize resizes the persistence volume given request size
 // It supports both CSI volume and migrated in-tree volume
-func (r *csiResizer) Resize(pv *v1.PersistentVolume, requestSize resource.Quantity) (resource.Quantity, bool, error) {
+func (r *csiResizer) Resize(pv *v1.PersistentVolume, requestSize resource.Quantity, metadata map[string]string) (resource.Quantity, bool, error) {
 	oldSize := pv.Spec.Capacity[v1.ResourceStorage]
 
 	var volumeID string
@@ -168,7 +200,7 @@ func (r *csiResizer) Resize(pv *v1.PersistentVolume, requestSize resource.Quanti
 	secreRef := source.ControllerExpandSecretRef
 	if secreRef != nil {
 		var err error
-		secrets, err = getCredentials(r.k8sClient, secreRef)
+		secrets, err = r.credentials.GetCredentials(context.TODO(), secreRef)
 		if err != nil {
 			return oldSize, false, err
 		}
@@ -181,6 +213,18 @@ func (r *csiResizer) Resize(pv *v1.PersistentVolume, requestSize resource.Quanti
 
 	ctx, cancel := timeoutCtx(r.timeout)
 	resizeCtx := context.WithValue(ctx, connection.AdditionalInfoKey, connection.AdditionalInfo{Migrated: strconv.FormatBool(migrated)})
+	if len(metadata) > 0 {
+		switch r.metadataMode {
+		case MetadataGRPC:
+			resizeCtx = grpcmetadata.NewOutgoingContext(resizeCtx, headers(metadata))
+		case MetadataSecrets:
+			secrets = maps.Clone(secrets)
+			if secrets == nil {
+				secrets = make(map[string]string, len(metadata))
+			}
+			maps.Copy(secrets, metadata)
+		}
+	}
 
 	defer cancel()
 	newSizeBytes, nodeResizeRequired, err := r.client.Expand(resizeCtx, volumeID, requestSize.Value(), secrets, capability)
@@ -244,6 +288,31 @@ func supportsPluginControllerService(client csi.Client, timeout time.Duration) (
 	return client.SupportsPluginControllerService(ctx)
 }
 
+// headers converts metadata to gRPC headers. Header names may only contain lowercase letters,
+// digits, '-', '_' and '.', other characters of the keys are replaced by '-'. Values that are
+// not printable ASCII cannot be sent as text headers and are skipped.
+func headers(metadata map[string]string) grpcmetadata.MD {
+	md := make(grpcmetadata.MD, len(metadata))
+	for key, value := range metadata {
+		if strings.IndexFunc(value, func(r rune) bool { return r < 0x20 || r > 0x7e }) >= 0 {
+			klog.V(4).InfoS("Skipping metadata that is not printable ASCII", "key", key)
+			continue
+		}
+		name := strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+				return r
+			case r >= 'A' && r <= 'Z':
+				return r - 'A' + 'a'
+			default:
+				return '-'
+			}
+		}, key)
+		md[name] = []string{value}
+	}
+	return md
+}
+
 func supportsControllerResize(client csi.Client, timeout time.Duration) (bool, error) {
 	ctx, cancel := timeoutCtx(timeout)
 	defer cancel()
@@ -265,20 +334,3 @@ func supportsControllerSingleNodeMultiWriter(client csi.Client, timeout time.Dur
 func timeoutCtx(timeout time.Duration) (context.Context, context.CancelFunc) {
 	return context.WithTimeout(context.Background(), timeout)
 }
-
-func getCredentials(k8sClient kubernetes.Interface, ref *v1.SecretReference) (map[string]string, error) {
-	if ref == nil {
-		return nil, nil
-	}
-
-	secret, err := k8sClient.CoreV1().Secrets(ref.Namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("error getting secret %s in namespace %s: %v", ref.Name, ref.Namespace, err)
-	}
-
-	credentials := map[string]string{}
-	for key, value := range secret.Data {
-		credentials[key] = string(value)
-	}
-	return credentials, nil
-}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	csilib "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/credentials"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/csi"
 	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
 	v1 "k8s.io/api/core/v1"
@@ -75,7 +76,7 @@ func TestNewResizer(t *testing.T) {
 		client := csi.NewMockClient("mock", c.SupportsNodeResize, c.SupportsControllerResize, false, c.SupportsPluginControllerService, c.SupportsControllerSingleNodeMultiWriter)
 		driverName := "mock-driver"
 		k8sClient := fake.NewSimpleClientset()
-		resizer, err := NewResizerFromClient(client, 0, k8sClient, driverName)
+		resizer, err := NewResizerFromClient(client, 0, k8sClient, driverName, nil, MetadataNone)
 		if err != c.Error {
 			t.Errorf("Case %d: Unexpected error: wanted %v, got %v", i, c.Error, err)
 		}
@@ -111,12 +112,13 @@ func TestResizeWithSecret(t *testing.T) {
 		k8sClient := fake.NewSimpleClientset(secret)
 		pv := makeTestPV("test-csi", 2, "ebs-csi", "vol-abcde", tc.hasExpansionSecret)
 		csiResizer := &csiResizer{
-			name:      "ebs-csi",
-			client:    client,
-			timeout:   10 * time.Second,
-			k8sClient: k8sClient,
+			name:        "ebs-csi",
+			client:      client,
+			timeout:     10 * time.Second,
+			k8sClient:   k8sClient,
+			credentials: credentials.NewAPIProvider(k8sClient),
 		}
-		_, _, err := csiResizer.Resize(pv, resource.MustParse("10Gi"))
+		_, _, err := csiResizer.Resize(pv, resource.MustParse("10Gi"), nil)
 		if err != nil {
 			t.Errorf("unexpected error while expansion : %v", err)
 		}
@@ -132,6 +134,69 @@ func TestResizeWithSecret(t *testing.T) {
 
 }
 
+func TestResizeWithMetadata(t *testing.T) {
+	metadata := map[string]string{
+		util.PVCNameKey: "data",
+		util.PVCLabelKeyPrefix + "example.com/App": "db",
+		util.PVCAnnotationKeyPrefix + "note":       "caf\u00e9",
+	}
+	tests := []struct {
+		name          string
+		mode          MetadataMode
+		expectSecrets map[string]string
+		expectHeaders map[string][]string
+	}{
+		{
+			name:          "none",
+			mode:          MetadataNone,
+			expectSecrets: map[string]string{"mykey": "mydata"},
+		},
+		{
+			name:          "grpc-metadata",
+			mode:          MetadataGRPC,
+			expectSecrets: map[string]string{"mykey": "mydata"},
+			expectHeaders: map[string][]string{
+				"csi.storage.k8s.io-pvc-name":                  {"data"},
+				"csi.storage.k8s.io-pvc-label-example.com-app": {"db"},
+			},
+		},
+		{
+			name: "secrets",
+			mode: MetadataSecrets,
+			expectSecrets: map[string]string{
+				"mykey":         "mydata",
+				util.PVCNameKey: "data",
+				util.PVCLabelKeyPrefix + "example.com/App": "db",
+				util.PVCAnnotationKeyPrefix + "note":       "caf\u00e9",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := csi.NewMockClient("mock", true, true, false, true, true)
+			k8sClient := fake.NewSimpleClientset(makeSecret("some-secret", "secret-namespace"))
+			resizer, err := NewResizerFromClient(client, 10*time.Second, k8sClient, "ebs-csi", nil, tc.mode)
+			if err != nil {
+				t.Fatalf("unexpected error creating resizer: %v", err)
+			}
+			pv := makeTestPV("test-csi", 2, "ebs-csi", "vol-abcde", true)
+			if _, _, err := resizer.Resize(pv, resource.MustParse("10Gi"), metadata); err != nil {
+				t.Fatalf("unexpected error while expansion: %v", err)
+			}
+			if secrets := client.GetSecrets(); !reflect.DeepEqual(secrets, tc.expectSecrets) {
+				t.Errorf("expected secrets %v, got %v", tc.expectSecrets, secrets)
+			}
+			if headers := client.GetMetadata(); len(headers) != len(tc.expectHeaders) || (len(headers) > 0 && !reflect.DeepEqual(map[string][]string(headers), tc.expectHeaders)) {
+				t.Errorf("expected headers %v, got %v", tc.expectHeaders, headers)
+			}
+		})
+	}
+
+	if _, err := NewResizerFromClient(csi.NewMockClient("mock", true, true, false, true, true), 0, fake.NewSimpleClientset(), "ebs-csi", nil, "labels"); err == nil {
+		t.Errorf("expected error for unknown metadata mode")
+	}
+}
+
 func TestResizeMigratedPV(t *testing.T) {
 	testCases := []struct {
 		name               string
@@ -167,14 +232,14 @@ func TestResizeMigratedPV(t *testing.T) {
 			client := csi.NewMockClient(driverName, true, true, false, true, true)
 			client.SetCheckMigratedLabel()
 			k8sClient := fake.NewSimpleClientset()
-			resizer, err := NewResizerFromClient(client, 0, k8sClient, driverName)
+			resizer, err := NewResizerFromClient(client, 0, k8sClient, driverName, nil, MetadataNone)
 			if err != nil {
 				t.Fatalf("Failed to create resizer: %v", err)
 			}
 
 			pv := tc.pv
 			expectedSize := quantityGB(2)
-			newSize, nodeResizeRequired, err := resizer.Resize(pv, expectedSize)
+			newSize, nodeResizeRequired, err := resizer.Resize(pv, expectedSize, nil)
 
 			if tc.err != nil {
 				if err == nil {
@@ -435,7 +500,7 @@ func TestCanSupport(t *testing.T) {
 			driverName := tc.driverName
 			client := csi.NewMockClient(driverName, true, true, false, true, true)
 			k8sClient := fake.NewSimpleClientset()
-			resizer, err := NewResizerFromClient(client, 0, k8sClient, driverName)
+			resizer, err := NewResizerFromClient(client, 0, k8sClient, driverName, nil, MetadataNone)
 			if err != nil {
 				t.Fatalf("Failed to create resizer: %v", err)
 			}
@@ -30,6 +30,7 @@ type Resizer interface {
 	// CanSupport returns true if resizer supports resize operation of this PV
 	// with its corresponding PVC.
 	CanSupport(pv *v1.PersistentVolume, pvc *v1.PersistentVolumeClaim) bool
-	// Resize executes the resize operation of this PV.
-	Resize(pv *v1.PersistentVolume, requestSize resource.Quantity) (newSize resource.Quantity, fsResizeRequired bool, err error)
+	// Resize executes the resize operation of this PV. metadata describes the PV and its PVC
+	// and is passed to the driver if the resizer is configured to do so, it may be nil.
+	Resize(pv *v1.PersistentVolume, requestSize resource.Quantity, metadata map[string]string) (newSize resource.Quantity, fsResizeRequired bool, err error)
 }
@@ -60,6 +60,6 @@ func (r *trivialResizer) DriverSupportsControlPlaneExpansion() bool {
 	return false
 }
 
-func (r *trivialResizer) Resize(pv *v1.PersistentVolume, requestSize resource.Quantity) (newSize resource.Quantity, fsResizeRequired bool, err error) {
+func (r *trivialResizer) Resize(pv *v1.PersistentVolume, requestSize resource.Quantity, metadata map[string]string) (newSize resource.Quantity, fsResizeRequired bool, err error) {
 	return requestSize, true, nil
 }
@@ -0,0 +1,145 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resizerctl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Action is a recovery step. It is checked and described before it is applied,
+// so that operators can review it first.
+type Action struct {
+	// Description says what Apply changes.
+	Description string
+	apply       func() error
+}
+
+// Apply carries out the action.
+func (a *Action) Apply() error {
+	return a.apply()
+}
+
+// ResetSize returns an action that lowers the requested size of a PVC to size, to recover
+// from an expansion the driver reported as infeasible. size must not be smaller than the
+// current capacity of the PVC. Unless force is set, the expansion must have failed as infeasible.
+func ResetSize(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string, size resource.Quantity, force bool) (*Action, error) {
+	pvc, err := kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	resizeStatus := pvc.Status.AllocatedResourceStatuses[v1.ResourceStorage]
+	if !force && resizeStatus != v1.PersistentVolumeClaimControllerResizeInfeasible && resizeStatus != v1.PersistentVolumeClaimNodeResizeInfeasible {
+		return nil, fmt.Errorf("resize status of PVC %s/%s is %q, not infeasible; use --force to reset the size anyway", namespace, name, resizeStatus)
+	}
+	requested := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+	capacity := pvc.Status.Capacity[v1.ResourceStorage]
+	if size.Cmp(requested) >= 0 {
+		return nil, fmt.Errorf("size %s is not smaller than the requested size %s", size.String(), requested.String())
+	}
+	if size.Cmp(capacity) < 0 {
+		return nil, fmt.Errorf("size %s is smaller than the capacity %s of the volume", size.String(), capacity.String())
+	}
+
+	newPVC := pvc.DeepCopy()
+	newPVC.Spec.Resources.Requests[v1.ResourceStorage] = size
+	return &Action{
+		Description: fmt.Sprintf("Change the requested size of PVC %s/%s from %s to %s", namespace, name, requested.String(), size.String()),
+		apply: func() error {
+			_, err := util.PatchClaimMetadata(kubeClient, pvc, newPVC, true)
+			return err
+		},
+	}, nil
+}
+
+// ClearPreResizeCapacity returns an action that removes a stale pre-resize capacity annotation from a PV.
+// Unless force is set, the expansion of the volume must have finished on the node, i.e. the capacity
+// of the bound PVC must have reached the capacity of the PV.
+func ClearPreResizeCapacity(ctx context.Context, kubeClient kubernetes.Interface, pvName string, force bool) (*Action, error) {
+	pv, err := kubeClient.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	preResizeCapacity, ok := pv.Annotations[util.AnnPreResizeCapacity]
+	if !ok {
+		return nil, fmt.Errorf("PV %s has no %s annotation", pvName, util.AnnPreResizeCapacity)
+	}
+	if !force {
+		if pv.Spec.ClaimRef == nil {
+			return nil, fmt.Errorf("PV %s is not bound; use --force to remove the annotation anyway", pvName)
+		}
+		pvc, err := kubeClient.CoreV1().PersistentVolumeClaims(pv.Spec.ClaimRef.Namespace).Get(ctx, pv.Spec.ClaimRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		pvcCapacity, pvCapacity := pvc.Status.Capacity[v1.ResourceStorage], pv.Spec.Capacity[v1.ResourceStorage]
+		if pvcCapacity.Cmp(pvCapacity) < 0 {
+			return nil, fmt.Errorf("capacity %s of PVC %s/%s has not reached capacity %s of PV %s, node expansion is not finished; use --force to remove the annotation anyway",
+				pvcCapacity.String(), pvc.Namespace, pvc.Name, pvCapacity.String(), pvName)
+		}
+	}
+
+	newPV := pv.DeepCopy()
+	delete(newPV.Annotations, util.AnnPreResizeCapacity)
+	return &Action{
+		Description: fmt.Sprintf("Remove annotation %s=%s from PV %s", util.AnnPreResizeCapacity, preResizeCapacity, pvName),
+		apply: func() error {
+			_, err := util.PatchPersistentVolume(kubeClient, pv, newPV)
+			return err
+		},
+	}, nil
+}
+
+// ResetModifyStatus returns an action that clears ModifyVolumeStatus and the modify conditions of a PVC,
+// so that the modify controller starts over. Unless force is set, no modification may be in progress.
+func ResetModifyStatus(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string, force bool) (*Action, error) {
+	pvc, err := kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	status := pvc.Status.ModifyVolumeStatus
+	if status == nil {
+		return nil, fmt.Errorf("PVC %s/%s has no modify volume status", namespace, name)
+	}
+	if !force && status.Status == v1.PersistentVolumeClaimModifyVolumeInProgress {
+		return nil, fmt.Errorf("modification of PVC %s/%s to %q is in progress; use --force to reset it anyway", namespace, name, status.TargetVolumeAttributesClassName)
+	}
+
+	newPVC := pvc.DeepCopy()
+	newPVC.Status.ModifyVolumeStatus = nil
+	newPVC.Status.Conditions = nil
+	for _, c := range pvc.Status.Conditions {
+		switch c.Type {
+		case v1.PersistentVolumeClaimVolumeModifyingVolume, v1.PersistentVolumeClaimVolumeModifyVolumeError, util.ModifyVolumePending:
+		default:
+			newPVC.Status.Conditions = append(newPVC.Status.Conditions, c)
+		}
+	}
+	return &Action{
+		Description: fmt.Sprintf("Clear modify volume status %s of PVC %s/%s to %q and its modify conditions", status.Status, namespace, name, status.TargetVolumeAttributesClassName),
+		apply: func() error {
+			_, err := util.PatchClaim(kubeClient, pvc, newPVC, true)
+			return err
+		},
+	}, nil
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resizerctl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testPVC(name, request, capacity string) *v1.PersistentVolumeClaim {
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: v1.PersistentVolumeClaimSpec{
+			VolumeName: name + "-pv",
+			Resources: v1.VolumeResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse(request)},
+			},
+		},
+		Status: v1.PersistentVolumeClaimStatus{
+			Phase:    v1.ClaimBound,
+			Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse(capacity)},
+		},
+	}
+}
+
+func testPV(pvc *v1.PersistentVolumeClaim, driver, capacity string) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: pvc.Spec.VolumeName},
+		Spec: v1.PersistentVolumeSpec{
+			Capacity:               v1.ResourceList{v1.ResourceStorage: resource.MustParse(capacity)},
+			ClaimRef:               &v1.ObjectReference{Namespace: pvc.Namespace, Name: pvc.Name},
+			PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{Driver: driver}},
+		},
+	}
+}
+
+func TestListStates(t *testing.T) {
+	settled := testPVC("settled", "1Gi", "1Gi")
+	infeasible := testPVC("infeasible", "10Ti", "1Gi")
+	infeasible.Status.AllocatedResourceStatuses = map[v1.ResourceName]v1.ClaimResourceStatus{
+		v1.ResourceStorage: v1.PersistentVolumeClaimControllerResizeInfeasible,
+	}
+	infeasible.Status.Conditions = []v1.PersistentVolumeClaimCondition{
+		{Type: v1.PersistentVolumeClaimControllerResizeError, Message: "out of capacity"},
+	}
+	modifying := testPVC("modifying", "1Gi", "1Gi")
+	modifying.Status.ModifyVolumeStatus = &v1.ModifyVolumeStatus{
+		TargetVolumeAttributesClassName: "gold",
+		Status:                          v1.PersistentVolumeClaimModifyVolumeInProgress,
+	}
+	otherDriver := testPVC("other", "2Gi", "1Gi")
+
+	client := fake.NewSimpleClientset(
+		settled, testPV(settled, "a", "1Gi"),
+		infeasible, testPV(infeasible, "a", "1Gi"),
+		modifying, testPV(modifying, "a", "1Gi"),
+		otherDriver, testPV(otherDriver, "b", "1Gi"),
+	)
+
+	states, err := ListStates(t.Context(), client, "", "a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(states) != 2 || states[0].Name != "infeasible" || states[1].Name != "modifying" {
+		t.Fatalf("unexpected states: %+v", states)
+	}
+	if states[0].LastError != "out of capacity" || states[0].ResizeStatus != v1.PersistentVolumeClaimControllerResizeInfeasible {
+		t.Errorf("unexpected state: %+v", states[0])
+	}
+
+	states, err = ListStates(t.Context(), client, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out bytes.Buffer
+	if err := PrintStates(&out, states); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "DRIVER: a") || !strings.Contains(out.String(), "DRIVER: b") {
+		t.Errorf("expected a section per driver, got:\n%s", out.String())
+	}
+}
+
+func TestResetSize(t *testing.T) {
+	pvc := testPVC("infeasible", "10Ti", "1Gi")
+	client := fake.NewSimpleClientset(pvc)
+
+	if _, err := ResetSize(t.Context(), client, "default", pvc.Name, resource.MustParse("2Gi"), false); err == nil {
+		t.Errorf("expected error for PVC that is not infeasible")
+	}
+	if _, err := ResetSize(t.Context(), client, "default", pvc.Name, resource.MustParse("512Mi"), true); err == nil {
+		t.Errorf("expected error for size below capacity")
+	}
+
+	action, err := ResetSize(t.Context(), client, "default", pvc.Name, resource.MustParse("2Gi"), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := action.Apply(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated, _ := client.CoreV1().PersistentVolumeClaims("default").Get(t.Context(), pvc.Name, metav1.GetOptions{})
+	if request := updated.Spec.Resources.Requests[v1.ResourceStorage]; request.Cmp(resource.MustParse("2Gi")) != 0 {
+		t.Errorf("expected request 2Gi, got %s", request.String())
+	}
+}
+
+func TestClearPreResizeCapacity(t *testing.T) {
+	pvc := testPVC("expanding", "2Gi", "1Gi")
+	pv := testPV(pvc, "a", "2Gi")
+	pv.Annotations = map[string]string{util.AnnPreResizeCapacity: "1Gi"}
+	client := fake.NewSimpleClientset(pvc, pv)
+
+	if _, err := ClearPreResizeCapacity(t.Context(), client, pv.Name, false); err == nil {
+		t.Errorf("expected error while node expansion is not finished")
+	}
+	action, err := ClearPreResizeCapacity(t.Context(), client, pv.Name, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := action.Apply(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated, _ := client.CoreV1().PersistentVolumes().Get(t.Context(), pv.Name, metav1.GetOptions{})
+	if _, ok := updated.Annotations[util.AnnPreResizeCapacity]; ok {
+		t.Errorf("expected annotation to be removed")
+	}
+}
+
+func TestResetModifyStatus(t *testing.T) {
+	pvc := testPVC("modifying", "1Gi", "1Gi")
+	pvc.Status.ModifyVolumeStatus = &v1.ModifyVolumeStatus{
+		TargetVolumeAttributesClassName: "gold",
+		Status:                          v1.PersistentVolumeClaimModifyVolumeInProgress,
+	}
+	pvc.Status.Conditions = []v1.PersistentVolumeClaimCondition{
+		{Type: v1.PersistentVolumeClaimVolumeModifyingVolume},
+		{Type: v1.PersistentVolumeClaimFileSystemResizePending},
+	}
+	client := fake.NewSimpleClientset(pvc)
+
+	if _, err := ResetModifyStatus(t.Context(), client, "default", pvc.Name, false); err == nil {
+		t.Errorf("expected error while modification is in progress")
+	}
+	action, err := ResetModifyStatus(t.Context(), client, "default", pvc.Name, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := action.Apply(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated, _ := client.CoreV1().PersistentVolumeClaims("default").Get(t.Context(), pvc.Name, metav1.GetOptions{})
+	if updated.Status.ModifyVolumeStatus != nil {
+		t.Errorf("expected modify volume status to be cleared, got %+v", updated.Status.ModifyVolumeStatus)
+	}
+	if len(updated.Status.Conditions) != 1 || updated.Status.Conditions[0].Type != v1.PersistentVolumeClaimFileSystemResizePending {
+		t.Errorf("expected only the resize condition to be kept, got %+v", updated.Status.Conditions)
+	}
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resizerctl implements the operator commands of resizerctl: showing PVCs whose
+// resize or modify operation did not finish and guided recovery actions for them.
+package resizerctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+)
+
+// PVCState is the resize and modify state of a PVC that has not settled.
+type PVCState struct {
+	Driver    string
+	Namespace string
+	Name      string
+	PV        string
+
+	Requested string
+	Capacity  string
+	Allocated string
+	// ResizeStatus is the AllocatedResourceStatuses entry for storage.
+	ResizeStatus v1.ClaimResourceStatus
+	// PreResizeCapacity is the pre-resize capacity annotation of the PV.
+	PreResizeCapacity string
+
+	CurrentVAC   string
+	TargetVAC    string
+	ModifyStatus v1.PersistentVolumeClaimModifyVolumeStatus
+
+	Conditions []v1.PersistentVolumeClaimConditionType
+	// LastError is the message of the most recent error condition.
+	LastError string
+}
+
+// ListStates returns the PVCs in namespace (all namespaces if empty) whose resize or modify
+// operation has not settled, sorted by driver, namespace and name. If driver is not empty,
+// only PVCs of volumes of that CSI driver are returned.
+func ListStates(ctx context.Context, kubeClient kubernetes.Interface, namespace, driver string) ([]PVCState, error) {
+	pvList, err := kubeClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PVs: %v", err)
+	}
+	pvs := make(map[string]*v1.PersistentVolume, len(pvList.Items))
+	for i := range pvList.Items {
+		pvs[pvList.Items[i].Name] = &pvList.Items[i]
+	}
+
+	pvcList, err := kubeClient.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PVCs: %v", err)
+	}
+
+	var states []PVCState
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		pv, ok := pvs[pvc.Spec.VolumeName]
+		if !ok || pv.Spec.CSI == nil {
+			continue
+		}
+		if driver != "" && pv.Spec.CSI.Driver != driver {
+			continue
+		}
+		if state, ok := stateOf(pvc, pv); ok {
+			states = append(states, state)
+		}
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		a, b := states[i], states[j]
+		if a.Driver != b.Driver {
+			return a.Driver < b.Driver
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return states, nil
+}
+
+// stateOf returns the state of pvc and whether it has an unsettled resize or modify operation.
+func stateOf(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) (PVCState, bool) {
+	requested := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+	capacity := pvc.Status.Capacity[v1.ResourceStorage]
+	state := PVCState{
+		Driver:            pv.Spec.CSI.Driver,
+		Namespace:         pvc.Namespace,
+		Name:              pvc.Name,
+		PV:                pv.Name,
+		Requested:         requested.String(),
+		Capacity:          capacity.String(),
+		ResizeStatus:      pvc.Status.AllocatedResourceStatuses[v1.ResourceStorage],
+		PreResizeCapacity: pv.Annotations[util.AnnPreResizeCapacity],
+		CurrentVAC:        ptr.Deref(pvc.Status.CurrentVolumeAttributesClassName, ""),
+	}
+	if allocated, ok := pvc.Status.AllocatedResources[v1.ResourceStorage]; ok {
+		state.Allocated = allocated.String()
+	}
+	if status := pvc.Status.ModifyVolumeStatus; status != nil {
+		state.TargetVAC = status.TargetVolumeAttributesClassName
+		state.ModifyStatus = status.Status
+	}
+
+	var lastErrorTime metav1.Time
+	for _, c := range pvc.Status.Conditions {
+		if !isResizeOrModifyCondition(c.Type) {
+			continue
+		}
+		state.Conditions = append(state.Conditions, c.Type)
+		if isErrorCondition(c.Type) && !c.LastTransitionTime.Before(&lastErrorTime) {
+			lastErrorTime = c.LastTransitionTime
+			state.LastError = c.Message
+		}
+	}
+
+	unsettled := state.ResizeStatus != "" || state.ModifyStatus != "" || state.PreResizeCapacity != "" ||
+		len(state.Conditions) > 0 || requested.Cmp(capacity) > 0
+	return state, unsettled
+}
+
+func isResizeOrModifyCondition(t v1.PersistentVolumeClaimConditionType) bool {
+	switch t {
+	case v1.PersistentVolumeClaimResizing,
+		v1.PersistentVolumeClaimFileSystemResizePending,
+		v1.PersistentVolumeClaimControllerResizeError,
+		v1.PersistentVolumeClaimNodeResizeError,
+		v1.PersistentVolumeClaimVolumeModifyingVolume,
+		v1.PersistentVolumeClaimVolumeModifyVolumeError,
+		util.ModifyVolumePending:
+		return true
+	}
+	return false
+}
+
+func isErrorCondition(t v1.PersistentVolumeClaimConditionType) bool {
+	return t == v1.PersistentVolumeClaimControllerResizeError ||
+		t == v1.PersistentVolumeClaimNodeResizeError ||
+		t == v1.PersistentVolumeClaimVolumeModifyVolumeError
+}
+
+// PrintStates writes states as a table, one section per driver.
+func PrintStates(out io.Writer, states []PVCState) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	driver := ""
+	for i, s := range states {
+		if i == 0 || s.Driver != driver {
+			driver = s.Driver
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "DRIVER: %s\n", driver)
+			fmt.Fprintln(w, "NAMESPACE\tPVC\tPV\tREQUESTED\tCAPACITY\tALLOCATED\tRESIZE STATUS\tPRE-RESIZE CAPACITY\tVAC\tTARGET VAC\tMODIFY STATUS\tCONDITIONS\tLAST ERROR")
+		}
+		conditions := make([]string, 0, len(s.Conditions))
+		for _, c := range s.Conditions {
+			conditions = append(conditions, string(c))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.Namespace, s.Name, s.PV, s.Requested, s.Capacity, orNone(s.Allocated), orNone(string(s.ResizeStatus)),
+			orNone(s.PreResizeCapacity), orNone(s.CurrentVAC), orNone(s.TargetVAC), orNone(string(s.ModifyStatus)),
+			orNone(strings.Join(conditions, ",")), orNone(s.LastError))
+	}
+	return w.Flush()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -0,0 +1,530 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sharding splits the PVCs of a driver among active replicas of the resizer.
+//
+// PVCs are hashed by namespace/name into a fixed number of shards. Every shard is owned by
+// at most one replica at a time, which holds the Lease of the shard. Replicas take free
+// shards until each of them owns its fair share, and give up shards they own beyond that,
+// so that shards are rebalanced when replicas join or leave. Every replica also holds a
+// Lease of its own, so that replicas that own no shard yet are counted for the fair share.
+// Replicas do not acquire shards while a replica without sharding holds the leader election
+// Lease, so that a rolling upgrade to sharding does not run two active replicas for a PVC.
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+)
+
+var ownedShards = metrics.NewGauge(
+	&metrics.GaugeOpts{
+		Subsystem:      "csi_resizer",
+		Name:           "owned_shards",
+		Help:           "Number of PVC shards owned by this replica.",
+		StabilityLevel: metrics.ALPHA,
+	},
+)
+
+func init() {
+	legacyregistry.MustRegister(ownedShards)
+}
+
+// labelGroup is the label of all shard and replica Leases of a Manager.
+const labelGroup = "resizer.csi.k8s.io/shard-group"
+
+// Shard returns the shard of a PVC key (namespace/name).
+func Shard(key string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// Handler is notified when the replica starts or stops owning a shard.
+// It must drop any state it keeps about PVCs of the shard in both cases,
+// because another replica may have worked on them in the meantime.
+type Handler interface {
+	// ShardAcquired is called after the replica acquired shard.
+	ShardAcquired(shard int)
+	// ShardReleased is called after the replica gave up or lost shard.
+	ShardReleased(shard int)
+}
+
+type shardState struct {
+	lease *coordinationv1.Lease
+	// renewed is when the lease was last renewed.
+	renewed time.Time
+	// draining shards accept no new work and are released when inFlight drops to zero.
+	draining bool
+	inFlight int
+}
+
+// Manager acquires, renews and releases the shard Leases of a replica.
+// A nil *Manager owns all PVCs.
+type Manager struct {
+	client        kubernetes.Interface
+	namespace     string
+	prefix        string
+	identity      string
+	group         string
+	shards        int
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	now           func() time.Time
+
+	mu       sync.Mutex
+	owned    map[int]*shardState
+	handlers []Handler
+}
+
+// NewManager returns a Manager for shards Leases named <prefix>-shard-<n> in namespace.
+// prefix is also the name of the leader election Lease used without sharding.
+// identity must be unique among the replicas.
+func NewManager(client kubernetes.Interface, namespace, prefix, identity string, shards int, leaseDuration time.Duration) *Manager {
+	group := prefix
+	if len(validation.IsValidLabelValue(group)) > 0 {
+		h := fnv.New64a()
+		h.Write([]byte(prefix))
+		group = fmt.Sprintf("%x", h.Sum64())
+	}
+	return &Manager{
+		client:        client,
+		namespace:     namespace,
+		prefix:        prefix,
+		identity:      identity,
+		group:         group,
+		shards:        shards,
+		leaseDuration: leaseDuration,
+		renewDeadline: leaseDuration * 2 / 3,
+		now:           time.Now,
+		owned:         map[int]*shardState{},
+	}
+}
+
+// AddHandler registers h for shard changes. It must be called before Run.
+func (m *Manager) AddHandler(h Handler) {
+	if m == nil {
+		return
+	}
+	m.handlers = append(m.handlers, h)
+}
+
+// Shard returns the shard of a PVC key, or 0 for a nil Manager.
+func (m *Manager) Shard(key string) int {
+	if m == nil {
+		return 0
+	}
+	return Shard(key, m.shards)
+}
+
+// Owns returns true if the replica owns the shard of a PVC key and accepts new work for it.
+func (m *Manager) Owns(key string) bool {
+	if m == nil {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.owned[Shard(key, m.shards)]
+	return s != nil && !s.draining
+}
+
+// Begin starts work on a PVC key. It returns false if the replica does not own the shard of the key.
+// Otherwise the shard is not released until the returned function is called.
+func (m *Manager) Begin(key string) (func(), bool) {
+	if m == nil {
+		return func() {}, true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.owned[Shard(key, m.shards)]
+	if s == nil || s.draining {
+		return nil, false
+	}
+	s.inFlight++
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		s.inFlight--
+	}, true
+}
+
+// Run maintains the shard Leases until ctx is done. Then it stops accepting new work,
+// waits for work in progress for up to the lease duration and releases all Leases.
+func (m *Manager) Run(ctx context.Context) {
+	klog.InfoS("Starting shard manager", "identity", m.identity, "shards", m.shards)
+	ticker := time.NewTicker(m.leaseDuration / 3)
+	defer ticker.Stop()
+	for {
+		m.sync(ctx)
+		select {
+		case <-ctx.Done():
+			m.releaseAll()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) leaseName(shard int) string {
+	return fmt.Sprintf("%s-shard-%d", m.prefix, shard)
+}
+
+func (m *Manager) replicaLeaseName() string {
+	return fmt.Sprintf("%s-replica-%s", m.prefix, m.identity)
+}
+
+func (m *Manager) expired(lease *coordinationv1.Lease, now time.Time) bool {
+	spec := lease.Spec
+	if ptr.Deref(spec.HolderIdentity, "") == "" || spec.RenewTime == nil {
+		return true
+	}
+	duration := time.Duration(ptr.Deref(spec.LeaseDurationSeconds, 0)) * time.Second
+	return spec.RenewTime.Add(duration).Before(now)
+}
+
+// labels returns the labels of lease with the group label added.
+func (m *Manager) labels(lease *coordinationv1.Lease) map[string]string {
+	l := make(map[string]string, len(lease.Labels)+1)
+	for k, v := range lease.Labels {
+		l[k] = v
+	}
+	l[labelGroup] = m.group
+	return l
+}
+
+// leaderElected returns true if a replica without sharding holds the leader election Lease.
+func (m *Manager) leaderElected(ctx context.Context, now time.Time) (bool, error) {
+	lease, err := m.client.CoordinationV1().Leases(m.namespace).Get(ctx, m.prefix, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !m.expired(lease, now), nil
+}
+
+// sync renews the owned Leases and acquires or releases shards to reach the fair share.
+func (m *Manager) sync(ctx context.Context) {
+	selector := labels.SelectorFromSet(labels.Set{labelGroup: m.group}).String()
+	list, err := m.client.CoordinationV1().Leases(m.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		klog.ErrorS(err, "Failed to list shard leases")
+		m.dropExpired()
+		return
+	}
+	now := m.now()
+	leases := map[string]*coordinationv1.Lease{}
+	holders := map[string]bool{m.identity: true}
+	var replicaLease *coordinationv1.Lease
+	for i := range list.Items {
+		lease := &list.Items[i]
+		switch {
+		case lease.Name == m.replicaLeaseName():
+			replicaLease = lease
+		case strings.HasPrefix(lease.Name, m.prefix+"-replica-"):
+			if !m.expired(lease, now) {
+				holders[ptr.Deref(lease.Spec.HolderIdentity, "")] = true
+			}
+		case strings.HasPrefix(lease.Name, m.prefix+"-shard-"):
+			leases[lease.Name] = lease
+		}
+	}
+	m.heartbeat(ctx, replicaLease)
+
+	m.renew(ctx, leases)
+
+	target := (m.shards + len(holders) - 1) / len(holders)
+	m.mu.Lock()
+	active := 0
+	for _, s := range m.owned {
+		if !s.draining {
+			active++
+		}
+	}
+	// Give up one shard at a time, so that work moves gradually.
+	if active > target {
+		for shard, s := range m.owned {
+			if !s.draining {
+				klog.V(2).InfoS("Draining shard for rebalancing", "shard", shard, "owned", active, "target", target)
+				s.draining = true
+				active--
+				break
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	if active < target {
+		elected, err := m.leaderElected(ctx, now)
+		if err != nil {
+			klog.ErrorS(err, "Failed to get leader election lease", "lease", m.prefix)
+			return
+		}
+		if elected {
+			klog.V(2).InfoS("Waiting for the leader election lease to be released before acquiring shards", "lease", m.prefix)
+			return
+		}
+	}
+
+	// Start at a different shard on every replica to reduce conflicts.
+	start := Shard(m.identity, m.shards)
+	for i := 0; i < m.shards && active < target; i++ {
+		shard := (start + i) % m.shards
+		m.mu.Lock()
+		_, owned := m.owned[shard]
+		m.mu.Unlock()
+		if owned {
+			continue
+		}
+		lease := leases[m.leaseName(shard)]
+		if lease != nil && !m.expired(lease, now) {
+			continue
+		}
+		if m.acquire(ctx, shard, lease) {
+			active++
+		}
+	}
+}
+
+// heartbeat creates or renews the Lease of the replica itself.
+func (m *Manager) heartbeat(ctx context.Context, lease *coordinationv1.Lease) {
+	now := metav1.NewMicroTime(m.now())
+	spec := coordinationv1.LeaseSpec{
+		HolderIdentity:       ptr.To(m.identity),
+		LeaseDurationSeconds: ptr.To(int32(m.leaseDuration.Seconds())),
+		RenewTime:            &now,
+	}
+	var err error
+	if lease == nil {
+		lease = &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: m.replicaLeaseName(), Namespace: m.namespace}, Spec: spec}
+		lease.Labels = m.labels(lease)
+		_, err = m.client.CoordinationV1().Leases(m.namespace).Create(ctx, lease, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			// The lease was created without the group label and is not listed.
+			lease, err = m.client.CoordinationV1().Leases(m.namespace).Get(ctx, m.replicaLeaseName(), metav1.GetOptions{})
+		} else {
+			lease = nil
+		}
+	}
+	if err == nil && lease != nil {
+		lease = lease.DeepCopy()
+		lease.Labels = m.labels(lease)
+		lease.Spec = spec
+		_, err = m.client.CoordinationV1().Leases(m.namespace).Update(ctx, lease, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		klog.ErrorS(err, "Failed to renew replica lease")
+	}
+}
+
+// renew renews the owned Leases, releases drained shards and drops shards that were lost.
+func (m *Manager) renew(ctx context.Context, leases map[string]*coordinationv1.Lease) {
+	m.mu.Lock()
+	shards := make([]int, 0, len(m.owned))
+	for shard := range m.owned {
+		shards = append(shards, shard)
+	}
+	m.mu.Unlock()
+
+	for _, shard := range shards {
+		lease := leases[m.leaseName(shard)]
+		if lease == nil || ptr.Deref(lease.Spec.HolderIdentity, "") != m.identity {
+			klog.InfoS("Lost shard lease to another replica", "shard", shard)
+			m.drop(shard)
+			continue
+		}
+
+		m.mu.Lock()
+		s := m.owned[shard]
+		release := s.draining && s.inFlight == 0
+		m.mu.Unlock()
+
+		lease = lease.DeepCopy()
+		if release {
+			lease.Spec.HolderIdentity = nil
+			lease.Spec.RenewTime = nil
+		} else {
+			lease.Spec.RenewTime = ptr.To(metav1.NewMicroTime(m.now()))
+		}
+		updated, err := m.client.CoordinationV1().Leases(m.namespace).Update(ctx, lease, metav1.UpdateOptions{})
+		switch {
+		case err == nil && release:
+			klog.V(2).InfoS("Released shard", "shard", shard)
+			m.drop(shard)
+		case err == nil:
+			m.mu.Lock()
+			s.lease = updated
+			s.renewed = m.now()
+			m.mu.Unlock()
+		case apierrors.IsConflict(err):
+			klog.InfoS("Lost shard lease to another replica", "shard", shard)
+			m.drop(shard)
+		default:
+			klog.ErrorS(err, "Failed to renew shard lease", "shard", shard)
+		}
+	}
+	m.dropExpired()
+}
+
+// dropExpired stops accepting new work for shards whose lease could not be renewed in time,
+// before another replica may take them over, and drops them once the work in progress finished.
+func (m *Manager) dropExpired() {
+	now := m.now()
+	m.mu.Lock()
+	var expired []int
+	for shard, s := range m.owned {
+		if now.Sub(s.renewed) <= m.renewDeadline {
+			continue
+		}
+		if !s.draining {
+			klog.InfoS("Failed to renew shard lease in time, draining", "shard", shard)
+			s.draining = true
+		}
+		if s.inFlight == 0 {
+			expired = append(expired, shard)
+		}
+	}
+	m.mu.Unlock()
+	for _, shard := range expired {
+		m.drop(shard)
+	}
+}
+
+func (m *Manager) acquire(ctx context.Context, shard int, lease *coordinationv1.Lease) bool {
+	now := metav1.NewMicroTime(m.now())
+	spec := coordinationv1.LeaseSpec{
+		HolderIdentity:       ptr.To(m.identity),
+		LeaseDurationSeconds: ptr.To(int32(m.leaseDuration.Seconds())),
+		AcquireTime:          &now,
+		RenewTime:            &now,
+	}
+	var err error
+	var updated *coordinationv1.Lease
+	if lease == nil {
+		lease = &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: m.leaseName(shard), Namespace: m.namespace}, Spec: spec}
+		lease.Labels = m.labels(lease)
+		updated, err = m.client.CoordinationV1().Leases(m.namespace).Create(ctx, lease, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			// The lease was created without the group label and is not listed.
+			lease, err = m.client.CoordinationV1().Leases(m.namespace).Get(ctx, m.leaseName(shard), metav1.GetOptions{})
+			if err == nil && !m.expired(lease, now.Time) {
+				return false
+			}
+		} else {
+			lease = nil
+		}
+	}
+	if err == nil && lease != nil {
+		lease = lease.DeepCopy()
+		lease.Labels = m.labels(lease)
+		spec.LeaseTransitions = ptr.To(ptr.Deref(lease.Spec.LeaseTransitions, 0) + 1)
+		lease.Spec = spec
+		// The resource version of the listed lease makes sure that only one replica takes over.
+		updated, err = m.client.CoordinationV1().Leases(m.namespace).Update(ctx, lease, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		if !apierrors.IsConflict(err) && !apierrors.IsAlreadyExists(err) {
+			klog.ErrorS(err, "Failed to acquire shard lease", "shard", shard)
+		}
+		return false
+	}
+
+	klog.V(2).InfoS("Acquired shard", "shard", shard)
+	m.mu.Lock()
+	m.owned[shard] = &shardState{lease: updated, renewed: m.now()}
+	ownedShards.Set(float64(len(m.owned)))
+	m.mu.Unlock()
+	for _, h := range m.handlers {
+		h.ShardAcquired(shard)
+	}
+	return true
+}
+
+func (m *Manager) drop(shard int) {
+	m.mu.Lock()
+	_, ok := m.owned[shard]
+	delete(m.owned, shard)
+	ownedShards.Set(float64(len(m.owned)))
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+	for _, h := range m.handlers {
+		h.ShardReleased(shard)
+	}
+}
+
+// releaseAll drains all shards and releases their Leases.
+func (m *Manager) releaseAll() {
+	m.mu.Lock()
+	for _, s := range m.owned {
+		s.draining = true
+	}
+	m.mu.Unlock()
+
+	deadline := m.now().Add(m.renewDeadline)
+	for m.now().Before(deadline) {
+		m.mu.Lock()
+		busy := false
+		for _, s := range m.owned {
+			busy = busy || s.inFlight > 0
+		}
+		m.mu.Unlock()
+		if !busy {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.renewDeadline)
+	defer cancel()
+	m.mu.Lock()
+	owned := make(map[int]*coordinationv1.Lease, len(m.owned))
+	for shard, s := range m.owned {
+		owned[shard] = s.lease
+	}
+	m.mu.Unlock()
+	for shard, lease := range owned {
+		lease = lease.DeepCopy()
+		lease.Spec.HolderIdentity = nil
+		lease.Spec.RenewTime = nil
+		if _, err := m.client.CoordinationV1().Leases(m.namespace).Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+			klog.ErrorS(err, "Failed to release shard lease", "shard", shard)
+		}
+		m.drop(shard)
+	}
+	// Let the other replicas take over the shards right away.
+	err := m.client.CoordinationV1().Leases(m.namespace).Delete(ctx, m.replicaLeaseName(), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.ErrorS(err, "Failed to delete replica lease")
+	}
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+type recordingHandler struct {
+	acquired, released []int
+}
+
+func (h *recordingHandler) ShardAcquired(shard int) { h.acquired = append(h.acquired, shard) }
+func (h *recordingHandler) ShardReleased(shard int) { h.released = append(h.released, shard) }
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestManager(client kubernetes.Interface, identity string, clock *fakeClock) (*Manager, *recordingHandler) {
+	m := NewManager(client, "default", "external-resizer-mock", identity, 4, 15*time.Second)
+	m.now = clock.now
+	h := &recordingHandler{}
+	m.AddHandler(h)
+	return m, h
+}
+
+func keyOfShard(t *testing.T, shard, shards int) string {
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("default/pvc-%d", i)
+		if Shard(key, shards) == shard {
+			return key
+		}
+	}
+	t.Fatalf("no key found for shard %d", shard)
+	return ""
+}
+
+func TestRebalance(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	clock := &fakeClock{t: time.Now()}
+	a, aHandler := newTestManager(client, "a", clock)
+	b, _ := newTestManager(client, "b", clock)
+
+	a.sync(t.Context())
+	if len(a.owned) != 4 || len(aHandler.acquired) != 4 {
+		t.Fatalf("expected a single replica to own all shards, got %d", len(a.owned))
+	}
+
+	for range 10 {
+		clock.t = clock.t.Add(time.Second)
+		a.sync(t.Context())
+		b.sync(t.Context())
+	}
+	if len(a.owned) != 2 || len(b.owned) != 2 {
+		t.Fatalf("expected 2 shards per replica, got %d and %d", len(a.owned), len(b.owned))
+	}
+	for shard := range 4 {
+		key := keyOfShard(t, shard, 4)
+		if a.Owns(key) == b.Owns(key) {
+			t.Errorf("expected shard %d to be owned by exactly one replica", shard)
+		}
+	}
+	if len(aHandler.released) != 2 {
+		t.Errorf("expected a to release 2 shards, got %v", aHandler.released)
+	}
+}
+
+func TestDrainWaitsForWorkInProgress(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	clock := &fakeClock{t: time.Now()}
+	m, _ := newTestManager(client, "a", clock)
+	m.sync(t.Context())
+
+	key := keyOfShard(t, 1, 4)
+	done, ok := m.Begin(key)
+	if !ok {
+		t.Fatalf("expected to own %s", key)
+	}
+	m.owned[1].draining = true
+	if _, ok := m.Begin(key); ok || m.Owns(key) {
+		t.Errorf("expected draining shard to accept no new work")
+	}
+
+	m.renew(t.Context(), leasesOf(t, m))
+	if _, ok := m.owned[1]; !ok {
+		t.Fatalf("expected shard with work in progress not to be released")
+	}
+	done()
+	m.renew(t.Context(), leasesOf(t, m))
+	if _, ok := m.owned[1]; ok {
+		t.Errorf("expected drained shard to be released")
+	}
+}
+
+func TestTakeOverExpiredShards(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	clock := &fakeClock{t: time.Now()}
+	a, aHandler := newTestManager(client, "a", clock)
+	b, _ := newTestManager(client, "b", clock)
+	a.sync(t.Context())
+
+	// a stops renewing, b waits for the leases to expire.
+	clock.t = clock.t.Add(10 * time.Second)
+	b.sync(t.Context())
+	if len(b.owned) != 0 {
+		t.Fatalf("expected no shards to be taken over before the leases expire")
+	}
+	clock.t = clock.t.Add(10 * time.Second)
+	b.sync(t.Context())
+	if len(b.owned) != 4 {
+		t.Fatalf("expected b to take over all shards, got %d", len(b.owned))
+	}
+
+	a.sync(t.Context())
+	if len(a.owned) != 0 || len(aHandler.released) != 4 {
+		t.Errorf("expected a to drop all shards, owns %d, released %v", len(a.owned), aHandler.released)
+	}
+}
+
+func TestExpiredShardDrainsWorkInProgress(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	clock := &fakeClock{t: time.Now()}
+	m, h := newTestManager(client, "a", clock)
+	m.sync(t.Context())
+
+	key := keyOfShard(t, 1, 4)
+	done, ok := m.Begin(key)
+	if !ok {
+		t.Fatalf("expected to own %s", key)
+	}
+	// The leases cannot be renewed in time.
+	clock.t = clock.t.Add(11 * time.Second)
+	m.dropExpired()
+	if m.Owns(key) {
+		t.Errorf("expected expired shard to accept no new work")
+	}
+	if _, ok := m.owned[1]; !ok || len(m.owned) != 1 {
+		t.Fatalf("expected only the shard with work in progress to be kept, got %d shards", len(m.owned))
+	}
+	done()
+	m.dropExpired()
+	if len(m.owned) != 0 || len(h.released) != 4 {
+		t.Errorf("expected all shards dropped, owns %d, released %v", len(m.owned), h.released)
+	}
+}
+
+func TestWaitForLeaderElection(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	leader := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "external-resizer-mock", Namespace: "default"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To("old"),
+			LeaseDurationSeconds: ptr.To(int32(15)),
+			RenewTime:            ptr.To(metav1.NewMicroTime(clock.t)),
+		},
+	}
+	client := fake.NewSimpleClientset(leader)
+	m, _ := newTestManager(client, "a", clock)
+
+	m.sync(t.Context())
+	if len(m.owned) != 0 {
+		t.Fatalf("expected no shards acquired while the leader election lease is held, got %d", len(m.owned))
+	}
+	clock.t = clock.t.Add(20 * time.Second)
+	m.sync(t.Context())
+	if len(m.owned) != 4 {
+		t.Fatalf("expected all shards acquired after the leader election lease expired, got %d", len(m.owned))
+	}
+	for name, lease := range leasesOf(t, m) {
+		if name != leader.Name && lease.Labels[labelGroup] != "external-resizer-mock" {
+			t.Errorf("expected lease %s labeled, got %v", name, lease.Labels)
+		}
+	}
+}
+
+func TestAcquireUnlabeledLease(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	var objs []runtime.Object
+	for shard := range 4 {
+		objs = append(objs, &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("external-resizer-mock-shard-%d", shard), Namespace: "default"}})
+	}
+	client := fake.NewSimpleClientset(objs...)
+	m, _ := newTestManager(client, "a", clock)
+
+	m.sync(t.Context())
+	if len(m.owned) != 4 {
+		t.Fatalf("expected free unlabeled leases acquired, got %d", len(m.owned))
+	}
+	m.sync(t.Context())
+	if len(m.owned) != 4 {
+		t.Errorf("expected acquired leases renewed, got %d", len(m.owned))
+	}
+}
+
+func TestNilManager(t *testing.T) {
+	var m *Manager
+	if !m.Owns("default/pvc") {
+		t.Errorf("expected nil manager to own all PVCs")
+	}
+	done, ok := m.Begin("default/pvc")
+	if !ok {
+		t.Errorf("expected nil manager to accept all work")
+	}
+	done()
+}
+
+func leasesOf(t *testing.T, m *Manager) map[string]*coordinationv1.Lease {
+	list, err := m.client.CoordinationV1().Leases(m.namespace).List(t.Context(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leases := map[string]*coordinationv1.Lease{}
+	for i := range list.Items {
+		leases[list.Items[i].Name] = &list.Items[i]
+	}
+	return leases
+}
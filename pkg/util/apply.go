@@ -0,0 +1,319 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/features"
+	v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// Field managers of the controllers for server-side apply.
+const (
+	ResizeFieldManager = "external-resizer-resize"
+	ModifyFieldManager = "external-resizer-modify"
+)
+
+// Resources written with server-side apply.
+const (
+	claimStatusResource = "persistentvolumeclaims/status"
+	pvResource          = "persistentvolumes"
+)
+
+// applyRetryInterval is how long strategic merge patches are used for a resource
+// after the API server rejected an apply request, before apply is tried again.
+const applyRetryInterval = 10 * time.Minute
+
+var (
+	// applyRejected holds the time the API server rejected an apply request, per resource.
+	applyRejected sync.Map
+	// applyNow is replaced in tests.
+	applyNow = time.Now
+)
+
+// Owner describes the fields of PVCs and PVs that a controller writes with server-side apply.
+type Owner struct {
+	// FieldManager of the controller.
+	FieldManager string
+	// Resize owns the capacity and allocated resources of PVCs and the capacity of PVs.
+	Resize bool
+	// Modify owns the VolumeAttributesClass status of PVCs and the VolumeAttributesClass of PVs.
+	Modify bool
+	// Conditions are the types of the PVC conditions owned.
+	Conditions []v1.PersistentVolumeClaimConditionType
+	// PVAnnotations are the keys of the PV annotations owned.
+	PVAnnotations []string
+}
+
+// ApplyClaimStatus writes the status of newPVC owned by owner with server-side apply. It falls back
+// to PatchClaim when the ServerSideApply feature is disabled or the API server does not support it,
+// when newPVC changes fields that owner does not own, and to remove owned fields that
+// another field manager still holds, e.g. fields written by strategic merge patches of older versions.
+// If addResourceVersionCheck is true, the write fails when the PVC was changed since oldPVC was read.
+func ApplyClaimStatus(kubeClient kubernetes.Interface, owner Owner, oldPVC, newPVC *v1.PersistentVolumeClaim, addResourceVersionCheck bool) (*v1.PersistentVolumeClaim, error) {
+	if !useServerSideApply(claimStatusResource) || !apiequality.Semantic.DeepEqual(owner.otherClaim(oldPVC), owner.otherClaim(newPVC)) {
+		return PatchClaim(kubeClient, oldPVC, newPVC, addResourceVersionCheck)
+	}
+
+	applyConfig := owner.claimStatusApplyConfiguration(newPVC)
+	if addResourceVersionCheck {
+		applyConfig.WithResourceVersion(oldPVC.ResourceVersion)
+	}
+	updatedPVC, err := kubeClient.CoreV1().PersistentVolumeClaims(newPVC.Namespace).
+		ApplyStatus(context.TODO(), applyConfig, metav1.ApplyOptions{FieldManager: owner.FieldManager, Force: true})
+	if applyUnsupported(claimStatusResource, err) {
+		return PatchClaim(kubeClient, oldPVC, newPVC, addResourceVersionCheck)
+	}
+	if err != nil {
+		return oldPVC, fmt.Errorf("can't apply status of PVC %s with %v", klog.KObj(oldPVC), err)
+	}
+
+	if !equalOwnedClaimStatus(owner.claimStatus(updatedPVC), owner.claimStatus(newPVC)) {
+		klog.V(4).InfoS("Patching PVC status fields held by another field manager", "PVC", klog.KObj(newPVC), "fieldManager", owner.FieldManager)
+		desiredPVC := updatedPVC.DeepCopy()
+		owner.setClaimStatus(desiredPVC, newPVC)
+		return PatchClaim(kubeClient, updatedPVC, desiredPVC, true)
+	}
+	return updatedPVC, nil
+}
+
+// ApplyPersistentVolume writes the fields of newPV owned by owner with server-side apply,
+// with the same fallbacks to PatchPersistentVolume as ApplyClaimStatus.
+func ApplyPersistentVolume(kubeClient kubernetes.Interface, owner Owner, oldPV, newPV *v1.PersistentVolume) (*v1.PersistentVolume, error) {
+	if !useServerSideApply(pvResource) || !apiequality.Semantic.DeepEqual(owner.otherPV(oldPV), owner.otherPV(newPV)) {
+		return PatchPersistentVolume(kubeClient, oldPV, newPV)
+	}
+
+	updatedPV, err := kubeClient.CoreV1().PersistentVolumes().
+		Apply(context.TODO(), owner.pvApplyConfiguration(newPV), metav1.ApplyOptions{FieldManager: owner.FieldManager, Force: true})
+	if applyUnsupported(pvResource, err) {
+		return PatchPersistentVolume(kubeClient, oldPV, newPV)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("apply of PV %s failed: %v", newPV.Name, err)
+	}
+
+	if !apiequality.Semantic.DeepEqual(owner.ownedPV(updatedPV), owner.ownedPV(newPV)) {
+		klog.V(4).InfoS("Patching PV fields held by another field manager", "PV", klog.KObj(newPV), "fieldManager", owner.FieldManager)
+		desiredPV := updatedPV.DeepCopy()
+		owner.setPV(desiredPV, newPV)
+		return PatchPersistentVolume(kubeClient, updatedPV, desiredPV)
+	}
+	return updatedPV, nil
+}
+
+func useServerSideApply(resource string) bool {
+	if !utilfeature.DefaultFeatureGate.Enabled(features.ServerSideApply) {
+		return false
+	}
+	rejected, ok := applyRejected.Load(resource)
+	return !ok || applyNow().Sub(rejected.(time.Time)) >= applyRetryInterval
+}
+
+// applyUnsupported returns true if err shows that the API server does not support server-side apply
+// of resource.
+func applyUnsupported(resource string, err error) bool {
+	if err == nil || !(apierrors.IsUnsupportedMediaType(err) || apierrors.IsMethodNotSupported(err) || apierrors.IsNotAcceptable(err)) {
+		return false
+	}
+	applyRejected.Store(resource, applyNow())
+	klog.InfoS("API server does not support server-side apply, using strategic merge patches", "resource", resource, "retryAfter", applyRetryInterval, "err", err)
+	return true
+}
+
+func (o Owner) ownsCondition(conditionType v1.PersistentVolumeClaimConditionType) bool {
+	return slices.Contains(o.Conditions, conditionType)
+}
+
+// claimStatus returns the status of pvc owned by o.
+func (o Owner) claimStatus(pvc *v1.PersistentVolumeClaim) v1.PersistentVolumeClaimStatus {
+	status := v1.PersistentVolumeClaimStatus{}
+	o.copyClaimStatus(&status, &pvc.Status)
+	return status
+}
+
+// otherClaim returns pvc without the status owned by o.
+func (o Owner) otherClaim(pvc *v1.PersistentVolumeClaim) *v1.PersistentVolumeClaim {
+	other := pvc.DeepCopy()
+	o.copyClaimStatus(&other.Status, &v1.PersistentVolumeClaimStatus{})
+	return other
+}
+
+// setClaimStatus sets the status of pvc owned by o from source.
+func (o Owner) setClaimStatus(pvc, source *v1.PersistentVolumeClaim) {
+	o.copyClaimStatus(&pvc.Status, &source.Status)
+}
+
+func (o Owner) copyClaimStatus(to, from *v1.PersistentVolumeClaimStatus) {
+	if o.Resize {
+		to.Capacity = from.Capacity
+		to.AllocatedResources = from.AllocatedResources
+		to.AllocatedResourceStatuses = from.AllocatedResourceStatuses
+	}
+	if o.Modify {
+		to.CurrentVolumeAttributesClassName = from.CurrentVolumeAttributesClassName
+		to.ModifyVolumeStatus = from.ModifyVolumeStatus
+	}
+	conditions := slices.DeleteFunc(slices.Clone(to.Conditions), func(c v1.PersistentVolumeClaimCondition) bool {
+		return o.ownsCondition(c.Type)
+	})
+	for _, c := range from.Conditions {
+		if o.ownsCondition(c.Type) {
+			conditions = append(conditions, c)
+		}
+	}
+	if len(conditions) == 0 {
+		conditions = nil
+	}
+	to.Conditions = conditions
+}
+
+func (o Owner) claimStatusApplyConfiguration(pvc *v1.PersistentVolumeClaim) *corev1ac.PersistentVolumeClaimApplyConfiguration {
+	status := corev1ac.PersistentVolumeClaimStatus()
+	if o.Resize {
+		if pvc.Status.Capacity != nil {
+			status.WithCapacity(pvc.Status.Capacity)
+		}
+		if pvc.Status.AllocatedResources != nil {
+			status.WithAllocatedResources(pvc.Status.AllocatedResources)
+		}
+		if pvc.Status.AllocatedResourceStatuses != nil {
+			status.WithAllocatedResourceStatuses(pvc.Status.AllocatedResourceStatuses)
+		}
+	}
+	if o.Modify {
+		if pvc.Status.CurrentVolumeAttributesClassName != nil {
+			status.WithCurrentVolumeAttributesClassName(*pvc.Status.CurrentVolumeAttributesClassName)
+		}
+		if modifyStatus := pvc.Status.ModifyVolumeStatus; modifyStatus != nil {
+			status.WithModifyVolumeStatus(corev1ac.ModifyVolumeStatus().
+				WithTargetVolumeAttributesClassName(modifyStatus.TargetVolumeAttributesClassName).
+				WithStatus(modifyStatus.Status))
+		}
+	}
+	for _, c := range pvc.Status.Conditions {
+		if !o.ownsCondition(c.Type) {
+			continue
+		}
+		status.WithConditions(corev1ac.PersistentVolumeClaimCondition().
+			WithType(c.Type).
+			WithStatus(c.Status).
+			WithLastProbeTime(c.LastProbeTime).
+			WithLastTransitionTime(c.LastTransitionTime).
+			WithReason(c.Reason).
+			WithMessage(c.Message))
+	}
+	return corev1ac.PersistentVolumeClaim(pvc.Name, pvc.Namespace).WithStatus(status)
+}
+
+// equalOwnedClaimStatus compares owned PVC status, ignoring condition timestamps
+// that lose precision in the round trip through the API server.
+func equalOwnedClaimStatus(a, b v1.PersistentVolumeClaimStatus) bool {
+	a.Conditions = withoutTimestamps(a.Conditions)
+	b.Conditions = withoutTimestamps(b.Conditions)
+	return apiequality.Semantic.DeepEqual(a, b)
+}
+
+func withoutTimestamps(conditions []v1.PersistentVolumeClaimCondition) []v1.PersistentVolumeClaimCondition {
+	result := make([]v1.PersistentVolumeClaimCondition, 0, len(conditions))
+	for _, c := range conditions {
+		c.LastProbeTime = metav1.Time{}
+		c.LastTransitionTime = metav1.Time{}
+		result = append(result, c)
+	}
+	slices.SortFunc(result, func(a, b v1.PersistentVolumeClaimCondition) int {
+		switch {
+		case a.Type < b.Type:
+			return -1
+		case a.Type > b.Type:
+			return 1
+		}
+		return 0
+	})
+	return result
+}
+
+// ownedPV returns the fields of pv owned by o.
+func (o Owner) ownedPV(pv *v1.PersistentVolume) *v1.PersistentVolume {
+	owned := &v1.PersistentVolume{}
+	o.copyPV(owned, pv)
+	return owned
+}
+
+// otherPV returns pv without the fields owned by o.
+func (o Owner) otherPV(pv *v1.PersistentVolume) *v1.PersistentVolume {
+	other := pv.DeepCopy()
+	o.copyPV(other, &v1.PersistentVolume{})
+	return other
+}
+
+// setPV sets the fields of pv owned by o from source.
+func (o Owner) setPV(pv, source *v1.PersistentVolume) {
+	o.copyPV(pv, source)
+}
+
+func (o Owner) copyPV(to, from *v1.PersistentVolume) {
+	if o.Resize {
+		to.Spec.Capacity = from.Spec.Capacity
+	}
+	if o.Modify {
+		to.Spec.VolumeAttributesClassName = from.Spec.VolumeAttributesClassName
+	}
+	for _, key := range o.PVAnnotations {
+		value, ok := from.Annotations[key]
+		if !ok {
+			delete(to.Annotations, key)
+			continue
+		}
+		if to.Annotations == nil {
+			to.Annotations = map[string]string{}
+		}
+		to.Annotations[key] = value
+	}
+	if len(to.Annotations) == 0 {
+		to.Annotations = nil
+	}
+}
+
+func (o Owner) pvApplyConfiguration(pv *v1.PersistentVolume) *corev1ac.PersistentVolumeApplyConfiguration {
+	applyConfig := corev1ac.PersistentVolume(pv.Name)
+	for _, key := range o.PVAnnotations {
+		if value, ok := pv.Annotations[key]; ok {
+			applyConfig.WithAnnotations(map[string]string{key: value})
+		}
+	}
+	spec := corev1ac.PersistentVolumeSpec()
+	if o.Resize && pv.Spec.Capacity != nil {
+		spec.WithCapacity(pv.Spec.Capacity)
+	}
+	if o.Modify && pv.Spec.VolumeAttributesClassName != nil {
+		spec.WithVolumeAttributesClassName(*pv.Spec.VolumeAttributesClassName)
+	}
+	return applyConfig.WithSpec(spec)
+}
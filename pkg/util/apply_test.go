@@ -0,0 +1,234 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/features"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	featuregatetesting "k8s.io/component-base/featuregate/testing"
+)
+
+var testOwner = Owner{
+	FieldManager:  ResizeFieldManager,
+	Resize:        true,
+	Conditions:    []v1.PersistentVolumeClaimConditionType{v1.PersistentVolumeClaimResizing},
+	PVAnnotations: []string{AnnPreResizeCapacity},
+}
+
+func applyTestPVC() *v1.PersistentVolumeClaim {
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc", Namespace: "default", ResourceVersion: "1"},
+		Status: v1.PersistentVolumeClaimStatus{
+			Capacity:   v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+			Conditions: []v1.PersistentVolumeClaimCondition{pvcWithModifyVolumeProgressCondition},
+		},
+	}
+}
+
+// patchTypes returns the patch types of the patch actions of client.
+func patchTypes(client *fake.Clientset) []types.PatchType {
+	var patchTypes []types.PatchType
+	for _, action := range client.Actions() {
+		if patch, ok := action.(core.PatchAction); ok {
+			patchTypes = append(patchTypes, patch.GetPatchType())
+		}
+	}
+	return patchTypes
+}
+
+func TestApplyClaimStatus(t *testing.T) {
+	tests := []struct {
+		name              string
+		disabled          bool
+		update            func(pvc *v1.PersistentVolumeClaim)
+		expectedPatches   []types.PatchType
+		expectedCondition []v1.PersistentVolumeClaimConditionType
+	}{
+		{
+			name: "owned fields are applied",
+			update: func(pvc *v1.PersistentVolumeClaim) {
+				pvc.Status.AllocatedResources = v1.ResourceList{v1.ResourceStorage: resource.MustParse("2Gi")}
+				pvc.Status.Conditions = append(pvc.Status.Conditions, v1.PersistentVolumeClaimCondition{
+					Type:   v1.PersistentVolumeClaimResizing,
+					Status: v1.ConditionTrue,
+				})
+			},
+			expectedPatches:   []types.PatchType{types.ApplyPatchType},
+			expectedCondition: []v1.PersistentVolumeClaimConditionType{v1.PersistentVolumeClaimVolumeModifyingVolume, v1.PersistentVolumeClaimResizing},
+		},
+		{
+			name:     "feature disabled",
+			disabled: true,
+			update: func(pvc *v1.PersistentVolumeClaim) {
+				pvc.Status.AllocatedResources = v1.ResourceList{v1.ResourceStorage: resource.MustParse("2Gi")}
+			},
+			expectedPatches:   []types.PatchType{types.StrategicMergePatchType},
+			expectedCondition: []v1.PersistentVolumeClaimConditionType{v1.PersistentVolumeClaimVolumeModifyingVolume},
+		},
+		{
+			name: "fields of other controllers are patched",
+			update: func(pvc *v1.PersistentVolumeClaim) {
+				pvc.Status.AllocatedResources = v1.ResourceList{v1.ResourceStorage: resource.MustParse("2Gi")}
+				pvc.Status.Conditions = nil
+			},
+			expectedPatches: []types.PatchType{types.StrategicMergePatchType},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.disabled {
+				featuregatetesting.SetFeatureGateDuringTest(t, utilfeature.DefaultFeatureGate, features.ServerSideApply, false)
+			}
+			pvc := applyTestPVC()
+			client := fake.NewSimpleClientset(pvc)
+			newPVC := pvc.DeepCopy()
+			test.update(newPVC)
+
+			updatedPVC, err := ApplyClaimStatus(client, testOwner, pvc, newPVC, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.expectedPatches, patchTypes(client)); diff != "" {
+				t.Errorf("unexpected patches (-want +got):\n%s", diff)
+			}
+			allocated := updatedPVC.Status.AllocatedResources[v1.ResourceStorage]
+			if allocated.Cmp(resource.MustParse("2Gi")) != 0 {
+				t.Errorf("expected allocated resources 2Gi, got %s", allocated.String())
+			}
+			var conditions []v1.PersistentVolumeClaimConditionType
+			for _, c := range updatedPVC.Status.Conditions {
+				conditions = append(conditions, c.Type)
+			}
+			slices.Sort(conditions)
+			if diff := cmp.Diff(test.expectedCondition, conditions); diff != "" {
+				t.Errorf("unexpected conditions (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyClaimStatusRemovesOwnedFieldsOfOtherManagers(t *testing.T) {
+	pvc := applyTestPVC()
+	pvc.Status.Conditions = append(pvc.Status.Conditions, v1.PersistentVolumeClaimCondition{
+		Type:   v1.PersistentVolumeClaimResizing,
+		Status: v1.ConditionTrue,
+	})
+	client := fake.NewSimpleClientset(pvc)
+	newPVC := pvc.DeepCopy()
+	newPVC.Status.Conditions = newPVC.Status.Conditions[:1]
+
+	// The condition written before by another manager is left by apply and removed with a patch.
+	updatedPVC, err := ApplyClaimStatus(client, testOwner, pvc, newPVC, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]types.PatchType{types.ApplyPatchType, types.StrategicMergePatchType}, patchTypes(client)); diff != "" {
+		t.Errorf("unexpected patches (-want +got):\n%s", diff)
+	}
+	if len(updatedPVC.Status.Conditions) != 1 || updatedPVC.Status.Conditions[0].Type != v1.PersistentVolumeClaimVolumeModifyingVolume {
+		t.Errorf("expected only the condition of the other controller, got %+v", updatedPVC.Status.Conditions)
+	}
+}
+
+func TestApplyFallbackOnUnsupportedServer(t *testing.T) {
+	t.Cleanup(func() {
+		applyRejected.Clear()
+		applyNow = time.Now
+	})
+	start := time.Now()
+	applyNow = func() time.Time { return start }
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv"},
+		Spec:       v1.PersistentVolumeSpec{Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")}},
+	}
+	client := fake.NewSimpleClientset(pv)
+	client.PrependReactor("patch", "persistentvolumes", func(action core.Action) (bool, runtime.Object, error) {
+		if action.(core.PatchAction).GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		return true, nil, &apierrors.StatusError{ErrStatus: metav1.Status{
+			Status: metav1.StatusFailure,
+			Code:   415,
+			Reason: metav1.StatusReasonUnsupportedMediaType,
+		}}
+	})
+
+	for _, size := range []string{"2Gi", "3Gi"} {
+		newPV := pv.DeepCopy()
+		newPV.Spec.Capacity[v1.ResourceStorage] = resource.MustParse(size)
+		metav1.SetMetaDataAnnotation(&newPV.ObjectMeta, AnnPreResizeCapacity, "1Gi")
+		updatedPV, err := ApplyPersistentVolume(client, testOwner, pv, newPV)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		capacity := updatedPV.Spec.Capacity[v1.ResourceStorage]
+		if capacity.Cmp(resource.MustParse(size)) != 0 || updatedPV.Annotations[AnnPreResizeCapacity] != "1Gi" {
+			t.Errorf("expected PV patched to %s, got %+v", size, updatedPV)
+		}
+	}
+	// Apply is not tried again once the server rejected it.
+	expected := []types.PatchType{types.ApplyPatchType, types.StrategicMergePatchType, types.StrategicMergePatchType}
+	if diff := cmp.Diff(expected, patchTypes(client)); diff != "" {
+		t.Errorf("unexpected patches (-want +got):\n%s", diff)
+	}
+	if !useServerSideApply(claimStatusResource) {
+		t.Errorf("expected apply of the PVC status not to be affected by the rejected PV apply")
+	}
+	applyNow = func() time.Time { return start.Add(applyRetryInterval) }
+	if !useServerSideApply(pvResource) {
+		t.Errorf("expected apply of PVs to be tried again after %s", applyRetryInterval)
+	}
+}
+
+func TestApplyClaimStatusResourceVersionCheck(t *testing.T) {
+	for _, check := range []bool{false, true} {
+		pvc := applyTestPVC()
+		client := fake.NewSimpleClientset(pvc)
+		newPVC := pvc.DeepCopy()
+		newPVC.Status.AllocatedResources = v1.ResourceList{v1.ResourceStorage: resource.MustParse("2Gi")}
+
+		if _, err := ApplyClaimStatus(client, testOwner, pvc, newPVC, check); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var applies []string
+		for _, action := range client.Actions() {
+			if patch, ok := action.(core.PatchAction); ok && patch.GetPatchType() == types.ApplyPatchType {
+				applies = append(applies, string(patch.GetPatch()))
+			}
+		}
+		if len(applies) != 1 {
+			t.Fatalf("expected one apply request, got %v", client.Actions())
+		}
+		if sent := strings.Contains(applies[0], `"resourceVersion":"1"`); sent != check {
+			t.Errorf("addResourceVersionCheck %v: expected resourceVersion sent %v, got %s", check, check, applies[0])
+		}
+	}
+}
@@ -18,14 +18,32 @@ package util
 
 // These constants are PVC condition types related to resize operation.
 const (
-	VolumeResizing           = "Resizing"
-	VolumeResizeFailed       = "VolumeResizeFailed"
-	VolumeResizeSuccess      = "VolumeResizeSuccessful"
-	VolumeModify             = "VolumeModify"
-	VolumeModifyFailed       = "VolumeModifyFailed"
-	VolumeModifySuccess      = "VolumeModifySuccessful"
-	VolumeModifyCancelled    = "VolumeModifyCanceled"
-	FileSystemResizeRequired = "FileSystemResizeRequired"
+	VolumeResizing                 = "Resizing"
+	VolumeResizeFailed             = "VolumeResizeFailed"
+	VolumeResizeSuccess            = "VolumeResizeSuccessful"
+	VolumeResizeScheduled          = "VolumeResizeScheduled"
+	VolumeExpansionRecorded        = "VolumeExpansionRecorded"
+	InvalidScheduledResize         = "InvalidScheduledResize"
+	VolumeModify                   = "VolumeModify"
+	VolumeModifyFailed             = "VolumeModifyFailed"
+	VolumeModifySuccess            = "VolumeModifySuccessful"
+	VolumeModifyCancelled          = "VolumeModifyCanceled"
+	VolumeModifyDriftReconciled    = "VolumeModifyDriftReconciled"
+	VolumeModifyDriftFailed        = "VolumeModifyDriftReconcileFailed"
+	VolumeModifyPending            = "VolumeModifyPending"
+	VolumeModifyBurst              = "VolumeModifyBurst"
+	InvalidVolumeModifyBurst       = "InvalidVolumeModifyBurst"
+	FileSystemResizeRequired       = "FileSystemResizeRequired"
+	NodeExpansionOverdue           = "NodeExpansionOverdue"
+	NodeExpansionWorkloadRestarted = "NodeExpansionWorkloadRestarted"
+	NodeExpansionRestartFailed     = "NodeExpansionRestartFailed"
+	InvalidSecretTemplate          = "InvalidSecretTemplate"
+	InvalidVolumeAttributesClass   = "InvalidVolumeAttributesClass"
+	VolumeOperationWaiting         = "VolumeOperationWaiting"
+	VolumeResizeRequestApplied     = "VolumeResizeRequestApplied"
+	VolumeResizeRequestCompleted   = "VolumeResizeRequestCompleted"
+	VolumeResizeRequestFailed      = "VolumeResizeRequestFailed"
+	VolumeResizeRequestRejected    = "VolumeResizeRequestRejected"
 )
 
 const (
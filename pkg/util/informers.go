@@ -0,0 +1,117 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Scope limits the objects a resizer instance watches, so that several instances can
+// serve separate tenants of a cluster. The zero value watches all objects.
+type Scope struct {
+	// Namespace limits PVCs and Pods to a single namespace.
+	Namespace string
+	// PVCLabelSelector limits PVCs to those matching it.
+	PVCLabelSelector string
+	// PVLabelSelector limits PVs to those matching it. The API server does not support
+	// selecting PVs by the namespace of their claim, so PVs must be labeled for this.
+	PVLabelSelector string
+	// PVFieldSelector limits PVs to those matching it, for the fields that the API server
+	// supports in field selectors of PVs.
+	PVFieldSelector string
+}
+
+// Validate returns an error if a label selector of the scope cannot be parsed.
+func (s Scope) Validate() error {
+	if _, err := labels.Parse(s.PVCLabelSelector); err != nil {
+		return fmt.Errorf("invalid PVC label selector %q: %v", s.PVCLabelSelector, err)
+	}
+	if _, err := labels.Parse(s.PVLabelSelector); err != nil {
+		return fmt.Errorf("invalid PV label selector %q: %v", s.PVLabelSelector, err)
+	}
+	if _, err := fields.ParseSelector(s.PVFieldSelector); err != nil {
+		return fmt.Errorf("invalid PV field selector %q: %v", s.PVFieldSelector, err)
+	}
+	return nil
+}
+
+// Identity returns a short name of the scope that is suitable as part of object names,
+// or an empty string for the zero value.
+func (s Scope) Identity() string {
+	if s == (Scope{}) {
+		return ""
+	}
+	identity := s.Namespace
+	if s.PVCLabelSelector != "" || s.PVLabelSelector != "" || s.PVFieldSelector != "" {
+		h := fnv.New32a()
+		h.Write([]byte(s.PVCLabelSelector + "\x00" + s.PVLabelSelector))
+		if s.PVFieldSelector != "" {
+			// Keep the identities of existing scopes without a field selector.
+			h.Write([]byte("\x00" + s.PVFieldSelector))
+		}
+		if identity != "" {
+			identity += "-"
+		}
+		identity += fmt.Sprintf("%08x", h.Sum32())
+	}
+	return identity
+}
+
+// NewScopedInformerFactory returns an informer factory whose PVC, Pod and PV informers watch only
+// the objects in scope. All other informers of the factory, like the ones of VolumeAttributesClasses
+// or VolumeAttachments, watch the whole cluster.
+func NewScopedInformerFactory(kubeClient kubernetes.Interface, resyncPeriod time.Duration, scope Scope) informers.SharedInformerFactory {
+	factory := informers.NewSharedInformerFactory(kubeClient, resyncPeriod)
+	indexers := cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}
+	// The factory returns the informers registered here when the controllers ask for PVCs, Pods and PVs.
+	if scope.Namespace != "" || scope.PVCLabelSelector != "" {
+		factory.InformerFor(&v1.PersistentVolumeClaim{}, func(client kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			return coreinformers.NewFilteredPersistentVolumeClaimInformer(client, scope.Namespace, resyncPeriod, indexers,
+				selectors(scope.PVCLabelSelector, ""))
+		})
+	}
+	if scope.Namespace != "" {
+		factory.InformerFor(&v1.Pod{}, func(client kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			return coreinformers.NewFilteredPodInformer(client, scope.Namespace, resyncPeriod, indexers, nil)
+		})
+	}
+	if scope.PVLabelSelector != "" || scope.PVFieldSelector != "" {
+		factory.InformerFor(&v1.PersistentVolume{}, func(client kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			return coreinformers.NewFilteredPersistentVolumeInformer(client, resyncPeriod, indexers,
+				selectors(scope.PVLabelSelector, scope.PVFieldSelector))
+		})
+	}
+	return factory
+}
+
+func selectors(labelSelector, fieldSelector string) func(*metav1.ListOptions) {
+	return func(options *metav1.ListOptions) {
+		options.LabelSelector = labelSelector
+		options.FieldSelector = fieldSelector
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+)
+
+func TestNewScopedInformerFactory(t *testing.T) {
+	tenant := map[string]string{"tenant": "a"}
+	client := fake.NewSimpleClientset(
+		&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "in-scope", Namespace: "a", Labels: tenant}},
+		&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled", Namespace: "a"}},
+		&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "other-namespace", Namespace: "b", Labels: tenant}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-a", Namespace: "a"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-b", Namespace: "b"}},
+		&v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-a", Labels: tenant}},
+		&v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-b"}},
+		&coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: "lease-b", Namespace: "b"}},
+	)
+	scope := Scope{Namespace: "a", PVCLabelSelector: "tenant=a", PVLabelSelector: "tenant=a"}
+	if err := scope.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	factory := NewScopedInformerFactory(client, 0, scope)
+	pvcLister := factory.Core().V1().PersistentVolumeClaims().Lister()
+	podLister := factory.Core().V1().Pods().Lister()
+	pvLister := factory.Core().V1().PersistentVolumes().Lister()
+	// Other namespaced objects are not limited to the namespace of the PVCs.
+	leaseLister := factory.Coordination().V1().Leases().Lister()
+	factory.Start(t.Context().Done())
+	factory.WaitForCacheSync(t.Context().Done())
+
+	pvcs, _ := pvcLister.List(labels.Everything())
+	if len(pvcs) != 1 || pvcs[0].Name != "in-scope" {
+		t.Errorf("expected only PVC in-scope, got %v", pvcs)
+	}
+	pods, _ := podLister.List(labels.Everything())
+	if len(pods) != 1 || pods[0].Name != "pod-a" {
+		t.Errorf("expected only pod-a, got %v", pods)
+	}
+	pvs, _ := pvLister.List(labels.Everything())
+	if len(pvs) != 1 || pvs[0].Name != "pv-a" {
+		t.Errorf("expected only pv-a, got %v", pvs)
+	}
+	if leases, _ := leaseLister.List(labels.Everything()); len(leases) != 1 {
+		t.Errorf("expected leases of all namespaces, got %v", leases)
+	}
+}
+
+func TestNewScopedInformerFactoryPVFieldSelector(t *testing.T) {
+	var fieldSelectors []string
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("list", "persistentvolumes", func(action core.Action) (bool, runtime.Object, error) {
+		fieldSelectors = append(fieldSelectors, action.(core.ListAction).GetListRestrictions().Fields.String())
+		return false, nil, nil
+	})
+	scope := Scope{PVFieldSelector: "metadata.name!=pv-b"}
+	if err := scope.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	factory := NewScopedInformerFactory(client, 0, scope)
+	factory.Core().V1().PersistentVolumes().Informer()
+	factory.Start(t.Context().Done())
+	factory.WaitForCacheSync(t.Context().Done())
+
+	if len(fieldSelectors) == 0 || fieldSelectors[0] != scope.PVFieldSelector {
+		t.Errorf("expected PVs listed with field selector %q, got %v", scope.PVFieldSelector, fieldSelectors)
+	}
+	if err := (Scope{PVFieldSelector: "metadata.name"}).Validate(); err == nil {
+		t.Errorf("expected error for invalid field selector")
+	}
+}
+
+func TestScopeIdentity(t *testing.T) {
+	if id := (Scope{}).Identity(); id != "" {
+		t.Errorf("expected empty identity, got %q", id)
+	}
+	if id := (Scope{Namespace: "a"}).Identity(); id != "a" {
+		t.Errorf("expected identity a, got %q", id)
+	}
+	x := Scope{Namespace: "a", PVCLabelSelector: "tenant=x"}.Identity()
+	y := Scope{Namespace: "a", PVCLabelSelector: "tenant=y"}.Identity()
+	if x == y || x == "a" {
+		t.Errorf("expected distinct identities for different selectors, got %q and %q", x, y)
+	}
+	if err := (Scope{PVCLabelSelector: "tenant in ("}).Validate(); err == nil {
+		t.Errorf("expected error for invalid selector")
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// Keys of the PV and PVC metadata that is passed to the CSI driver.
+const (
+	PVCNameKey      = "csi.storage.k8s.io/pvc/name"
+	PVCNamespaceKey = "csi.storage.k8s.io/pvc/namespace"
+	PVNameKey       = "csi.storage.k8s.io/pv/name"
+	// PVCLabelKeyPrefix and PVCAnnotationKeyPrefix are followed by the key of the label or annotation.
+	PVCLabelKeyPrefix      = "csi.storage.k8s.io/pvc/label/"
+	PVCAnnotationKeyPrefix = "csi.storage.k8s.io/pvc/annotation/"
+)
+
+// ExtraMetadata selects the PVC labels and annotations that are passed to the CSI driver
+// together with the names of the PVC and PV. The zero value passes only the names.
+type ExtraMetadata struct {
+	PVCLabels      []string
+	PVCAnnotations []string
+}
+
+// Parameters returns the metadata of pvc and pv. Labels and annotations that are not set are omitted.
+func (m ExtraMetadata) Parameters(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) map[string]string {
+	params := map[string]string{
+		PVCNameKey:      pvc.Name,
+		PVCNamespaceKey: pvc.Namespace,
+		PVNameKey:       pv.Name,
+	}
+	for _, key := range m.PVCLabels {
+		if value, ok := pvc.Labels[key]; ok {
+			params[PVCLabelKeyPrefix+key] = value
+		}
+	}
+	for _, key := range m.PVCAnnotations {
+		if value, ok := pvc.Annotations[key]; ok {
+			params[PVCAnnotationKeyPrefix+key] = value
+		}
+	}
+	return params
+}
+
+// MetadataParameters maps allowlisted labels and annotations of PVCs and their PVs to parameters
+// of ControllerModifyVolume, each under Prefix followed by its key. A nil *MetadataParameters maps nothing.
+type MetadataParameters struct {
+	Prefix         string
+	PVCLabels      []string
+	PVCAnnotations []string
+	PVLabels       []string
+	PVAnnotations  []string
+}
+
+// Parameters returns the parameters of pvc and pv. Values of the PVC take precedence over values of the PV
+// with the same key, labels take precedence over annotations.
+func (m *MetadataParameters) Parameters(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) map[string]string {
+	if m == nil {
+		return nil
+	}
+	params := map[string]string{}
+	m.add(params, m.PVAnnotations, pv.Annotations)
+	m.add(params, m.PVLabels, pv.Labels)
+	m.add(params, m.PVCAnnotations, pvc.Annotations)
+	m.add(params, m.PVCLabels, pvc.Labels)
+	return params
+}
+
+// Changed returns true if an allowlisted label or annotation differs between oldPVC and newPVC.
+func (m *MetadataParameters) Changed(oldPVC, newPVC *v1.PersistentVolumeClaim) bool {
+	if m == nil {
+		return false
+	}
+	for _, key := range m.PVCLabels {
+		if oldPVC.Labels[key] != newPVC.Labels[key] {
+			return true
+		}
+	}
+	for _, key := range m.PVCAnnotations {
+		if oldPVC.Annotations[key] != newPVC.Annotations[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchesPV returns true if labels or annotations of PVs are mapped.
+func (m *MetadataParameters) WatchesPV() bool {
+	return m != nil && len(m.PVLabels)+len(m.PVAnnotations) > 0
+}
+
+// PVChanged returns true if an allowlisted label or annotation differs between oldPV and newPV.
+func (m *MetadataParameters) PVChanged(oldPV, newPV *v1.PersistentVolume) bool {
+	if m == nil {
+		return false
+	}
+	for _, key := range m.PVLabels {
+		if oldPV.Labels[key] != newPV.Labels[key] {
+			return true
+		}
+	}
+	for _, key := range m.PVAnnotations {
+		if oldPV.Annotations[key] != newPV.Annotations[key] {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MetadataParameters) add(params map[string]string, keys []string, values map[string]string) {
+	for _, key := range keys {
+		if value, ok := values[key]; ok {
+			params[m.Prefix+key] = value
+		}
+	}
+}
+
+// HashParameters returns a short hash of params, or "" if there are none.
+func HashParameters(params map[string]string) string {
+	if len(params) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		// Keys cannot contain NUL, values are separated by their length.
+		fmt.Fprintf(h, "%s\x00%d:%s", key, len(params[key]), params[key])
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
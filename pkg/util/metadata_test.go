@@ -0,0 +1,111 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExtraMetadataParameters(t *testing.T) {
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-1"}}
+	pvc := &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:        "data",
+		Namespace:   "tenant-a",
+		Labels:      map[string]string{"app": "db", "team": "storage"},
+		Annotations: map[string]string{"example.com/owner": "alice"},
+	}}
+	names := map[string]string{
+		PVCNameKey:      "data",
+		PVCNamespaceKey: "tenant-a",
+		PVNameKey:       "pv-1",
+	}
+
+	if params := (ExtraMetadata{}).Parameters(pvc, pv); !reflect.DeepEqual(params, names) {
+		t.Errorf("expected only names, got %v", params)
+	}
+
+	m := ExtraMetadata{
+		PVCLabels:      []string{"app", "missing"},
+		PVCAnnotations: []string{"example.com/owner"},
+	}
+	expected := map[string]string{
+		PVCNameKey:                "data",
+		PVCNamespaceKey:           "tenant-a",
+		PVNameKey:                 "pv-1",
+		PVCLabelKeyPrefix + "app": "db",
+		PVCAnnotationKeyPrefix + "example.com/owner": "alice",
+	}
+	if params := m.Parameters(pvc, pv); !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected %v, got %v", expected, params)
+	}
+}
+
+func TestMetadataParameters(t *testing.T) {
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{
+		Name:   "pv-1",
+		Labels: map[string]string{"environment": "staging", "zone": "a"},
+	}}
+	pvc := &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:        "data",
+		Namespace:   "tenant-a",
+		Labels:      map[string]string{"environment": "prod"},
+		Annotations: map[string]string{"example.com/cost-center": "1234"},
+	}}
+	m := &MetadataParameters{
+		Prefix:         "qos/",
+		PVCLabels:      []string{"environment"},
+		PVCAnnotations: []string{"example.com/cost-center"},
+		PVLabels:       []string{"environment", "zone", "missing"},
+	}
+	expected := map[string]string{
+		"qos/environment":             "prod",
+		"qos/zone":                    "a",
+		"qos/example.com/cost-center": "1234",
+	}
+	params := m.Parameters(pvc, pv)
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected %v, got %v", expected, params)
+	}
+
+	changed := pvc.DeepCopy()
+	if m.Changed(pvc, changed) {
+		t.Errorf("expected no change")
+	}
+	changed.Labels["unrelated"] = "x"
+	if m.Changed(pvc, changed) {
+		t.Errorf("expected change of other labels to be ignored")
+	}
+	changed.Annotations["example.com/cost-center"] = "5678"
+	if !m.Changed(pvc, changed) {
+		t.Errorf("expected changed annotation to be noticed")
+	}
+	if HashParameters(params) == HashParameters(m.Parameters(changed, pv)) {
+		t.Errorf("expected hash to change")
+	}
+	if HashParameters(nil) != "" || HashParameters(map[string]string{"a": "b=c"}) == HashParameters(map[string]string{"a=b": "c"}) {
+		t.Errorf("unexpected hash")
+	}
+
+	var nilParams *MetadataParameters
+	if nilParams.Parameters(pvc, pv) != nil || nilParams.Changed(pvc, changed) {
+		t.Errorf("expected nil MetadataParameters to map nothing")
+	}
+}
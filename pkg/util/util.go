@@ -50,8 +50,30 @@ var (
 	AnnPreResizeCapacity = "volume.alpha.kubernetes.io/pre-resize-capacity"
 
 	NodeExpansionNotRequired = "volume.kubernetes.io/node-expansion-not-required"
+
+	// AnnScheduledSize and AnnScheduledTime request an expansion of a PVC to the given size
+	// at the given RFC 3339 time. The external-resizer sets the requested size of the PVC when
+	// the time is reached and removes both annotations. Removing them cancels the expansion.
+	AnnScheduledSize = "resizer.csi.k8s.io/scheduled-size"
+	AnnScheduledTime = "resizer.csi.k8s.io/scheduled-time"
+
+	// AnnBurstVAC, AnnBurstStart and AnnBurstEnd request a temporary change of the
+	// VolumeAttributesClass of a PVC from the optional RFC 3339 start time until the end time.
+	// The external-resizer records the VolumeAttributesClass to return to in AnnBurstRevertVAC.
+	AnnBurstVAC       = "resizer.csi.k8s.io/burst-vac"
+	AnnBurstStart     = "resizer.csi.k8s.io/burst-start"
+	AnnBurstEnd       = "resizer.csi.k8s.io/burst-end"
+	AnnBurstRevertVAC = "resizer.csi.k8s.io/burst-revert-vac"
+
+	// AnnModifyMetadataParameters is set on a PV to a hash of the parameters from labels and annotations
+	// that were passed to its last successful modification, so that changes of them are carried out.
+	AnnModifyMetadataParameters = "resizer.csi.k8s.io/modify-metadata-parameters"
 )
 
+// ModifyVolumePending is the PVC condition set by the modify controller to explain why a modification
+// is held in Pending state.
+const ModifyVolumePending v1.PersistentVolumeClaimConditionType = "ModifyVolumePending"
+
 // MergeResizeConditionsOfPVC updates pvc with requested resize conditions
 // leaving other conditions untouched.
 func MergeResizeConditionsOfPVC(oldConditions, newConditions []v1.PersistentVolumeClaimCondition, keepOldResizeConditions bool) []v1.PersistentVolumeClaimCondition {
@@ -227,6 +249,22 @@ func PatchClaim(kubeClient kubernetes.Interface, oldPVC, newPVC *v1.PersistentVo
 	return updatedClaim, nil
 }
 
+// PatchClaimMetadata patches the metadata and spec of a PVC with changes from newPVC.
+// Status changes must be written with PatchClaim. If addResourceVersionCheck is true
+// the patch fails when the PVC was changed since oldPVC was read.
+func PatchClaimMetadata(kubeClient kubernetes.Interface, oldPVC, newPVC *v1.PersistentVolumeClaim, addResourceVersionCheck bool) (*v1.PersistentVolumeClaim, error) {
+	patchBytes, err := GetPVCPatchData(oldPVC, newPVC, addResourceVersionCheck)
+	if err != nil {
+		return oldPVC, fmt.Errorf("can't patch PVC %s as generate path data failed: %v", klog.KObj(oldPVC), err)
+	}
+	updatedClaim, updateErr := kubeClient.CoreV1().PersistentVolumeClaims(oldPVC.Namespace).
+		Patch(context.TODO(), oldPVC.Name, types.StrategicMergePatchType, patchBytes, metav1.PatchOptions{})
+	if updateErr != nil {
+		return oldPVC, fmt.Errorf("can't patch PVC %s with %v", klog.KObj(oldPVC), updateErr)
+	}
+	return updatedClaim, nil
+}
+
 func PatchPersistentVolume(kubeClient kubernetes.Interface, oldPV, newPV *v1.PersistentVolume) (*v1.PersistentVolume, error) {
 	patchBytes, err := GetPatchData(oldPV, newPV)
 	if err != nil {
@@ -0,0 +1,211 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package vacschema validates the parameters of VolumeAttributesClasses against a schema
+// published by the CSI driver, before they are passed to ControllerModifyVolume.
+package vacschema
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// Types of parameters.
+const (
+	TypeString   = "string"
+	TypeInteger  = "integer"
+	TypeNumber   = "number"
+	TypeBoolean  = "boolean"
+	TypeQuantity = "quantity"
+)
+
+// ReasonInvalidParameters is the reason of the ModifyVolumePending condition of PVCs
+// whose VolumeAttributesClass does not match the schema.
+const ReasonInvalidParameters = "InvalidVolumeAttributesClassParameters"
+
+// reservedPrefix is the prefix of parameters that are meant for the sidecars, not for the driver.
+const reservedPrefix = "csi.storage.k8s.io/"
+
+// Schema describes the parameters of VolumeAttributesClasses that a driver accepts.
+type Schema struct {
+	// Parameters maps parameter keys to their schema.
+	Parameters map[string]ParameterSchema `json:"parameters"`
+	// AllowUnknownParameters accepts parameters that are not in Parameters.
+	AllowUnknownParameters bool `json:"allowUnknownParameters,omitempty"`
+}
+
+// ParameterSchema describes the values of a parameter.
+type ParameterSchema struct {
+	// Type is one of string, integer, number, boolean and quantity. Empty means string.
+	Type string `json:"type,omitempty"`
+	// Required parameters must be set in every VolumeAttributesClass.
+	Required bool `json:"required,omitempty"`
+	// Enum lists the allowed values, if not empty.
+	Enum []string `json:"enum,omitempty"`
+	// Minimum and Maximum limit integer, number and quantity values, inclusive.
+	Minimum string `json:"minimum,omitempty"`
+	Maximum string `json:"maximum,omitempty"`
+	// Pattern is a regular expression that string values must match.
+	Pattern string `json:"pattern,omitempty"`
+
+	pattern  *regexp.Regexp
+	min, max *float64
+}
+
+// Parse parses a YAML or JSON schema.
+func Parse(data []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := yaml.UnmarshalStrict(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse parameter schema: %v", err)
+	}
+	for key, p := range s.Parameters {
+		if err := p.compile(); err != nil {
+			return nil, fmt.Errorf("invalid schema of parameter %q: %v", key, err)
+		}
+		s.Parameters[key] = p
+	}
+	return s, nil
+}
+
+func (p *ParameterSchema) compile() error {
+	switch p.Type {
+	case "":
+		p.Type = TypeString
+	case TypeString, TypeInteger, TypeNumber, TypeBoolean, TypeQuantity:
+	default:
+		return fmt.Errorf("unknown type %q", p.Type)
+	}
+	if p.Pattern != "" {
+		if p.Type != TypeString {
+			return fmt.Errorf("pattern is only supported for strings")
+		}
+		var err error
+		if p.pattern, err = regexp.Compile("^(?:" + p.Pattern + ")$"); err != nil {
+			return err
+		}
+	}
+	for _, limit := range []struct {
+		value string
+		into  **float64
+	}{{p.Minimum, &p.min}, {p.Maximum, &p.max}} {
+		if limit.value == "" {
+			continue
+		}
+		if p.Type == TypeString || p.Type == TypeBoolean {
+			return fmt.Errorf("minimum and maximum are not supported for %ss", p.Type)
+		}
+		v, err := p.number(limit.value)
+		if err != nil {
+			return fmt.Errorf("invalid limit %q: %v", limit.value, err)
+		}
+		*limit.into = &v
+	}
+	for _, value := range p.Enum {
+		if err := p.validate(value); err != nil {
+			return fmt.Errorf("invalid enum value %q: %v", value, err)
+		}
+	}
+	return nil
+}
+
+// Validate returns a description of every parameter of params that does not match the schema,
+// sorted by key. A nil schema accepts everything.
+func (s *Schema) Validate(params map[string]string) []string {
+	if s == nil {
+		return nil
+	}
+	var errs []string
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		if strings.HasPrefix(key, reservedPrefix) {
+			continue
+		}
+		p, ok := s.Parameters[key]
+		if !ok {
+			if !s.AllowUnknownParameters {
+				errs = append(errs, fmt.Sprintf("unknown parameter %q", key))
+			}
+			continue
+		}
+		if err := p.validate(params[key]); err != nil {
+			errs = append(errs, fmt.Sprintf("parameter %q: %v", key, err))
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(s.Parameters)) {
+		if _, ok := params[key]; !ok && s.Parameters[key].Required {
+			errs = append(errs, fmt.Sprintf("missing required parameter %q", key))
+		}
+	}
+	return errs
+}
+
+func (p *ParameterSchema) validate(value string) error {
+	if len(p.Enum) > 0 && !slices.Contains(p.Enum, value) {
+		return fmt.Errorf("%q is not one of %s", value, strings.Join(p.Enum, ", "))
+	}
+	switch p.Type {
+	case TypeBoolean:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+	case TypeString:
+		if p.pattern != nil && !p.pattern.MatchString(value) {
+			return fmt.Errorf("%q does not match %q", value, p.Pattern)
+		}
+	default:
+		v, err := p.number(value)
+		if err != nil {
+			return err
+		}
+		if p.min != nil && v < *p.min {
+			return fmt.Errorf("%s is less than the minimum %s", value, p.Minimum)
+		}
+		if p.max != nil && v > *p.max {
+			return fmt.Errorf("%s is greater than the maximum %s", value, p.Maximum)
+		}
+	}
+	return nil
+}
+
+// number parses an integer, number or quantity value.
+func (p *ParameterSchema) number(value string) (float64, error) {
+	switch p.Type {
+	case TypeInteger:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not an integer", value)
+		}
+		return float64(v), nil
+	case TypeQuantity:
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a quantity", value)
+		}
+		return q.AsApproximateFloat64(), nil
+	default:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", value)
+		}
+		return v, nil
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vacschema
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testSchema = `
+parameters:
+  iops:
+    type: integer
+    required: true
+    minimum: "3000"
+    maximum: "16000"
+  throughput:
+    type: quantity
+    maximum: 1Gi
+  tier:
+    enum: [gold, silver]
+  encrypted:
+    type: boolean
+  name:
+    pattern: "[a-z]+"
+`
+
+func TestValidate(t *testing.T) {
+	schema, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	tests := []struct {
+		name   string
+		params map[string]string
+		errs   []string
+	}{
+		{
+			name:   "valid",
+			params: map[string]string{"iops": "3000", "throughput": "500Mi", "tier": "gold", "encrypted": "true", "name": "abc"},
+		},
+		{
+			name:   "reserved parameters are ignored",
+			params: map[string]string{"iops": "16000", "csi.storage.k8s.io/controller-modify-secret-name": "secret"},
+		},
+		{
+			name:   "unknown",
+			params: map[string]string{"iops": "3000", "ipos": "3000"},
+			errs:   []string{`unknown parameter "ipos"`},
+		},
+		{
+			name:   "missing required",
+			params: map[string]string{"tier": "gold"},
+			errs:   []string{`missing required parameter "iops"`},
+		},
+		{
+			name:   "invalid values",
+			params: map[string]string{"iops": "2000", "throughput": "2Gi", "tier": "bronze", "encrypted": "maybe", "name": "ABC"},
+			errs: []string{
+				`parameter "encrypted": "maybe" is not a boolean`,
+				`parameter "iops": 2000 is less than the minimum 3000`,
+				`parameter "name": "ABC" does not match "[a-z]+"`,
+				`parameter "throughput": 2Gi is greater than the maximum 1Gi`,
+				`parameter "tier": "bronze" is not one of gold, silver`,
+			},
+		},
+		{
+			name:   "wrong type",
+			params: map[string]string{"iops": "3k"},
+			errs:   []string{`parameter "iops": "3k" is not an integer`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if errs := schema.Validate(test.params); !reflect.DeepEqual(errs, test.errs) {
+				t.Errorf("expected %q, got %q", test.errs, errs)
+			}
+		})
+	}
+
+	var nilSchema *Schema
+	if errs := nilSchema.Validate(map[string]string{"anything": "x"}); errs != nil {
+		t.Errorf("expected nil schema to accept everything, got %v", errs)
+	}
+	unknown := &Schema{AllowUnknownParameters: true}
+	if errs := unknown.Validate(map[string]string{"anything": "x"}); errs != nil {
+		t.Errorf("expected unknown parameters to be allowed, got %v", errs)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, schema := range []string{
+		"parameters: {iops: {type: float}}",
+		"parameters: {iops: {type: boolean, minimum: '1'}}",
+		"parameters: {iops: {type: integer, minimum: 'one'}}",
+		"parameters: {iops: {type: integer, pattern: '[0-9]+'}}",
+		"parameters: {tier: {pattern: '['}}",
+		"parameters: {iops: {type: integer, enum: [fast]}}",
+		"parameters: {iops: {typo: integer}}",
+	} {
+		if _, err := Parse([]byte(schema)); err == nil {
+			t.Errorf("expected error for %s", schema)
+		}
+	}
+}
+
+func TestSources(t *testing.T) {
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "schema", Namespace: "kube-system"},
+		Data:       map[string]string{SchemaKey: testSchema},
+	}
+	client := fake.NewSimpleClientset(cm)
+	source, err := NewConfigMapSource(client, "kube-system", "schema", 0)
+	if err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+	changes := make(chan struct{}, 10)
+	source.AddHandler(func() { changes <- struct{}{} })
+	source.Run(t.Context())
+	if !source.HasSynced() {
+		t.Fatalf("expected source to be synced")
+	}
+	if schema := source.Schema(); schema == nil || len(schema.Parameters) != 5 {
+		t.Fatalf("expected schema from ConfigMap, got %+v", schema)
+	}
+
+	cm = cm.DeepCopy()
+	cm.ResourceVersion = "2"
+	cm.Data[SchemaKey] = "parameters: {iops: {type: float}}"
+	if _, err := client.CoreV1().ConfigMaps("kube-system").Update(t.Context(), cm, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("failed to update ConfigMap: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for (source.Schema() != nil || len(changes) < 2) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if source.Schema() != nil {
+		t.Errorf("expected invalid schema to be ignored")
+	}
+	if len(changes) != 2 {
+		t.Errorf("expected handler called for the initial and the changed schema, got %d calls", len(changes))
+	}
+
+	// A ConfigMap without a schema does not hold back modifications with unknown parameters.
+	for i, data := range []map[string]string{{SchemaKey: testSchema}, {}} {
+		cm = cm.DeepCopy()
+		cm.ResourceVersion = strconv.Itoa(3 + i)
+		cm.Data = data
+		if _, err := client.CoreV1().ConfigMaps("kube-system").Update(t.Context(), cm, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("failed to update ConfigMap: %v", err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for (source.Schema() == nil) == (len(data) > 0) && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if source.Schema() != nil {
+		t.Errorf("expected no schema without the %s key", SchemaKey)
+	}
+
+	value, set := testSchema, true
+	annotation := NewAnnotationSource(func() (string, bool) { return value, set })
+	first := annotation.Schema()
+	if first == nil || annotation.Schema() != first {
+		t.Errorf("expected schema from annotation to be parsed once")
+	}
+	var calls int
+	annotation.AddHandler(func() { calls++ })
+	annotation.Changed()
+	set = false
+	if annotation.Schema() != nil {
+		t.Errorf("expected no schema without annotation")
+	}
+	annotation.Changed()
+	if calls != 1 {
+		t.Errorf("expected handler called once for the removed annotation, got %d calls", calls)
+	}
+	value, set = " ", true
+	if annotation.Schema() != nil {
+		t.Errorf("expected no schema with an empty annotation")
+	}
+}
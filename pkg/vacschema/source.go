@@ -0,0 +1,211 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vacschema
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// SchemaKey is the key of the schema in the ConfigMap.
+	SchemaKey = "schema.yaml"
+	// AnnSchema is the annotation of the CSIDriver object with the schema.
+	AnnSchema = "resizer.csi.k8s.io/vac-parameter-schema"
+)
+
+// Source provides the current schema of a driver.
+type Source interface {
+	// Schema returns the schema, or nil if the driver publishes none or it is invalid.
+	Schema() *Schema
+	// HasSynced returns true once the schema was read for the first time.
+	HasSynced() bool
+	// AddHandler registers handler to be called after the schema changed.
+	AddHandler(handler func())
+}
+
+// ConfigMapSource is a Source backed by a schema stored in a ConfigMap under SchemaKey.
+// It follows changes of the ConfigMap.
+type ConfigMapSource struct {
+	factory  informers.SharedInformerFactory
+	synced   cache.InformerSynced
+	schema   atomic.Pointer[Schema]
+	handlers []func()
+}
+
+// NewConfigMapSource returns a ConfigMapSource that watches only the named ConfigMap.
+func NewConfigMapSource(kubeClient kubernetes.Interface, namespace, name string, resyncPeriod time.Duration) (*ConfigMapSource, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(kubeClient, resyncPeriod,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	s := &ConfigMapSource{factory: factory}
+	informer := factory.Core().V1().ConfigMaps().Informer()
+	s.synced = informer.HasSynced
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    s.update,
+		UpdateFunc: s.updated,
+		DeleteFunc: func(interface{}) { s.store(nil) },
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Run starts watching the ConfigMap.
+func (s *ConfigMapSource) Run(ctx context.Context) {
+	s.factory.Start(ctx.Done())
+	cache.WaitForCacheSync(ctx.Done(), s.synced)
+}
+
+func (s *ConfigMapSource) updated(oldObj, newObj interface{}) {
+	oldCM, ok := oldObj.(*v1.ConfigMap)
+	if !ok {
+		return
+	}
+	newCM, ok := newObj.(*v1.ConfigMap)
+	if !ok || newCM.ResourceVersion == oldCM.ResourceVersion {
+		// Resyncs don't change the schema.
+		return
+	}
+	s.update(newCM)
+}
+
+func (s *ConfigMapSource) update(obj interface{}) {
+	cm, ok := obj.(*v1.ConfigMap)
+	if !ok {
+		return
+	}
+	value := cm.Data[SchemaKey]
+	if strings.TrimSpace(value) == "" {
+		// An empty schema would hold all modifications as unknown parameters.
+		klog.ErrorS(nil, "Ignoring VolumeAttributesClass parameter schema without parameters", "configMap", klog.KObj(cm), "key", SchemaKey)
+		s.store(nil)
+		return
+	}
+	schema, err := Parse([]byte(value))
+	if err != nil {
+		// Don't block modifications because of a broken schema.
+		klog.ErrorS(err, "Ignoring invalid VolumeAttributesClass parameter schema", "configMap", klog.KObj(cm))
+		s.store(nil)
+		return
+	}
+	klog.V(2).InfoS("Loaded VolumeAttributesClass parameter schema", "configMap", klog.KObj(cm), "parameters", len(schema.Parameters))
+	s.store(schema)
+}
+
+func (s *ConfigMapSource) store(schema *Schema) {
+	s.schema.Store(schema)
+	for _, handler := range s.handlers {
+		handler()
+	}
+}
+
+func (s *ConfigMapSource) Schema() *Schema {
+	return s.schema.Load()
+}
+
+// HasSynced returns true once the ConfigMap was listed.
+func (s *ConfigMapSource) HasSynced() bool {
+	return s.synced()
+}
+
+// AddHandler registers handler to be called after the ConfigMap changed. It must be called before Run.
+func (s *ConfigMapSource) AddHandler(handler func()) {
+	s.handlers = append(s.handlers, handler)
+}
+
+// AnnotationSource is a Source backed by a schema in an annotation, like AnnSchema of the CSIDriver object.
+// Changed must be called when the annotation may have changed.
+type AnnotationSource struct {
+	annotation func() (string, bool)
+
+	mutex    sync.Mutex
+	last     string
+	schema   *Schema
+	observed string
+	set      bool
+	handlers []func()
+}
+
+// NewAnnotationSource returns an AnnotationSource that reads the schema with annotation,
+// which returns the value of the annotation and whether it is set.
+func NewAnnotationSource(annotation func() (string, bool)) *AnnotationSource {
+	s := &AnnotationSource{annotation: annotation}
+	s.observed, s.set = annotation()
+	return s
+}
+
+// HasSynced returns true, the annotation is read when the schema is needed.
+func (s *AnnotationSource) HasSynced() bool {
+	return true
+}
+
+// AddHandler registers handler to be called after the annotation changed.
+func (s *AnnotationSource) AddHandler(handler func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.handlers = append(s.handlers, handler)
+}
+
+// Changed calls the handlers if the annotation changed since the last call.
+func (s *AnnotationSource) Changed() {
+	value, set := s.annotation()
+	s.mutex.Lock()
+	changed := value != s.observed || set != s.set
+	s.observed, s.set = value, set
+	handlers := s.handlers
+	s.mutex.Unlock()
+	if !changed {
+		return
+	}
+	for _, handler := range handlers {
+		handler()
+	}
+}
+
+func (s *AnnotationSource) Schema() *Schema {
+	value, ok := s.annotation()
+	if !ok || strings.TrimSpace(value) == "" {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if value == s.last {
+		return s.schema
+	}
+	schema, err := Parse([]byte(value))
+	if err != nil {
+		// Don't block modifications because of a broken schema.
+		klog.ErrorS(err, "Ignoring invalid VolumeAttributesClass parameter schema", "annotation", AnnSchema)
+	}
+	s.last, s.schema = value, schema
+	return schema
+}
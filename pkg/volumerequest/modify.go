@@ -0,0 +1,357 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volumerequest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+)
+
+// LabelPersistentVolumeClaimUID is set on VolumeModifyRequests to the UID of their PVC.
+const LabelPersistentVolumeClaimUID = "resizer.csi.k8s.io/pvc-uid"
+
+// Reasons of the status of VolumeModifyRequests.
+const (
+	reasonSuperseded = "Superseded"
+	reasonRolledBack = "RolledBack"
+)
+
+// ModifyHistory records each VolumeAttributesClass change of a PVC in a VolumeModifyRequest,
+// because the PVC only keeps the status of the last change. The modify controller tells it
+// about every status it writes and every ControllerModifyVolume call. Failures to record are
+// logged and don't affect the modification. A nil ModifyHistory records nothing.
+type ModifyHistory struct {
+	client dynamic.NamespaceableResourceInterface
+	// limit is the number of finished requests kept per PVC.
+	limit int
+	now   func() time.Time
+	// open maps keys of PVCs to their unfinished request.
+	open sync.Map
+}
+
+// NewModifyHistory returns a ModifyHistory that keeps up to limit finished requests per PVC.
+func NewModifyHistory(client dynamic.Interface, limit int) *ModifyHistory {
+	return &ModifyHistory{
+		client: client.Resource(VolumeModifyRequestResource),
+		limit:  limit,
+		now:    time.Now,
+	}
+}
+
+// Attempted records a ControllerModifyVolume call that modified the volume of pvc to targetVAC.
+func (h *ModifyHistory) Attempted(ctx context.Context, pvc *v1.PersistentVolumeClaim, targetVAC string, err error) {
+	if h == nil {
+		return
+	}
+	request, recordErr := h.openRequest(ctx, pvc, targetVAC)
+	if recordErr != nil {
+		klog.ErrorS(recordErr, "Failed to record modification attempt", "PVC", klog.KObj(pvc))
+		return
+	}
+	newRequest := request.DeepCopy()
+	newRequest.Status.Phase = PhaseInProgress
+	newRequest.Status.Attempts++
+	newRequest.Status.LastErrorCode = ""
+	if err != nil {
+		newRequest.Status.LastErrorCode = status.Code(err).String()
+	}
+	h.update(ctx, pvc, request, newRequest)
+}
+
+// Observe records the ModifyVolumeStatus of pvc, after the modify controller wrote it.
+func (h *ModifyHistory) Observe(ctx context.Context, pvc *v1.PersistentVolumeClaim) {
+	if h == nil {
+		return
+	}
+	specVAC := ptr.Deref(pvc.Spec.VolumeAttributesClassName, "")
+	currentVAC := ptr.Deref(pvc.Status.CurrentVolumeAttributesClassName, "")
+	modifyStatus := pvc.Status.ModifyVolumeStatus
+	if modifyStatus == nil || (specVAC == "" && modifyStatus.Status != v1.PersistentVolumeClaimModifyVolumeInProgress) {
+		// The modification completed, or it was rolled back by clearing the VAC of the PVC.
+		request, err := h.lookup(ctx, pvc)
+		if err != nil {
+			klog.ErrorS(err, "Failed to record modification", "PVC", klog.KObj(pvc))
+			return
+		}
+		if request == nil {
+			return
+		}
+		newRequest := request.DeepCopy()
+		if modifyStatus == nil && currentVAC == request.Spec.VolumeAttributesClassName {
+			h.finish(newRequest, PhaseCompleted, "", fmt.Sprintf("Modified volume to %q.", currentVAC))
+		} else {
+			h.cancel(newRequest, reasonRolledBack, "The VolumeAttributesClass of the PVC was removed.")
+		}
+		h.update(ctx, pvc, request, newRequest)
+		return
+	}
+
+	targetVAC := modifyStatus.TargetVolumeAttributesClassName
+	if modifyStatus.Status == v1.PersistentVolumeClaimModifyVolumePending {
+		// Pending modifications don't always record their target.
+		targetVAC = specVAC
+	}
+	request, err := h.openRequest(ctx, pvc, targetVAC)
+	if err != nil {
+		klog.ErrorS(err, "Failed to record modification", "PVC", klog.KObj(pvc))
+		return
+	}
+	newRequest := request.DeepCopy()
+	newRequest.Status.Reason, newRequest.Status.Message = "", ""
+	if modifyStatus.Status == v1.PersistentVolumeClaimModifyVolumePending {
+		newRequest.Status.Phase = PhasePending
+		if c := condition(pvc, util.ModifyVolumePending); c != nil {
+			newRequest.Status.Reason, newRequest.Status.Message = c.Reason, c.Message
+		}
+	} else {
+		newRequest.Status.Phase = PhaseInProgress
+		if c := condition(pvc, v1.PersistentVolumeClaimVolumeModifyVolumeError); c != nil {
+			newRequest.Status.Reason, newRequest.Status.Message = c.Reason, c.Message
+		}
+	}
+	h.update(ctx, pvc, request, newRequest)
+}
+
+// Forget drops the cached request of the PVC with key. The requests themselves are deleted
+// by the garbage collector together with the PVC.
+func (h *ModifyHistory) Forget(key string) {
+	if h == nil {
+		return
+	}
+	h.open.Delete(key)
+}
+
+// openRequest returns the unfinished request of pvc to targetVAC. An unfinished request to
+// another VAC is finished as superseded first, and a new request is created if needed.
+func (h *ModifyHistory) openRequest(ctx context.Context, pvc *v1.PersistentVolumeClaim, targetVAC string) (*VolumeModifyRequest, error) {
+	request, err := h.lookup(ctx, pvc)
+	if err != nil {
+		return nil, err
+	}
+	if request != nil && request.Spec.VolumeAttributesClassName == targetVAC {
+		return request, nil
+	}
+	if request != nil {
+		newRequest := request.DeepCopy()
+		h.cancel(newRequest, reasonSuperseded, fmt.Sprintf("The PVC was changed to VolumeAttributesClass %q.", targetVAC))
+		h.update(ctx, pvc, request, newRequest)
+	}
+	return h.create(ctx, pvc, targetVAC)
+}
+
+// lookup returns the unfinished request of pvc, or nil if it has none.
+func (h *ModifyHistory) lookup(ctx context.Context, pvc *v1.PersistentVolumeClaim) (*VolumeModifyRequest, error) {
+	key := cache.MetaObjectToName(pvc).String()
+	if obj, ok := h.open.Load(key); ok {
+		if request := obj.(*VolumeModifyRequest); request.Labels[LabelPersistentVolumeClaimUID] == string(pvc.UID) {
+			return request, nil
+		}
+		h.open.Delete(key)
+	}
+	requests, err := h.list(ctx, pvc)
+	if err != nil {
+		return nil, err
+	}
+	for _, request := range requests {
+		if !request.Status.Phase.Finished() {
+			if request.Status.StartTime == nil {
+				// Setting the start time after the create failed.
+				request.Status.StartTime = ptr.To(request.CreationTimestamp)
+			}
+			h.open.Store(key, request)
+			return request, nil
+		}
+	}
+	return nil, nil
+}
+
+// list returns the requests of pvc, newest first.
+func (h *ModifyHistory) list(ctx context.Context, pvc *v1.PersistentVolumeClaim) ([]*VolumeModifyRequest, error) {
+	list, err := h.client.Namespace(pvc.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set{LabelPersistentVolumeClaimUID: string(pvc.UID)}.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VolumeModifyRequests of PVC %s: %w", klog.KObj(pvc), err)
+	}
+	requests := make([]*VolumeModifyRequest, 0, len(list.Items))
+	for i := range list.Items {
+		request := &VolumeModifyRequest{}
+		if err := fromUnstructured(&list.Items[i], request); err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	slices.SortFunc(requests, func(a, b *VolumeModifyRequest) int {
+		return b.CreationTimestamp.Compare(a.CreationTimestamp.Time)
+	})
+	return requests, nil
+}
+
+// create creates a request of pvc to targetVAC, owned by the PVC.
+func (h *ModifyHistory) create(ctx context.Context, pvc *v1.PersistentVolumeClaim, targetVAC string) (*VolumeModifyRequest, error) {
+	generateName := pvc.Name
+	if len(generateName) > 240 {
+		generateName = generateName[:240]
+	}
+	request := &VolumeModifyRequest{
+		TypeMeta: metav1.TypeMeta{APIVersion: GroupVersion.String(), Kind: "VolumeModifyRequest"},
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: generateName + "-",
+			Namespace:    pvc.Namespace,
+			Labels:       map[string]string{LabelPersistentVolumeClaimUID: string(pvc.UID)},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "PersistentVolumeClaim",
+				Name:       pvc.Name,
+				UID:        pvc.UID,
+			}},
+		},
+		Spec: VolumeModifyRequestSpec{
+			PersistentVolumeClaimName:         pvc.Name,
+			VolumeAttributesClassName:         targetVAC,
+			PreviousVolumeAttributesClassName: ptr.Deref(pvc.Status.CurrentVolumeAttributesClassName, ""),
+		},
+	}
+	u, err := toUnstructured(request)
+	if err != nil {
+		return nil, err
+	}
+	created, err := h.client.Namespace(pvc.Namespace).Create(ctx, u, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create VolumeModifyRequest of PVC %s: %w", klog.KObj(pvc), err)
+	}
+	request = &VolumeModifyRequest{}
+	if err := fromUnstructured(created, request); err != nil {
+		return nil, err
+	}
+	// The status is not written on create.
+	request.Status.StartTime = ptr.To(metav1.NewTime(h.now()))
+	if u, err = toUnstructured(request); err != nil {
+		return nil, err
+	}
+	updated, err := h.client.Namespace(pvc.Namespace).UpdateStatus(ctx, u, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set start time of VolumeModifyRequest %s: %w", klog.KObj(request), err)
+	}
+	request = &VolumeModifyRequest{}
+	if err := fromUnstructured(updated, request); err != nil {
+		return nil, err
+	}
+	klog.V(4).InfoS("Created VolumeModifyRequest", "request", klog.KObj(request), "PVC", klog.KObj(pvc), "VAC", targetVAC)
+	h.open.Store(cache.MetaObjectToName(pvc).String(), request)
+	return request, nil
+}
+
+// cancel finishes a request that was not carried out. It failed if its last attempt failed with a final error.
+func (h *ModifyHistory) cancel(request *VolumeModifyRequest, reason, msg string) {
+	if request.Status.Phase == PhaseInProgress && request.Status.LastErrorCode != "" {
+		h.finish(request, PhaseFailed, reason, msg)
+		return
+	}
+	h.finish(request, PhaseCancelled, reason, msg)
+}
+
+func (h *ModifyHistory) finish(request *VolumeModifyRequest, phase Phase, reason, msg string) {
+	request.Status.Phase = phase
+	request.Status.Reason = reason
+	request.Status.Message = msg
+	request.Status.CompletionTime = ptr.To(metav1.NewTime(h.now()))
+}
+
+// update writes the status of newRequest if it changed, and prunes the history of pvc when
+// the request finished.
+func (h *ModifyHistory) update(ctx context.Context, pvc *v1.PersistentVolumeClaim, request, newRequest *VolumeModifyRequest) {
+	key := cache.MetaObjectToName(pvc).String()
+	if apiequality.Semantic.DeepEqual(request.Status, newRequest.Status) {
+		return
+	}
+	u, err := toUnstructured(newRequest)
+	if err != nil {
+		klog.ErrorS(err, "Failed to record modification", "PVC", klog.KObj(pvc))
+		return
+	}
+	updated, err := h.client.Namespace(newRequest.Namespace).UpdateStatus(ctx, u, metav1.UpdateOptions{})
+	if err != nil {
+		// Read the request again next time.
+		h.open.Delete(key)
+		klog.ErrorS(err, "Failed to update status of VolumeModifyRequest", "request", klog.KObj(newRequest), "PVC", klog.KObj(pvc))
+		return
+	}
+	if err := fromUnstructured(updated, newRequest); err != nil {
+		h.open.Delete(key)
+		return
+	}
+
+	phase := newRequest.Status.Phase
+	if !phase.Finished() {
+		h.open.Store(key, newRequest)
+		return
+	}
+	h.open.Delete(key)
+	klog.V(2).InfoS("VolumeModifyRequest finished", "request", klog.KObj(newRequest), "phase", phase, "reason", newRequest.Status.Reason)
+	finishedRequests.WithLabelValues("VolumeModifyRequest", string(phase)).Inc()
+	h.prune(ctx, pvc)
+}
+
+// prune deletes the oldest finished requests of pvc beyond the history limit.
+func (h *ModifyHistory) prune(ctx context.Context, pvc *v1.PersistentVolumeClaim) {
+	requests, err := h.list(ctx, pvc)
+	if err != nil {
+		klog.ErrorS(err, "Failed to prune VolumeModifyRequests", "PVC", klog.KObj(pvc))
+		return
+	}
+	kept := 0
+	for _, request := range requests {
+		if !request.Status.Phase.Finished() {
+			continue
+		}
+		if kept++; kept <= h.limit {
+			continue
+		}
+		klog.V(4).InfoS("Deleting VolumeModifyRequest beyond the history limit", "request", klog.KObj(request))
+		err := h.client.Namespace(request.Namespace).Delete(ctx, request.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &request.UID},
+		})
+		if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+			klog.ErrorS(err, "Failed to delete VolumeModifyRequest", "request", klog.KObj(request))
+		}
+	}
+}
+
+func condition(pvc *v1.PersistentVolumeClaim, conditionType v1.PersistentVolumeClaimConditionType) *v1.PersistentVolumeClaimCondition {
+	for i := range pvc.Status.Conditions {
+		if pvc.Status.Conditions[i].Type == conditionType {
+			return &pvc.Status.Conditions[i]
+		}
+	}
+	return nil
+}
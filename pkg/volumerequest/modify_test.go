@@ -0,0 +1,207 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volumerequest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+func newTestHistory(limit int) (*ModifyHistory, *dynamicfake.FakeDynamicClient) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{VolumeModifyRequestResource: "VolumeModifyRequestList"})
+	// The fake client does not generate names and creation timestamps.
+	created := 0
+	client.PrependReactor("create", "volumemodifyrequests", func(action core.Action) (bool, runtime.Object, error) {
+		u := action.(core.CreateAction).GetObject().(*unstructured.Unstructured)
+		created++
+		u.SetName(fmt.Sprintf("%s%d", u.GetGenerateName(), created))
+		u.SetCreationTimestamp(metav1.NewTime(testNow.Add(time.Duration(created) * time.Minute)))
+		return false, nil, nil
+	})
+	history := NewModifyHistory(client, limit)
+	history.now = func() time.Time { return testNow }
+	return history, client
+}
+
+func modifyingPVC(currentVAC, targetVAC string, modifyStatus v1.PersistentVolumeClaimModifyVolumeStatus) *v1.PersistentVolumeClaim {
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "claim01", Namespace: "default", UID: "pvc-uid"},
+		Spec:       v1.PersistentVolumeClaimSpec{VolumeAttributesClassName: ptr.To(targetVAC)},
+		Status: v1.PersistentVolumeClaimStatus{
+			CurrentVolumeAttributesClassName: ptr.To(currentVAC),
+			ModifyVolumeStatus: &v1.ModifyVolumeStatus{
+				TargetVolumeAttributesClassName: targetVAC,
+				Status:                          modifyStatus,
+			},
+		},
+	}
+}
+
+func completedPVC(vac string) *v1.PersistentVolumeClaim {
+	pvc := modifyingPVC(vac, vac, "")
+	pvc.Status.ModifyVolumeStatus = nil
+	return pvc
+}
+
+// modifyRequests returns the requests in the order they were created.
+func modifyRequests(t *testing.T, client *dynamicfake.FakeDynamicClient) []*VolumeModifyRequest {
+	t.Helper()
+	list, err := client.Resource(VolumeModifyRequestResource).Namespace("default").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := make([]*VolumeModifyRequest, len(list.Items))
+	for i := range list.Items {
+		requests[i] = &VolumeModifyRequest{}
+		if err := fromUnstructured(&list.Items[i], requests[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return requests
+}
+
+func TestModifyHistory(t *testing.T) {
+	ctx := context.TODO()
+	history, client := newTestHistory(10)
+
+	history.Observe(ctx, modifyingPVC("silver", "gold", v1.PersistentVolumeClaimModifyVolumeInProgress))
+	history.Attempted(ctx, modifyingPVC("silver", "gold", v1.PersistentVolumeClaimModifyVolumeInProgress), "gold", status.Error(codes.Unavailable, "busy"))
+	if requests := modifyRequests(t, client); len(requests) != 1 || requests[0].Status.LastErrorCode != codes.Unavailable.String() {
+		t.Fatalf("expected one request with the error code of the failed attempt, got %+v", requests)
+	}
+	history.Attempted(ctx, modifyingPVC("silver", "gold", v1.PersistentVolumeClaimModifyVolumeInProgress), "gold", nil)
+	history.Observe(ctx, completedPVC("gold"))
+
+	requests := modifyRequests(t, client)
+	if len(requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(requests))
+	}
+	request := requests[0]
+	if request.Spec.VolumeAttributesClassName != "gold" || request.Spec.PreviousVolumeAttributesClassName != "silver" {
+		t.Errorf("expected change from silver to gold, got %+v", request.Spec)
+	}
+	if request.Labels[LabelPersistentVolumeClaimUID] != "pvc-uid" || len(request.OwnerReferences) != 1 {
+		t.Errorf("expected request owned by the PVC, got %+v", request.ObjectMeta)
+	}
+	if request.Status.Phase != PhaseCompleted || request.Status.Attempts != 2 || request.Status.LastErrorCode != "" {
+		t.Errorf("expected completed request after 2 attempts, got %+v", request.Status)
+	}
+	if request.Status.StartTime == nil || request.Status.CompletionTime == nil {
+		t.Errorf("expected start and completion time, got %+v", request.Status)
+	}
+}
+
+func TestModifyHistoryStartTime(t *testing.T) {
+	ctx := context.TODO()
+	history, client := newTestHistory(10)
+	client.PrependReactor("update", "volumemodifyrequests", func(action core.Action) (bool, runtime.Object, error) {
+		u := action.(core.UpdateAction).GetObject().(*unstructured.Unstructured)
+		if phase, _, _ := unstructured.NestedString(u.Object, "status", "phase"); action.GetSubresource() == "status" && phase != "" {
+			return true, nil, fmt.Errorf("update failed")
+		}
+		return false, nil, nil
+	})
+
+	// The start time is written right after the create, it is kept when recording the phase fails.
+	history.Observe(ctx, modifyingPVC("silver", "gold", v1.PersistentVolumeClaimModifyVolumeInProgress))
+	requests := modifyRequests(t, client)
+	if len(requests) != 1 || requests[0].Status.StartTime == nil {
+		t.Fatalf("expected one request with a start time, got %+v", requests)
+	}
+}
+
+func TestModifyHistoryOutcome(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		next           *v1.PersistentVolumeClaim
+		expectedPhase  Phase
+		expectedReason string
+	}{
+		{
+			name:           "superseded",
+			next:           modifyingPVC("silver", "platinum", v1.PersistentVolumeClaimModifyVolumeInProgress),
+			expectedPhase:  PhaseCancelled,
+			expectedReason: reasonSuperseded,
+		},
+		{
+			name:           "superseded after infeasible",
+			err:            status.Error(codes.InvalidArgument, "unsupported"),
+			next:           modifyingPVC("silver", "platinum", v1.PersistentVolumeClaimModifyVolumeInProgress),
+			expectedPhase:  PhaseFailed,
+			expectedReason: reasonSuperseded,
+		},
+		{
+			name: "rolled back",
+			err:  status.Error(codes.InvalidArgument, "unsupported"),
+			next: func() *v1.PersistentVolumeClaim {
+				pvc := modifyingPVC("silver", "gold", v1.PersistentVolumeClaimModifyVolumeInfeasible)
+				pvc.Spec.VolumeAttributesClassName = nil
+				return pvc
+			}(),
+			expectedPhase:  PhaseFailed,
+			expectedReason: reasonRolledBack,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.TODO()
+			history, client := newTestHistory(10)
+			pvc := modifyingPVC("silver", "gold", v1.PersistentVolumeClaimModifyVolumeInProgress)
+			history.Observe(ctx, pvc)
+			if test.err != nil {
+				history.Attempted(ctx, pvc, "gold", test.err)
+			}
+			history.Observe(ctx, test.next)
+
+			requests := modifyRequests(t, client)
+			if requests[0].Status.Phase != test.expectedPhase || requests[0].Status.Reason != test.expectedReason {
+				t.Errorf("expected phase %q and reason %q, got %+v", test.expectedPhase, test.expectedReason, requests[0].Status)
+			}
+		})
+	}
+}
+
+func TestModifyHistoryLimit(t *testing.T) {
+	ctx := context.TODO()
+	history, client := newTestHistory(1)
+	current := "silver"
+	for _, vac := range []string{"gold", "platinum", "silver"} {
+		history.Observe(ctx, modifyingPVC(current, vac, v1.PersistentVolumeClaimModifyVolumeInProgress))
+		history.Observe(ctx, completedPVC(vac))
+		current = vac
+	}
+
+	requests := modifyRequests(t, client)
+	if len(requests) != 1 || requests[0].Spec.VolumeAttributesClassName != "silver" {
+		t.Errorf("expected only the last request kept, got %+v", requests)
+	}
+}
@@ -0,0 +1,453 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volumerequest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/features"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/policy"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/resizer"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/sharding"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+)
+
+// pvcIndex indexes requests by the key of their PVC.
+const pvcIndex = "pvc"
+
+// Reasons of the status of VolumeResizeRequests.
+const (
+	reasonPVCNotFound       = "PersistentVolumeClaimNotFound"
+	reasonPVCNotBound       = "PersistentVolumeClaimNotBound"
+	reasonSizeBelowCapacity = "SizeBelowCapacity"
+	reasonDeadlineExceeded  = "DeadlineExceeded"
+	reasonInfeasible        = "Infeasible"
+)
+
+var finishedRequests = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Subsystem:      "csi_resizer",
+		Name:           "volume_requests_finished_total",
+		Help:           "Number of finished volume requests, by kind and phase.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"kind", "phase"},
+)
+
+func init() {
+	legacyregistry.MustRegister(finishedRequests)
+}
+
+// ResizeController carries out the VolumeResizeRequests of the PVCs of a driver. It sets the
+// requested size of the PVC and leaves the expansion to the resize controller.
+type ResizeController struct {
+	name          string
+	resizer       resizer.Resizer
+	kubeClient    kubernetes.Interface
+	client        dynamic.NamespaceableResourceInterface
+	queue         workqueue.TypedRateLimitingInterface[string]
+	eventRecorder record.EventRecorder
+
+	requests  cache.SharedIndexInformer
+	pvcLister corelisters.PersistentVolumeClaimLister
+	pvLister  corelisters.PersistentVolumeLister
+	pvcSynced cache.InformerSynced
+	pvSynced  cache.InformerSynced
+	policy    *policy.ResizePolicy
+	shards    *sharding.Manager
+	ttl       time.Duration
+	now       func() time.Time
+}
+
+// NewResizeController returns a ResizeController. Finished requests are deleted after ttl,
+// unless they set their own.
+func NewResizeController(
+	name string,
+	resizer resizer.Resizer,
+	kubeClient kubernetes.Interface,
+	client dynamic.Interface,
+	informerFactory informers.SharedInformerFactory,
+	requestInformerFactory dynamicinformer.DynamicSharedInformerFactory,
+	resizePolicy *policy.ResizePolicy,
+	shardManager *sharding.Manager,
+	ttl time.Duration) *ResizeController {
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartStructuredLogging(0)
+	eventBroadcaster.StartRecordingToSink(&corev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events(v1.NamespaceAll)})
+	eventRecorder := eventBroadcaster.NewRecorder(scheme.Scheme,
+		v1.EventSource{Component: fmt.Sprintf("external-resizer %s", name)})
+
+	pvcInformer := informerFactory.Core().V1().PersistentVolumeClaims()
+	pvInformer := informerFactory.Core().V1().PersistentVolumes()
+	requests := requestInformerFactory.ForResource(VolumeResizeRequestResource).Informer()
+
+	ctrl := &ResizeController{
+		name:          name,
+		resizer:       resizer,
+		kubeClient:    kubeClient,
+		client:        client.Resource(VolumeResizeRequestResource),
+		eventRecorder: eventRecorder,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](), workqueue.TypedRateLimitingQueueConfig[string]{
+				Name: fmt.Sprintf("%s-resize-requests", name),
+			}),
+		requests:  requests,
+		pvcLister: pvcInformer.Lister(),
+		pvLister:  pvInformer.Lister(),
+		pvcSynced: pvcInformer.Informer().HasSynced,
+		pvSynced:  pvInformer.Informer().HasSynced,
+		policy:    resizePolicy,
+		shards:    shardManager,
+		ttl:       ttl,
+		now:       time.Now,
+	}
+
+	if err := requests.AddIndexers(cache.Indexers{pvcIndex: resizeRequestPVCIndexFunc}); err != nil {
+		klog.ErrorS(err, "Failed to index VolumeResizeRequests by PVC")
+	}
+	requests.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    ctrl.enqueue,
+		UpdateFunc: func(_, newObj interface{}) { ctrl.enqueue(newObj) },
+	})
+	pvcInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    ctrl.enqueueForPVC,
+		UpdateFunc: func(_, newObj interface{}) { ctrl.enqueueForPVC(newObj) },
+		DeleteFunc: ctrl.enqueueForPVC,
+	})
+	return ctrl
+}
+
+// resizeRequestPVCIndexFunc indexes VolumeResizeRequests by the key of their PVC.
+func resizeRequestPVCIndexFunc(obj interface{}) ([]string, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil
+	}
+	name, _, _ := unstructured.NestedString(u.Object, "spec", "persistentVolumeClaimName")
+	if name == "" {
+		return nil, nil
+	}
+	return []string{u.GetNamespace() + "/" + name}, nil
+}
+
+func (ctrl *ResizeController) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		klog.ErrorS(err, "Failed to get key of VolumeResizeRequest")
+		return
+	}
+	ctrl.queue.Add(key)
+}
+
+// enqueueForPVC enqueues the requests of a PVC, to update their status.
+func (ctrl *ResizeController) enqueueForPVC(obj interface{}) {
+	pvcKey, err := util.GetObjectKey(obj)
+	if err != nil {
+		return
+	}
+	objs, err := ctrl.requests.GetIndexer().ByIndex(pvcIndex, pvcKey)
+	if err != nil {
+		klog.ErrorS(err, "Failed to list VolumeResizeRequests of PVC", "PVC", pvcKey)
+		return
+	}
+	for _, obj := range objs {
+		ctrl.enqueue(obj)
+	}
+}
+
+// Run starts the controller.
+func (ctrl *ResizeController) Run(workers int, ctx context.Context, wg *sync.WaitGroup) {
+	defer ctrl.queue.ShutDown()
+
+	klog.InfoS("Starting VolumeResizeRequest controller", "controller", ctrl.name)
+	defer klog.InfoS("Shutting down VolumeResizeRequest controller", "controller", ctrl.name)
+
+	stopCh := ctx.Done()
+	if !cache.WaitForCacheSync(stopCh, ctrl.requests.HasSynced, ctrl.pvcSynced, ctrl.pvSynced) {
+		klog.ErrorS(nil, "Cannot sync VolumeResizeRequest, pv or pvc caches")
+		return
+	}
+
+	worker := func() {
+		for ctrl.processNext(ctx) {
+		}
+	}
+	if utilfeature.DefaultFeatureGate.Enabled(features.ReleaseLeaderElectionOnExit) {
+		for range workers {
+			wg.Go(func() {
+				wait.Until(worker, 0, stopCh)
+			})
+		}
+	} else {
+		for range workers {
+			go wait.Until(worker, 0, stopCh)
+		}
+	}
+
+	<-stopCh
+}
+
+func (ctrl *ResizeController) processNext(ctx context.Context) bool {
+	key, quit := ctrl.queue.Get()
+	if quit {
+		return false
+	}
+	defer ctrl.queue.Done(key)
+
+	if err := ctrl.sync(ctx, key); err != nil {
+		klog.ErrorS(err, "Error syncing VolumeResizeRequest", "key", key)
+		ctrl.queue.AddRateLimited(key)
+		return true
+	}
+	ctrl.queue.Forget(key)
+	return true
+}
+
+// sync moves a VolumeResizeRequest forward and updates its status.
+func (ctrl *ResizeController) sync(ctx context.Context, key string) error {
+	obj, exists, err := ctrl.requests.GetStore().GetByKey(key)
+	if err != nil || !exists {
+		return err
+	}
+	u := obj.(*unstructured.Unstructured)
+	request := &VolumeResizeRequest{}
+	if err := fromUnstructured(u, request); err != nil {
+		klog.ErrorS(err, "Ignoring invalid VolumeResizeRequest", "key", key)
+		return nil
+	}
+	pvcKey := request.Namespace + "/" + request.Spec.PersistentVolumeClaimName
+	if !ctrl.shards.Owns(pvcKey) {
+		return nil
+	}
+	if request.Status.Phase.Finished() {
+		return ctrl.collect(ctx, key, request)
+	}
+
+	pvc, err := ctrl.pvcLister.PersistentVolumeClaims(request.Namespace).Get(request.Spec.PersistentVolumeClaimName)
+	if apierrors.IsNotFound(err) {
+		return ctrl.pending(ctx, key, request, reasonPVCNotFound, fmt.Sprintf("PVC %s does not exist.", pvcKey))
+	}
+	if err != nil {
+		return err
+	}
+	if pvc.Spec.VolumeName == "" {
+		return ctrl.pending(ctx, key, request, reasonPVCNotBound, fmt.Sprintf("PVC %s is not bound.", pvcKey))
+	}
+	pv, err := ctrl.pvLister.Get(pvc.Spec.VolumeName)
+	if apierrors.IsNotFound(err) {
+		return ctrl.pending(ctx, key, request, reasonPVCNotBound, fmt.Sprintf("PV %s of PVC %s does not exist.", pvc.Spec.VolumeName, pvcKey))
+	}
+	if err != nil {
+		return err
+	}
+	if !ctrl.resizer.CanSupport(pv, pvc) {
+		// The request is carried out by the resizer of another driver.
+		return nil
+	}
+
+	newRequest := request.DeepCopy()
+	newRequest.Status.Capacity = ptr.To(pvc.Status.Capacity[v1.ResourceStorage])
+	newRequest.Status.AllocatedResourceStatuses = pvc.Status.AllocatedResourceStatuses
+	newRequest.Status.Conditions = resizeConditions(pvc.Status.Conditions)
+
+	switch {
+	case request.Status.Phase != PhaseInProgress:
+		pvc, err = ctrl.apply(newRequest, pvc)
+		if err != nil {
+			return err
+		}
+	case newRequest.Status.Capacity.Cmp(request.Spec.Size) >= 0:
+		ctrl.finish(newRequest, PhaseCompleted, "", fmt.Sprintf("PVC %s was expanded to %s.", pvcKey, newRequest.Status.Capacity.String()))
+	case infeasible(pvc):
+		ctrl.finish(newRequest, PhaseFailed, reasonInfeasible, fmt.Sprintf("Expansion of PVC %s is infeasible.", pvcKey))
+	}
+
+	if !newRequest.Status.Phase.Finished() && request.Spec.Deadline != nil {
+		if left := request.Spec.Deadline.Sub(ctrl.now()); left > 0 {
+			ctrl.queue.AddAfter(key, left)
+		} else {
+			ctrl.finish(newRequest, PhaseFailed, reasonDeadlineExceeded,
+				fmt.Sprintf("PVC %s was not expanded to %s by %s.", pvcKey, request.Spec.Size.String(), request.Spec.Deadline.UTC().Format(time.RFC3339)))
+		}
+	}
+	return ctrl.updateStatus(ctx, request, newRequest)
+}
+
+// apply validates the request and sets the requested size of the PVC.
+func (ctrl *ResizeController) apply(request *VolumeResizeRequest, pvc *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, error) {
+	size := request.Spec.Size
+	capacity := pvc.Status.Capacity[v1.ResourceStorage]
+	if size.Cmp(capacity) < 0 {
+		ctrl.finish(request, PhaseRejected, reasonSizeBelowCapacity,
+			fmt.Sprintf("Size %s is smaller than the capacity %s of the PVC, volumes can't be shrunk.", size.String(), capacity.String()))
+		return pvc, nil
+	}
+	if reason, msg := ctrl.policy.CheckResize(pvc, size); reason != "" {
+		ctrl.finish(request, PhaseRejected, reason, msg)
+		return pvc, nil
+	}
+
+	requestSize := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+	request.Status.Phase = PhaseInProgress
+	request.Status.Reason = ""
+	request.Status.AppliedTime = ptr.To(metav1.NewTime(ctrl.now()))
+	if size.Cmp(requestSize) <= 0 {
+		request.Status.Message = fmt.Sprintf("PVC already requests %s.", requestSize.String())
+		return pvc, nil
+	}
+
+	newPVC := pvc.DeepCopy()
+	metav1.SetMetaDataAnnotation(&newPVC.ObjectMeta, AnnResizeRequest, request.Name)
+	if newPVC.Spec.Resources.Requests == nil {
+		newPVC.Spec.Resources.Requests = v1.ResourceList{}
+	}
+	newPVC.Spec.Resources.Requests[v1.ResourceStorage] = size
+	updatedPVC, err := util.PatchClaimMetadata(ctrl.kubeClient, pvc, newPVC, true)
+	if err != nil {
+		return pvc, err
+	}
+	request.Status.Message = fmt.Sprintf("Requested size of the PVC changed from %s to %s.", requestSize.String(), size.String())
+	msg := fmt.Sprintf("Requested size changed from %s to %s by VolumeResizeRequest %s", requestSize.String(), size.String(), request.Name)
+	if request.Spec.Reason != "" {
+		msg += ": " + request.Spec.Reason
+	}
+	ctrl.eventRecorder.Event(updatedPVC, v1.EventTypeNormal, util.VolumeResizeRequestApplied, msg)
+	return updatedPVC, nil
+}
+
+// pending records why the request can't be applied yet.
+func (ctrl *ResizeController) pending(ctx context.Context, key string, request *VolumeResizeRequest, reason, msg string) error {
+	newRequest := request.DeepCopy()
+	if request.Status.Phase == "" || request.Status.Phase == PhasePending {
+		newRequest.Status.Phase = PhasePending
+		newRequest.Status.Reason = reason
+		newRequest.Status.Message = msg
+	}
+	if request.Spec.Deadline != nil {
+		if left := request.Spec.Deadline.Sub(ctrl.now()); left > 0 {
+			ctrl.queue.AddAfter(key, left)
+		} else {
+			ctrl.finish(newRequest, PhaseFailed, reasonDeadlineExceeded, msg)
+		}
+	}
+	return ctrl.updateStatus(ctx, request, newRequest)
+}
+
+func (ctrl *ResizeController) finish(request *VolumeResizeRequest, phase Phase, reason, msg string) {
+	request.Status.Phase = phase
+	request.Status.Reason = reason
+	request.Status.Message = msg
+	request.Status.CompletionTime = ptr.To(metav1.NewTime(ctrl.now()))
+}
+
+// updateStatus writes the status of newRequest if it changed.
+func (ctrl *ResizeController) updateStatus(ctx context.Context, request, newRequest *VolumeResizeRequest) error {
+	if apiequality.Semantic.DeepEqual(request.Status, newRequest.Status) {
+		return nil
+	}
+	u, err := toUnstructured(newRequest)
+	if err != nil {
+		return err
+	}
+	updated, err := ctrl.client.Namespace(newRequest.Namespace).UpdateStatus(ctx, u, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update status of VolumeResizeRequest %s: %w", klog.KObj(newRequest), err)
+	}
+
+	phase := newRequest.Status.Phase
+	if phase == request.Status.Phase {
+		return nil
+	}
+	klog.V(2).InfoS("VolumeResizeRequest changed phase", "request", klog.KObj(newRequest), "phase", phase, "reason", newRequest.Status.Reason)
+	switch phase {
+	case PhaseInProgress:
+		ctrl.eventRecorder.Event(updated, v1.EventTypeNormal, util.VolumeResizeRequestApplied, newRequest.Status.Message)
+	case PhaseCompleted:
+		ctrl.eventRecorder.Event(updated, v1.EventTypeNormal, util.VolumeResizeRequestCompleted, newRequest.Status.Message)
+	case PhaseFailed:
+		ctrl.eventRecorder.Event(updated, v1.EventTypeWarning, util.VolumeResizeRequestFailed, newRequest.Status.Message)
+	case PhaseRejected:
+		ctrl.eventRecorder.Event(updated, v1.EventTypeWarning, util.VolumeResizeRequestRejected, newRequest.Status.Message)
+	}
+	if phase.Finished() {
+		finishedRequests.WithLabelValues("VolumeResizeRequest", string(phase)).Inc()
+		ctrl.queue.AddAfter(cache.MetaObjectToName(newRequest).String(), keepFor(newRequest.Status.CompletionTime, newRequest.Spec.TTLSecondsAfterFinished, ctrl.ttl, ctrl.now()))
+	}
+	return nil
+}
+
+// collect deletes a finished request once its TTL is over.
+func (ctrl *ResizeController) collect(ctx context.Context, key string, request *VolumeResizeRequest) error {
+	if left := keepFor(request.Status.CompletionTime, request.Spec.TTLSecondsAfterFinished, ctrl.ttl, ctrl.now()); left > 0 {
+		ctrl.queue.AddAfter(key, left)
+		return nil
+	}
+	klog.V(4).InfoS("Deleting finished VolumeResizeRequest", "request", klog.KObj(request))
+	err := ctrl.client.Namespace(request.Namespace).Delete(ctx, request.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &request.UID},
+	})
+	if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+		return fmt.Errorf("failed to delete VolumeResizeRequest %s: %w", klog.KObj(request), err)
+	}
+	return nil
+}
+
+// resizeConditions returns the resize conditions of a PVC.
+func resizeConditions(conditions []v1.PersistentVolumeClaimCondition) []v1.PersistentVolumeClaimCondition {
+	var result []v1.PersistentVolumeClaimCondition
+	for _, c := range conditions {
+		switch c.Type {
+		case v1.PersistentVolumeClaimResizing, v1.PersistentVolumeClaimFileSystemResizePending,
+			v1.PersistentVolumeClaimControllerResizeError, v1.PersistentVolumeClaimNodeResizeError:
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func infeasible(pvc *v1.PersistentVolumeClaim) bool {
+	switch pvc.Status.AllocatedResourceStatuses[v1.ResourceStorage] {
+	case v1.PersistentVolumeClaimControllerResizeInfeasible, v1.PersistentVolumeClaimNodeResizeInfeasible:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volumerequest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/csi"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/policy"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/resizer"
+	"github.com/kubernetes-csi/external-resizer/v2/pkg/testutil"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+)
+
+const driverName = "mock"
+
+var testNow = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func testPV(driver string) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv"},
+		Spec: v1.PersistentVolumeSpec{
+			Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: driver, VolumeHandle: "vol"},
+			},
+		},
+	}
+}
+
+func testResizeRequest(size string) *VolumeResizeRequest {
+	return &VolumeResizeRequest{
+		TypeMeta:   metav1.TypeMeta{APIVersion: GroupVersion.String(), Kind: "VolumeResizeRequest"},
+		ObjectMeta: metav1.ObjectMeta{Name: "grow", Namespace: "default", UID: "request-uid"},
+		Spec: VolumeResizeRequestSpec{
+			PersistentVolumeClaimName: "claim01",
+			Size:                      resource.MustParse(size),
+			Reason:                    "capacity planner",
+		},
+	}
+}
+
+type testEnv struct {
+	ctrl       *ResizeController
+	kubeClient *fake.Clientset
+	client     *dynamicfake.FakeDynamicClient
+}
+
+func newTestEnv(t *testing.T, request *VolumeResizeRequest, resizePolicy *policy.ResizePolicy, objs ...runtime.Object) *testEnv {
+	t.Helper()
+	kubeClient := fake.NewSimpleClientset(objs...)
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	for _, obj := range objs {
+		switch obj := obj.(type) {
+		case *v1.PersistentVolumeClaim:
+			informerFactory.Core().V1().PersistentVolumeClaims().Informer().GetStore().Add(obj)
+		case *v1.PersistentVolume:
+			informerFactory.Core().V1().PersistentVolumes().Informer().GetStore().Add(obj)
+		}
+	}
+
+	u, err := toUnstructured(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{VolumeResizeRequestResource: "VolumeResizeRequestList"}, u)
+	requestInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+
+	csiResizer, err := resizer.NewResizerFromClient(csi.NewMockClient(driverName, true, true, false, true, true),
+		15*time.Second, kubeClient, driverName, nil, resizer.MetadataNone)
+	if err != nil {
+		t.Fatalf("Unable to create resizer: %v", err)
+	}
+	ctrl := NewResizeController(driverName, csiResizer, kubeClient, client, informerFactory, requestInformerFactory,
+		resizePolicy, nil /* shardManager */, time.Hour)
+	t.Cleanup(ctrl.queue.ShutDown)
+	ctrl.requests.GetStore().Add(u)
+	ctrl.eventRecorder = record.NewFakeRecorder(10)
+	ctrl.now = func() time.Time { return testNow }
+	return &testEnv{ctrl: ctrl, kubeClient: kubeClient, client: client}
+}
+
+func (e *testEnv) request(t *testing.T) *VolumeResizeRequest {
+	t.Helper()
+	u, err := e.client.Resource(VolumeResizeRequestResource).Namespace("default").Get(context.TODO(), "grow", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get request: %v", err)
+	}
+	request := &VolumeResizeRequest{}
+	if err := fromUnstructured(u, request); err != nil {
+		t.Fatal(err)
+	}
+	return request
+}
+
+func TestSyncResizeRequest(t *testing.T) {
+	inProgress := testResizeRequest("2Gi")
+	inProgress.Status.Phase = PhaseInProgress
+
+	tests := []struct {
+		name            string
+		request         *VolumeResizeRequest
+		pvc             *v1.PersistentVolumeClaim
+		pv              *v1.PersistentVolume
+		policy          *policy.ResizePolicy
+		expectedPhase   Phase
+		expectedReason  string
+		expectedRequest string
+	}{
+		{
+			name:            "applied to PVC",
+			request:         testResizeRequest("2Gi"),
+			pvc:             testutil.GetTestPVC("pv", "1Gi", "1Gi", "", ""),
+			pv:              testPV(driverName),
+			expectedPhase:   PhaseInProgress,
+			expectedRequest: "2Gi",
+		},
+		{
+			name:            "rejected by policy",
+			request:         testResizeRequest("2Gi"),
+			pvc:             testutil.GetTestPVC("pv", "1Gi", "1Gi", "", ""),
+			pv:              testPV(driverName),
+			policy:          &policy.ResizePolicy{MaxSize: ptr.To(resource.MustParse("1500Mi"))},
+			expectedPhase:   PhaseRejected,
+			expectedReason:  policy.ReasonSizeAboveLimit,
+			expectedRequest: "1Gi",
+		},
+		{
+			name:            "shrink rejected",
+			request:         testResizeRequest("1Gi"),
+			pvc:             testutil.GetTestPVC("pv", "2Gi", "2Gi", "", ""),
+			pv:              testPV(driverName),
+			expectedPhase:   PhaseRejected,
+			expectedReason:  reasonSizeBelowCapacity,
+			expectedRequest: "2Gi",
+		},
+		{
+			name:            "waiting for expansion",
+			request:         inProgress,
+			pvc:             testutil.GetTestPVC("pv", "2Gi", "1Gi", "2Gi", v1.PersistentVolumeClaimControllerResizeInProgress),
+			pv:              testPV(driverName),
+			expectedPhase:   PhaseInProgress,
+			expectedRequest: "2Gi",
+		},
+		{
+			name:            "expansion completed",
+			request:         inProgress,
+			pvc:             testutil.GetTestPVC("pv", "2Gi", "2Gi", "2Gi", ""),
+			pv:              testPV(driverName),
+			expectedPhase:   PhaseCompleted,
+			expectedRequest: "2Gi",
+		},
+		{
+			name:            "expansion infeasible",
+			request:         inProgress,
+			pvc:             testutil.GetTestPVC("pv", "2Gi", "1Gi", "2Gi", v1.PersistentVolumeClaimControllerResizeInfeasible),
+			pv:              testPV(driverName),
+			expectedPhase:   PhaseFailed,
+			expectedReason:  reasonInfeasible,
+			expectedRequest: "2Gi",
+		},
+		{
+			name:            "PVC of another driver",
+			request:         testResizeRequest("2Gi"),
+			pvc:             testutil.GetTestPVC("pv", "1Gi", "1Gi", "", ""),
+			pv:              testPV("other"),
+			expectedRequest: "1Gi",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := newTestEnv(t, test.request, test.policy, test.pvc, test.pv)
+			if err := env.ctrl.sync(context.TODO(), "default/grow"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			request := env.request(t)
+			if request.Status.Phase != test.expectedPhase || request.Status.Reason != test.expectedReason {
+				t.Errorf("expected phase %q and reason %q, got %q and %q (%s)", test.expectedPhase, test.expectedReason,
+					request.Status.Phase, request.Status.Reason, request.Status.Message)
+			}
+			if test.expectedPhase != "" && !request.Status.Capacity.Equal(test.pvc.Status.Capacity[v1.ResourceStorage]) {
+				t.Errorf("expected capacity of PVC mirrored, got %v", request.Status.Capacity)
+			}
+
+			pvc, err := env.kubeClient.CoreV1().PersistentVolumeClaims("default").Get(context.TODO(), "claim01", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			requestSize := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+			if requestSize.Cmp(resource.MustParse(test.expectedRequest)) != 0 {
+				t.Errorf("expected PVC to request %s, got %s", test.expectedRequest, requestSize.String())
+			}
+		})
+	}
+}
+
+func TestResizeRequestDeadline(t *testing.T) {
+	request := testResizeRequest("2Gi")
+	request.Spec.Deadline = ptr.To(metav1.NewTime(testNow.Add(-time.Minute)))
+	// The PVC does not exist, the request can't be applied before its deadline.
+	env := newTestEnv(t, request, nil)
+	if err := env.ctrl.sync(context.TODO(), "default/grow"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := env.request(t); got.Status.Phase != PhaseFailed || got.Status.Reason != reasonDeadlineExceeded {
+		t.Errorf("expected request to fail with %s, got %+v", reasonDeadlineExceeded, got.Status)
+	}
+}
+
+func TestCollectResizeRequest(t *testing.T) {
+	request := testResizeRequest("2Gi")
+	request.Status.Phase = PhaseCompleted
+	request.Status.CompletionTime = ptr.To(metav1.NewTime(testNow.Add(-30 * time.Minute)))
+
+	env := newTestEnv(t, request, nil)
+	if err := env.ctrl.sync(context.TODO(), "default/grow"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The request is kept for the TTL of an hour.
+	env.request(t)
+
+	env.ctrl.now = func() time.Time { return testNow.Add(time.Hour) }
+	if err := env.ctrl.sync(context.TODO(), "default/grow"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err := env.client.Resource(VolumeResizeRequestResource).Namespace("default").Get(context.TODO(), "grow", metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected request to be deleted after its TTL, got %v", err)
+	}
+}
+
+func TestCheckResource(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	if err := CheckResource(client.Discovery(), VolumeResizeRequestResource); err == nil {
+		t.Errorf("expected an error without the CustomResourceDefinitions")
+	}
+
+	client.Resources = []*metav1.APIResourceList{{
+		GroupVersion: GroupVersion.String(),
+		APIResources: []metav1.APIResource{{Name: VolumeResizeRequestResource.Resource, Kind: "VolumeResizeRequest", Namespaced: true}},
+	}}
+	if err := CheckResource(client.Discovery(), VolumeResizeRequestResource); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := CheckResource(client.Discovery(), VolumeModifyRequestResource); err == nil {
+		t.Errorf("expected an error for a resource that is not served")
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package volumerequest carries out volume operations that tools without write access to
+// PVCs request with custom resources, and reports their progress in the status of the resources.
+// It also records the history of VolumeAttributesClass changes of PVCs in custom resources.
+package volumerequest
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/utils/ptr"
+)
+
+// GroupVersion of the custom resources, see deploy/kubernetes/volumerequests.yaml.
+var GroupVersion = schema.GroupVersion{Group: "resizer.csi.k8s.io", Version: "v1alpha1"}
+
+// VolumeResizeRequestResource is the resource of VolumeResizeRequests.
+var VolumeResizeRequestResource = GroupVersion.WithResource("volumeresizerequests")
+
+// VolumeModifyRequestResource is the resource of VolumeModifyRequests.
+var VolumeModifyRequestResource = GroupVersion.WithResource("volumemodifyrequests")
+
+// CheckResource returns an error if the API server does not serve resource,
+// i.e. the CustomResourceDefinitions from deploy/kubernetes/volumerequests.yaml are not installed.
+// Informers of a resource that is not served never sync.
+func CheckResource(client discovery.DiscoveryInterface, resource schema.GroupVersionResource) error {
+	resources, err := client.ServerResourcesForGroupVersion(resource.GroupVersion().String())
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to discover %s: %v", resource.GroupVersion(), err)
+	}
+	if resources != nil {
+		for _, r := range resources.APIResources {
+			if r.Name == resource.Resource {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("the API server does not serve %s, install the CustomResourceDefinitions from deploy/kubernetes/volumerequests.yaml", resource.GroupResource())
+}
+
+// AnnResizeRequest is set on a PVC to the name of the last VolumeResizeRequest applied to it.
+const AnnResizeRequest = "resizer.csi.k8s.io/resize-request"
+
+// Phase is the phase of a request.
+type Phase string
+
+const (
+	// PhasePending requests are not applied to their PVC yet, e.g. because it is not bound.
+	PhasePending Phase = "Pending"
+	// PhaseInProgress requests are applied to their PVC and the operation is not finished yet.
+	PhaseInProgress Phase = "InProgress"
+	// PhaseCompleted requests are carried out.
+	PhaseCompleted Phase = "Completed"
+	// PhaseFailed requests could not be carried out, e.g. because their deadline passed.
+	PhaseFailed Phase = "Failed"
+	// PhaseRejected requests are not allowed by the policy or are invalid. They are never applied.
+	PhaseRejected Phase = "Rejected"
+	// PhaseCancelled requests were replaced by another request or withdrawn before they finished.
+	PhaseCancelled Phase = "Cancelled"
+)
+
+// Finished returns true if requests in phase p don't change anymore.
+func (p Phase) Finished() bool {
+	return p == PhaseCompleted || p == PhaseFailed || p == PhaseRejected || p == PhaseCancelled
+}
+
+// VolumeResizeRequest requests the expansion of the volume of a PVC in the same namespace.
+type VolumeResizeRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VolumeResizeRequestSpec   `json:"spec"`
+	Status VolumeResizeRequestStatus `json:"status,omitempty"`
+}
+
+// VolumeResizeRequestSpec is the requested expansion.
+type VolumeResizeRequestSpec struct {
+	// PersistentVolumeClaimName is the name of the PVC to expand.
+	PersistentVolumeClaimName string `json:"persistentVolumeClaimName"`
+	// Size is the requested size of the PVC.
+	Size resource.Quantity `json:"size"`
+	// Reason tells why the expansion is requested. It is recorded in the events of the PVC.
+	Reason string `json:"reason,omitempty"`
+	// Deadline is the time by which the expansion must be finished. The request fails when it is not.
+	Deadline *metav1.Time `json:"deadline,omitempty"`
+	// TTLSecondsAfterFinished overrides how long the request is kept after it finished.
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// VolumeResizeRequestStatus is the progress of the expansion.
+type VolumeResizeRequestStatus struct {
+	Phase   Phase  `json:"phase,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Capacity is the capacity of the PVC.
+	Capacity *resource.Quantity `json:"capacity,omitempty"`
+	// AllocatedResourceStatuses and Conditions mirror the resize status of the PVC.
+	AllocatedResourceStatuses map[v1.ResourceName]v1.ClaimResourceStatus `json:"allocatedResourceStatuses,omitempty"`
+	Conditions                []v1.PersistentVolumeClaimCondition        `json:"conditions,omitempty"`
+	// AppliedTime is when the request was applied to the PVC.
+	AppliedTime *metav1.Time `json:"appliedTime,omitempty"`
+	// CompletionTime is when the request finished.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// DeepCopy returns a deep copy of r.
+func (r *VolumeResizeRequest) DeepCopy() *VolumeResizeRequest {
+	out := *r
+	r.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec.Size = r.Spec.Size.DeepCopy()
+	out.Spec.Deadline = r.Spec.Deadline.DeepCopy()
+	if r.Spec.TTLSecondsAfterFinished != nil {
+		out.Spec.TTLSecondsAfterFinished = ptr.To(*r.Spec.TTLSecondsAfterFinished)
+	}
+	if r.Status.Capacity != nil {
+		out.Status.Capacity = ptr.To(r.Status.Capacity.DeepCopy())
+	}
+	out.Status.AllocatedResourceStatuses = maps.Clone(r.Status.AllocatedResourceStatuses)
+	out.Status.Conditions = deepCopyConditions(r.Status.Conditions)
+	out.Status.AppliedTime = r.Status.AppliedTime.DeepCopy()
+	out.Status.CompletionTime = r.Status.CompletionTime.DeepCopy()
+	return &out
+}
+
+func deepCopyConditions(conditions []v1.PersistentVolumeClaimCondition) []v1.PersistentVolumeClaimCondition {
+	if conditions == nil {
+		return nil
+	}
+	out := make([]v1.PersistentVolumeClaimCondition, len(conditions))
+	for i := range conditions {
+		conditions[i].DeepCopyInto(&out[i])
+	}
+	return out
+}
+
+// VolumeModifyRequest records a change of the VolumeAttributesClass of a PVC in the same namespace.
+// The modify controller creates one for each change and keeps it after the change finished.
+type VolumeModifyRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VolumeModifyRequestSpec   `json:"spec"`
+	Status VolumeModifyRequestStatus `json:"status,omitempty"`
+}
+
+// VolumeModifyRequestSpec is the recorded change.
+type VolumeModifyRequestSpec struct {
+	// PersistentVolumeClaimName is the name of the modified PVC.
+	PersistentVolumeClaimName string `json:"persistentVolumeClaimName"`
+	// VolumeAttributesClassName is the requested VolumeAttributesClass.
+	VolumeAttributesClassName string `json:"volumeAttributesClassName"`
+	// PreviousVolumeAttributesClassName is the VolumeAttributesClass of the volume before the change.
+	PreviousVolumeAttributesClassName string `json:"previousVolumeAttributesClassName,omitempty"`
+}
+
+// VolumeModifyRequestStatus is the progress and outcome of the change.
+type VolumeModifyRequestStatus struct {
+	Phase   Phase  `json:"phase,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Attempts is the number of ControllerModifyVolume calls.
+	Attempts int32 `json:"attempts,omitempty"`
+	// LastErrorCode is the gRPC code of the last failed ControllerModifyVolume call.
+	LastErrorCode string `json:"lastErrorCode,omitempty"`
+	// StartTime is when the change was noticed.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is when the change finished.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// DeepCopy returns a deep copy of r.
+func (r *VolumeModifyRequest) DeepCopy() *VolumeModifyRequest {
+	out := *r
+	r.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Status.StartTime = r.Status.StartTime.DeepCopy()
+	out.Status.CompletionTime = r.Status.CompletionTime.DeepCopy()
+	return &out
+}
+
+// keepFor returns how long a finished request is kept after now, or zero if it may be deleted.
+func keepFor(completionTime *metav1.Time, ttlSeconds *int32, defaultTTL time.Duration, now time.Time) time.Duration {
+	ttl := defaultTTL
+	if ttlSeconds != nil {
+		ttl = time.Duration(*ttlSeconds) * time.Second
+	}
+	if completionTime == nil {
+		return ttl
+	}
+	return max(completionTime.Add(ttl).Sub(now), 0)
+}
+
+func fromUnstructured(obj *unstructured.Unstructured, into any) error {
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), into)
+}
+
+func toUnstructured(obj any) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: content}, nil
+}